@@ -511,6 +511,11 @@ type HostConfig struct {
 	Ulimits          []ULimit               `json:"Ulimits,omitempty" yaml:"Ulimits,omitempty"`
 	VolumeDriver     string                 `json:"VolumeDriver,omitempty" yaml:"VolumeDriver,omitempty"`
 	OomScoreAdj      int                    `json:"OomScoreAdj,omitempty" yaml:"OomScoreAdj,omitempty"`
+	PidsLimit        int64                  `json:"PidsLimit,omitempty" yaml:"PidsLimit,omitempty"`
+	Tmpfs            map[string]string      `json:"Tmpfs,omitempty" yaml:"Tmpfs,omitempty"`
+	UsernsMode       string                 `json:"UsernsMode,omitempty" yaml:"UsernsMode,omitempty"`
+	ShmSize          int64                  `json:"ShmSize,omitempty" yaml:"ShmSize,omitempty"`
+	Sysctls          map[string]string      `json:"Sysctls,omitempty" yaml:"Sysctls,omitempty"`
 }
 
 // StartContainer starts a container, returning an error in case of failure.
@@ -10,6 +10,7 @@ import (
 	"github.com/eris-ltd/eris-cli/chains"
 	"github.com/eris-ltd/eris-cli/definitions"
 	"github.com/eris-ltd/eris-cli/services"
+	"github.com/eris-ltd/eris-cli/util"
 
 	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 )
@@ -27,6 +28,13 @@ func Do(do *definitions.Do) error {
 		return err
 	}
 
+	if do.Action.Machine != nil {
+		unmet := util.UnmetRequirements(do.Action.Machine.Requires, do.Operations)
+		if len(unmet) != 0 {
+			return util.RequirementsReport(map[string][]string{do.Action.Name: unmet})
+		}
+	}
+
 	resolveServices(do)
 	resolveChain(do)
 	fixChain(do.Action, do.ChainName)
@@ -42,6 +50,52 @@ func Do(do *definitions.Do) error {
 	return nil
 }
 
+// GraphAction displays the dependency graph of an action: the services
+// and chains [eris actions do] would bring up.
+//
+//  do.Operations.Args - action name (required)
+//  do.ChainName       - chain to run the action against
+//  do.ServicesSlice   - additional services to start
+//  do.GraphFormat     - text (default) or dot
+//
+func GraphAction(do *definitions.Do) error {
+	var err error
+	do.Action, _, err = LoadActionDefinition(strings.Join(do.Operations.Args, "_"))
+	if err != nil {
+		return err
+	}
+
+	resolveServices(do)
+	resolveChain(do)
+
+	g := services.NewGraph(do.ChainName)
+	g.AddAction(do.Action)
+	return services.WriteGraph(g, do)
+}
+
+// PerformHookAction performs the action as a lifecycle hook of a service
+// or a chain (see the hooks package). The services the action depends on
+// are started, but not its chain: that one is being started or stopped
+// by the hook caller. $chain in the steps is replaced with chain, and
+// env is exported to the steps.
+func PerformHookAction(name, chain string, env []string) error {
+	action, actionVars, err := LoadActionDefinition(strings.Join(strings.Fields(name), "_"))
+	if err != nil {
+		return err
+	}
+	fixChain(action, chain)
+
+	if action.Dependencies != nil && len(action.Dependencies.Services) != 0 {
+		doSrvs := definitions.NowDo()
+		doSrvs.Operations.Args = action.Dependencies.Services
+		if err := services.StartService(doSrvs); err != nil {
+			return err
+		}
+	}
+
+	return PerformCommand(action, append(actionVars, env...), false)
+}
+
 func StartServicesAndChains(do *definitions.Do) error {
 	// start the services and chains
 	doSrvs := definitions.NowDo()
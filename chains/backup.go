@@ -0,0 +1,144 @@
+package chains
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/services"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// BackupChain writes a compressed archive with the contents of the data
+// container of the chain, its definition file, the image digest, the
+// chain ID, and a manifest with checksums of all files. The archive
+// name is returned in do.Result.
+//
+//  do.Name        - name of the chain (required)
+//  do.Destination - archive to write (defaults to NAME_DATE.tar.gz)
+//
+func BackupChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	definition := util.GetFileByNameAndType("chains", do.Name)
+	if definition == "" {
+		return fmt.Errorf("I cannot find that chain. Please check the chain name you sent me.")
+	}
+
+	manifest := &data.BackupManifest{
+		Name:    do.Name,
+		Type:    definitions.TypeChain,
+		Image:   chain.Service.Image,
+		ChainID: chain.ChainID,
+	}
+	if manifest.ChainID == "" {
+		manifest.ChainID, _ = getChainIDFromGenesis(filepath.Join(ChainsPath, do.Name, "genesis.json"), do.Name)
+	}
+	var containerID string
+	if c := util.FindChainContainer(do.Name, true); c != nil {
+		containerID = c.ContainerID
+	}
+	manifest.ImageID, manifest.ImageDigests = data.ImageDigests(chain.Service.Image, containerID)
+
+	archive := services.BackupFileName(do.Name, do.Destination)
+	if err := data.WriteBackup(manifest, archive, definition); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"=>":       archive,
+		"chain id": manifest.ChainID,
+	}).Warn("Chain backed up")
+	do.Result = archive
+	return nil
+}
+
+// RestoreChain verifies the backup archive and recreates the chain
+// definition file and the data container from it. If the chain is
+// restored under a new name, the chain directory in the data container
+// is renamed accordingly; the chain ID stays the same.
+//
+//  do.Source  - backup archive written by BackupChain (required)
+//  do.NewName - name to restore the chain under (optional)
+//  do.Force   - replace the existing definition file and data container
+//
+func RestoreChain(do *definitions.Do) error {
+	b, err := data.ReadBackup(do.Source)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	if b.Manifest.Type != definitions.TypeChain {
+		return fmt.Errorf("%s is a backup of the %s %s, not a chain", do.Source, b.Manifest.Type, b.Manifest.Name)
+	}
+
+	name := b.Manifest.Name
+	if do.NewName != "" {
+		name = do.NewName
+	}
+
+	if old := util.GetFileByNameAndType("chains", name); old != "" {
+		if !do.Force {
+			return fmt.Errorf("Chain %s already exists. Use --force to replace it", name)
+		}
+		os.Remove(old)
+	}
+
+	fileName := filepath.Join(ChainsPath, name+path.Ext(b.Manifest.Definition))
+	if err := ioutil.WriteFile(fileName, b.Definition, 0644); err != nil {
+		return err
+	}
+
+	var rename func(string) string
+	if name != b.Manifest.Name {
+		chain, err := loaders.ReadChainDefinition(name)
+		if err != nil {
+			return err
+		}
+		// Only the name and the chain ID (the chain directory) change;
+		// the rest of the definition file is restored as it was.
+		content, err := util.SetDefinitionValues(b.Definition, fileName, map[string]string{
+			"name":     name,
+			"chain_id": name,
+		})
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+			return err
+		}
+
+		from := path.Join(path.Base(ErisContainerRoot), "chains", path.Base(chainDir(chain)))
+		to := path.Join(path.Base(ErisContainerRoot), "chains", name)
+		rename = func(file string) string {
+			if file == from || strings.HasPrefix(file, from+"/") {
+				return to + strings.TrimPrefix(file, from)
+			}
+			return file
+		}
+	}
+
+	if err := b.Restore(name, do.Force, rename); err != nil {
+		return err
+	}
+	b.Manifest.WarnImageDrift()
+
+	log.WithFields(log.Fields{
+		"=>":       name,
+		"chain id": b.Manifest.ChainID,
+	}).Warn("Chain restored")
+	do.Result = name
+	return nil
+}
@@ -0,0 +1,423 @@
+package chains
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// Layout of a chain bundle (a gzipped tarball): the manifest first, the
+// chain definition file, and the files of the chain directory.
+const (
+	BundleManifestFile  = "manifest.json"
+	BundleDefinitionDir = "definition"
+	BundleChainDir      = "chain"
+)
+
+// bundleFiles are the files of the chain directory a bundle holds.
+var bundleFiles = []string{"genesis.json", "config.toml", "server_conf.toml", "priv_validator.json"}
+
+// bundleDefinitionExts are the extensions a bundled chain definition
+// file may have.
+var bundleDefinitionExts = map[string]bool{".toml": true, ".json": true, ".yaml": true, ".yml": true}
+
+// BundleManifest describes the contents of a chain bundle.
+type BundleManifest struct {
+	Name    string    `json:"name"`
+	ChainID string    `json:"chain_id"`
+	Created time.Time `json:"created"`
+	// path of the definition file in the bundle
+	Definition string `json:"definition"`
+	Image      string `json:"image"`
+	// seeds setting of the bundled config.toml
+	Seeds []string `json:"seeds,omitempty"`
+	// true if the bundle holds a priv_validator.json file
+	Validator bool `json:"validator"`
+
+	// SHA-256 checksums of all files in the bundle (except for the
+	// manifest itself) by their path in the bundle
+	Checksums map[string]string `json:"checksums"`
+}
+
+// Bundle is a verified chain bundle read by ReadBundle.
+type Bundle struct {
+	Manifest   *BundleManifest
+	Definition []byte
+	// files of the chain directory by name
+	Files map[string][]byte
+}
+
+// BundleChain writes a bundle with everything needed to join the chain:
+// its definition file, and the genesis.json, config.toml,
+// server_conf.toml, and (unless excluded) priv_validator.json files of
+// its first node. The bundle name is returned in do.Result.
+//
+//  do.Name       - name of the chain (required)
+//  do.BundleFile - bundle to write (defaults to NAME.tar.gz)
+//  do.NoPriv     - leave out the priv_validator.json file (optional)
+//  do.ConfigOpts - <key>=<value> pairs to set in the bundled config.toml (optional)
+//
+func BundleChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	definition := util.GetFileByNameAndType("chains", chain.Name)
+	if definition == "" {
+		return fmt.Errorf("I cannot find that chain. Please check the chain name you sent me.")
+	}
+	if !util.IsDataContainer(chain.Name) {
+		return fmt.Errorf("There is no data container for %s. Only chains with data containers can be bundled", chain.Name)
+	}
+
+	names := bundleFiles
+	if do.NoPriv {
+		names = names[:len(names)-1]
+	}
+	files, dir, err := exportChainFiles(chain, 1, names)
+	if err != nil {
+		return err
+	}
+	os.RemoveAll(dir)
+	if _, ok := files["genesis.json"]; !ok {
+		return fmt.Errorf("The chain %s has no genesis.json file in %s", chain.Name, chainDir(chain))
+	}
+
+	for _, pair := range do.ConfigOpts {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("Invalid config option %q. Use KEY=VALUE", pair)
+		}
+		files["config.toml"] = util.SetTOMLValue(files["config.toml"], parts[0], tomlLiteral(parts[1]))
+	}
+	if err := validTOML(files["config.toml"]); err != nil {
+		return fmt.Errorf("The bundled config.toml is not valid: %v", err)
+	}
+
+	manifest := &BundleManifest{
+		Name:       chain.Name,
+		ChainID:    path.Base(chainDir(chain)),
+		Created:    time.Now().UTC(),
+		Definition: path.Join(BundleDefinitionDir, filepath.Base(definition)),
+		Image:      chain.Service.Image,
+		Seeds:      configSeeds(files["config.toml"]),
+		Validator:  files["priv_validator.json"] != nil,
+		Checksums:  make(map[string]string),
+	}
+	def, err := ioutil.ReadFile(definition)
+	if err != nil {
+		return err
+	}
+	bundled := map[string][]byte{manifest.Definition: def}
+	for name, content := range files {
+		bundled[path.Join(BundleChainDir, name)] = content
+	}
+
+	archive := do.BundleFile
+	if archive == "" {
+		archive = chain.Name + ".tar.gz"
+	}
+	if err := writeBundle(archive, manifest, bundled); err != nil {
+		os.Remove(archive)
+		return err
+	}
+
+	if manifest.Validator {
+		log.WithField("=>", archive).Warn("The bundle holds the validator key of the chain. Only give it to whoever runs this validator (or use --no-priv)")
+	}
+	log.WithFields(log.Fields{
+		"=>":       archive,
+		"chain id": manifest.ChainID,
+	}).Warn("Chain bundled")
+	do.Result = archive
+	return nil
+}
+
+// UnbundleChain verifies the bundle and sets up the chain definition
+// file and the data container of the chain from it, ready for
+// [eris chains start].
+//
+//  do.Source  - bundle written by BundleChain (required)
+//  do.NewName - name to set the chain up under (optional; defaults to the bundled name)
+//  do.Priv    - priv_validator.json file to use instead of the bundled one (optional)
+//  do.Force   - replace the existing chain definition file and data container (optional)
+//
+func UnbundleChain(do *definitions.Do) error {
+	b, err := ReadBundle(do.Source)
+	if err != nil {
+		return err
+	}
+
+	name := b.Manifest.Name
+	if do.NewName != "" {
+		name = do.NewName
+	}
+	if err := checkUnbundle(name, b.Manifest.Definition); err != nil {
+		return err
+	}
+	if do.Priv != "" {
+		if b.Files["priv_validator.json"], err = ioutil.ReadFile(do.Priv); err != nil {
+			return err
+		}
+	}
+
+	if old := util.GetFileByNameAndType("chains", name); old != "" || util.IsDataContainer(name) {
+		if !do.Force {
+			return fmt.Errorf("Chain %s already exists. Use --force to replace it", name)
+		}
+		if old != "" {
+			doRm := definitions.NowDo()
+			doRm.Name = name
+			doRm.RmD = true
+			doRm.Volumes = true
+			doRm.Force = true
+			if err := RmChain(doRm); err != nil {
+				return err
+			}
+		}
+		if util.IsDataContainer(name) {
+			ops := definitions.BlankOperation()
+			ops.SrvContainerName = util.DataContainersName(name)
+			if err := perform.DockerRemove(nil, ops, false, true, false); err != nil {
+				return err
+			}
+		}
+		os.Remove(old)
+	}
+
+	chain, err := writeUnbundledDefinition(b, name)
+	if err != nil {
+		return err
+	}
+
+	log.WithField("=>", name).Info("Creating data container")
+	ops := loaders.LoadDataDefinition(name)
+	if err := perform.DockerCreateData(ops); err != nil {
+		return fmt.Errorf("Error creating data container %v.", err)
+	}
+	doMkdir := definitions.NowDo()
+	doMkdir.Name = name
+	doMkdir.Operations.Args = []string{"mkdir", "--parents", chainDir(chain)}
+	if _, err := data.ExecData(doMkdir); err != nil {
+		return err
+	}
+	if err := importChainConfig(chain, 1, b.Files); err != nil {
+		return err
+	}
+
+	if b.Files["priv_validator.json"] == nil {
+		log.WithField("=>", name).Warn("The bundle has no validator key. The chain will make one at start; use --priv to join as a validator")
+	}
+	log.WithFields(log.Fields{
+		"=>":       name,
+		"chain id": b.Manifest.ChainID,
+		"seeds":    strings.Join(b.Manifest.Seeds, ","),
+	}).Warn("Chain unbundled. Start it with [eris chains start " + name + "]")
+	do.Result = name
+	return nil
+}
+
+// writeUnbundledDefinition writes the bundled definition file of the
+// chain to ChainsPath as it was bundled, changing only its name and
+// chain ID, and reads it back.
+func writeUnbundledDefinition(b *Bundle, name string) (*definitions.Chain, error) {
+	fileName := filepath.Join(ChainsPath, name+path.Ext(b.Manifest.Definition))
+	content, err := util.SetDefinitionValues(b.Definition, fileName, map[string]string{
+		"name":     name,
+		"chain_id": b.Manifest.ChainID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+		return nil, err
+	}
+	return loaders.ReadChainDefinition(name)
+}
+
+// checkUnbundle makes sure the chain name and the path of the definition
+// file, both of which come from the bundle manifest, are safe to write a
+// chain definition file in ChainsPath with.
+func checkUnbundle(name, definition string) error {
+	if !snapshotTag.MatchString(name) {
+		return fmt.Errorf("Invalid chain name %q. Use letters, digits, dots, dashes, and underscores", name)
+	}
+	if ext := path.Ext(definition); !bundleDefinitionExts[ext] {
+		return fmt.Errorf("Invalid chain definition file %q in the bundle. Use .toml, .json, .yaml, or .yml", definition)
+	}
+	return nil
+}
+
+// ReadBundle reads the chain bundle and verifies the checksums of all
+// files listed in its manifest.
+func ReadBundle(archive string) (*Bundle, error) {
+	in, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a chain bundle: %v", archive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != BundleManifestFile {
+		return nil, fmt.Errorf("%s is not a chain bundle: %s is missing", archive, BundleManifestFile)
+	}
+	b := &Bundle{Manifest: &BundleManifest{}, Files: make(map[string][]byte)}
+	if err := json.NewDecoder(tr).Decode(b.Manifest); err != nil {
+		return nil, fmt.Errorf("Cannot read the bundle manifest: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		contents, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if sum, ok := b.Manifest.Checksums[header.Name]; !ok || sum != bundleChecksum(contents) {
+			return nil, fmt.Errorf("Checksum mismatch for %s. The chain bundle is corrupted", header.Name)
+		}
+		seen[header.Name] = true
+
+		switch {
+		case header.Name == b.Manifest.Definition:
+			b.Definition = contents
+		case path.Dir(header.Name) == BundleChainDir:
+			b.Files[path.Base(header.Name)] = contents
+		}
+	}
+
+	var missing []string
+	for name := range b.Manifest.Checksums {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("The chain bundle is missing files listed in its manifest:\n  %s", strings.Join(missing, "\n  "))
+	}
+	if b.Definition == nil || b.Files["genesis.json"] == nil {
+		return nil, fmt.Errorf("%s is not a chain bundle: the definition or genesis.json file is missing", archive)
+	}
+	return b, nil
+}
+
+// writeBundle writes the manifest and the files (by their path in the
+// bundle) to the archive, adding the checksums to the manifest.
+func writeBundle(archive string, manifest *BundleManifest, files map[string][]byte) error {
+	var names []string
+	for name, content := range files {
+		manifest.Checksums[name] = bundleChecksum(content)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	meta, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeBundleFile(tw, BundleManifestFile, meta, 0644); err != nil {
+		return err
+	}
+	for _, name := range names {
+		mode := int64(0644)
+		if path.Base(name) == "priv_validator.json" {
+			mode = 0600
+		}
+		if err := writeBundleFile(tw, name, files[name], mode); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeBundleFile(tw *tar.Writer, name string, contents []byte, mode int64) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     int64(len(contents)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(contents)
+	return err
+}
+
+func bundleChecksum(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// configSeeds returns the addresses of the seeds setting of the
+// config.toml file (a comma separated string or a list).
+func configSeeds(config []byte) []string {
+	value, found, err := tomlValue(config, "seeds")
+	if err != nil || !found {
+		return nil
+	}
+
+	var list []string
+	switch v := value.(type) {
+	case string:
+		list = strings.Split(v, ",")
+	case []interface{}:
+		for _, seed := range v {
+			list = append(list, fmt.Sprint(seed))
+		}
+	}
+
+	var seeds []string
+	for _, seed := range list {
+		if seed = strings.TrimSpace(seed); seed != "" {
+			seeds = append(seeds, seed)
+		}
+	}
+	return seeds
+}
@@ -1,27 +1,40 @@
 package chains
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/eris-ltd/eris-cli/config"
 	def "github.com/eris-ltd/eris-cli/definitions"
 	ini "github.com/eris-ltd/eris-cli/initialize"
 	"github.com/eris-ltd/eris-cli/list"
 	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/services"
 	"github.com/eris-ltd/eris-cli/tests"
 	"github.com/eris-ltd/eris-cli/util"
 	ver "github.com/eris-ltd/eris-cli/version"
 
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/BurntSushi/toml"
 	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 	logger "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
 )
 
 var (
@@ -190,6 +203,36 @@ func TestRestartChain(t *testing.T) {
 	}
 }
 
+func TestRestartChainRecreate(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	start(t, chainName)
+	name := util.ContainersName(def.TypeChain, chainName)
+	before, err := util.DockerClient.InspectContainer(name)
+	if err != nil {
+		t.Fatalf("expected chain container, got %v", err)
+	}
+
+	do := def.NowDo()
+	do.Name = chainName
+	do.Recreate = true
+	do.Timeout = 1
+	if err := RestartChain(do); err != nil {
+		t.Fatalf("expected chain to be restarted, got %v", err)
+	}
+
+	after, err := util.DockerClient.InspectContainer(name)
+	if err != nil {
+		t.Fatalf("expected chain container, got %v", err)
+	}
+	if after.ID == before.ID {
+		t.Fatalf("expected a new chain container, got the same one")
+	}
+	if n := util.HowManyContainersRunning(chainName, def.TypeChain); n != 1 {
+		t.Fatalf("expecting 1 chain container running, got %v", n)
+	}
+}
+
 func TestExecChain(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -237,6 +280,23 @@ func TestCatChainLocalConfig(t *testing.T) {
 	}
 }
 
+func TestCatChainLocalConfigFormat(t *testing.T) {
+	buf := new(bytes.Buffer)
+	config.GlobalConfig.Writer = buf
+
+	do := def.NowDo()
+	do.Name = chainName
+	do.Type = "toml"
+	do.Format = "json"
+	if err := CatChain(do); err != nil {
+		t.Fatalf("expected getting a local config to succeed, got %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `"name": "`+chainName+`"`) {
+		t.Fatalf("expected the chain definition in JSON, got %v", buf.String())
+	}
+}
+
 func TestCatChainContainerConfig(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -285,6 +345,470 @@ func TestCatChainContainerGenesis(t *testing.T) {
 	}
 }
 
+func TestChainConfigSetGet(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	create(t, chainName)
+
+	do := def.NowDo()
+	do.Name = chainName
+	do.ConfigOpts = []string{"moniker=marmot", "fast_sync=false"}
+	if err := SetChainConfig(do); err != nil {
+		t.Fatalf("expected config to be set, got %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	config.GlobalConfig.Writer = buf
+
+	do = def.NowDo()
+	do.Name = chainName
+	do.Operations.Args = []string{"moniker", "fast_sync"}
+	if err := GetChainConfig(do); err != nil {
+		t.Fatalf("expected config to be read, got %v", err)
+	}
+	if buf.String() != "marmot\nfalse\n" {
+		t.Fatalf("expected the values set, got %q", buf.String())
+	}
+
+	do.Operations.Args = []string{"no_such_key"}
+	if err := GetChainConfig(do); err == nil {
+		t.Fatalf("expected a missing key to fail, got nil")
+	}
+}
+
+func TestSetTOMLValue(t *testing.T) {
+	const config = `# node settings
+moniker = "a"
+fast_sync = true
+
+[bind]
+  address = ""
+  port = 1337
+
+[TLS]
+tls = false
+`
+
+	for _, test := range []struct {
+		key, literal, expected string
+	}{
+		{"moniker", `"b"`, strings.Replace(config, `moniker = "a"`, `moniker = "b"`, 1)},
+		{"log_level", `"debug"`, strings.Replace(config, "fast_sync = true\n", "fast_sync = true\nlog_level = \"debug\"\n", 1)},
+		{"bind.port", "1338", strings.Replace(config, "  port = 1337", "  port = 1338", 1)},
+		{"TLS.cert_path", `"cert"`, config + "cert_path = \"cert\"\n"},
+		{"CORS.enable", "true", config + "\n[CORS]\nenable = true\n"},
+	} {
+		returned := string(util.SetTOMLValue([]byte(config), test.key, test.literal))
+		if returned != test.expected {
+			t.Fatalf("%s: expected %q, got %q", test.key, test.expected, returned)
+		}
+		if value, found, err := tomlValue([]byte(returned), test.key); err != nil || !found || fmt.Sprint(value) != strings.Trim(test.literal, `"`) {
+			t.Fatalf("%s: expected %s to be set, got %v, %v, %v", test.key, test.literal, value, found, err)
+		}
+	}
+
+	if returned := string(util.SetTOMLValue(nil, "bind.port", "1")); returned != "[bind]\nport = 1\n" {
+		t.Fatalf("expected a new table, got %q", returned)
+	}
+}
+
+func TestLookupConfig(t *testing.T) {
+	files := map[string][]byte{
+		"config.toml":      []byte("moniker = \"a\"\nseeds = [\"x\", \"y\"]\n"),
+		"server_conf.toml": []byte("moniker = \"b\"\n[bind]\nport = 1337\n"),
+	}
+
+	for key, expected := range map[string][2]string{
+		"moniker":   {"a", "config.toml"},
+		"seeds":     {`["x", "y"]`, "config.toml"},
+		"bind.port": {"1337", "server_conf.toml"},
+	} {
+		value, file, err := lookupConfig(files, key)
+		if err != nil || value != expected[0] || file != expected[1] {
+			t.Fatalf("%s: expected %v, got %v, %v, %v", key, expected, value, file, err)
+		}
+	}
+
+	for _, key := range []string{"bind", "bind.port.x", "missing"} {
+		if _, _, err := lookupConfig(files, key); err == nil {
+			t.Fatalf("%s: expected an error, got nil", key)
+		}
+	}
+	if _, _, err := lookupConfig(files, "missing"); err == nil {
+		t.Fatalf("expected an error, got nil")
+	} else if _, ok := err.(configKeyNotSetError); !ok {
+		t.Fatalf("expected the key not to be set, got %v", err)
+	}
+	if _, _, err := lookupConfig(files, "bind"); err == nil {
+		t.Fatalf("expected an error, got nil")
+	} else if _, ok := err.(configKeyNotSetError); ok {
+		t.Fatalf("expected a table to be an error other than not set, got %v", err)
+	}
+	broken := map[string][]byte{"config.toml": []byte("moniker = \n")}
+	if _, _, err := lookupConfig(broken, "moniker"); err == nil {
+		t.Fatalf("expected an error, got nil")
+	} else if _, ok := err.(configKeyNotSetError); ok {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+
+	for value, literal := range map[string]string{
+		"true":       "true",
+		"1337":       "1337",
+		`"quoted"`:   `"quoted"`,
+		`["a", "b"]`: `["a", "b"]`,
+		"debug":      `"debug"`,
+		"tcp://x:1":  `"tcp://x:1"`,
+	} {
+		if returned := tomlLiteral(value); returned != literal {
+			t.Fatalf("%s: expected %s, got %s", value, literal, returned)
+		}
+	}
+}
+
+func TestChainSnapshotReset(t *testing.T) {
+	defer tests.RemoveAllContainers()
+	defer os.RemoveAll(SnapshotsPath(chainName))
+
+	create(t, chainName)
+
+	do := def.NowDo()
+	do.Name = chainName
+	do.Tag = "genesis"
+	if err := SnapshotChain(do); err != nil {
+		t.Fatalf("expected chain to be snapshotted, got %v", err)
+	}
+	if _, err := os.Stat(nodeArchive(do.Result, 1)); err != nil {
+		t.Fatalf("expected the node archive to exist, got %v", err)
+	}
+	if n := util.HowManyContainersRunning(chainName, def.TypeChain); n != 1 {
+		t.Fatalf("expecting 1 chain container running after the snapshot, got %v", n)
+	}
+
+	if err := SnapshotChain(do); err == nil {
+		t.Fatalf("expected a second snapshot with the same tag to fail, got nil")
+	}
+	do.Force = true
+	if err := SnapshotChain(do); err != nil {
+		t.Fatalf("expected the snapshot to be replaced, got %v", err)
+	}
+
+	exec(t, chainName, []string{"touch", path.Join(chainDir(&def.Chain{Name: chainName}), "marmot")})
+
+	do = def.NowDo()
+	do.Name = chainName
+	do.Tag = "genesis"
+	if err := ResetChain(do); err != nil {
+		t.Fatalf("expected chain to be reset, got %v", err)
+	}
+	if out := exec(t, chainName, []string{"ls", chainDir(&def.Chain{Name: chainName})}); strings.Contains(out, "marmot") {
+		t.Fatalf("expected the chain directory to be restored, got %q", out)
+	}
+
+	if err := RmSnapshot(do); err != nil {
+		t.Fatalf("expected snapshot to be removed, got %v", err)
+	}
+	if err := ResetChain(do); err == nil {
+		t.Fatalf("expected reset to a removed snapshot to fail, got nil")
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	const name = "snapshot-chain"
+	defer os.RemoveAll(SnapshotsPath(name))
+
+	now := time.Now().UTC()
+	for i, tag := range []string{"second", "first"} {
+		dir, err := snapshotPath(name, tag)
+		if err != nil {
+			t.Fatalf("expected a valid tag, got %v", err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("expected snapshot dir to be created, got %v", err)
+		}
+		manifest, _ := json.Marshal(&Snapshot{Tag: tag, Chain: name, Nodes: 1, Created: now.Add(-time.Duration(i) * time.Hour)})
+		if err := ioutil.WriteFile(filepath.Join(dir, snapshotManifestFile), manifest, 0644); err != nil {
+			t.Fatalf("expected manifest to be written, got %v", err)
+		}
+	}
+	// Directories without a manifest (e.g. an interrupted snapshot) are skipped.
+	os.MkdirAll(filepath.Join(SnapshotsPath(name), "broken"), 0755)
+
+	snapshots, err := ListSnapshots(name)
+	if err != nil {
+		t.Fatalf("expected snapshots to be listed, got %v", err)
+	}
+	var tags []string
+	for _, snapshot := range snapshots {
+		tags = append(tags, snapshot.Tag)
+	}
+	if !reflect.DeepEqual(tags, []string{"first", "second"}) {
+		t.Fatalf("expected snapshots oldest first, got %v", tags)
+	}
+
+	if _, err := ReadSnapshot(name, "missing"); err == nil {
+		t.Fatalf("expected a missing snapshot to fail, got nil")
+	}
+	for _, tag := range []string{"", "../escape", ".hidden", "a/b"} {
+		if _, err := snapshotPath(name, tag); err == nil {
+			t.Fatalf("%q: expected an invalid tag to fail, got nil", tag)
+		}
+	}
+}
+
+func TestChainUpgradeRollback(t *testing.T) {
+	defer tests.RemoveAllContainers()
+	defer os.RemoveAll(SnapshotsPath(chainName))
+
+	create(t, chainName)
+
+	before, err := loaders.LoadChainDefinition(chainName, false)
+	if err != nil {
+		t.Fatalf("expected chain definition to load, got %v", err)
+	}
+
+	// The data image doesn't run a chain, so the upgrade has to roll back.
+	do := def.NowDo()
+	do.Name = chainName
+	do.Image = path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_DATA)
+	do.Wait = 10
+	if err := UpgradeChain(do); err == nil {
+		t.Fatalf("expected the upgrade to fail, got nil")
+	}
+
+	after, err := loaders.LoadChainDefinition(chainName, false)
+	if err != nil {
+		t.Fatalf("expected chain definition to load, got %v", err)
+	}
+	if after.Service.Image != before.Service.Image {
+		t.Fatalf("expected the image to be rolled back to %s, got %s", before.Service.Image, after.Service.Image)
+	}
+	if after.PreviousImage != "" {
+		t.Fatalf("expected no previous image after a rollback, got %s", after.PreviousImage)
+	}
+	if n := util.HowManyContainersRunning(chainName, def.TypeChain); n != 1 {
+		t.Fatalf("expecting 1 chain container running after the rollback, got %v", n)
+	}
+	if _, err := ReadSnapshot(chainName, UpgradeSnapshot); err != nil {
+		t.Fatalf("expected the pre-upgrade snapshot to exist, got %v", err)
+	}
+
+	do.Image = before.Service.Image
+	if err := UpgradeChain(do); err == nil {
+		t.Fatalf("expected an upgrade to the same image to fail, got nil")
+	}
+}
+
+func TestChainUpgrade(t *testing.T) {
+	defer tests.RemoveAllContainers()
+	defer os.RemoveAll(SnapshotsPath(chainName))
+
+	create(t, chainName)
+
+	before, err := loaders.LoadChainDefinition(chainName, false)
+	if err != nil {
+		t.Fatalf("expected chain definition to load, got %v", err)
+	}
+
+	// The same image under another tag runs the chain just as well.
+	repo, _ := util.SplitImage(before.Service.Image)
+	image := repo + ":upgrade-test"
+	if err := util.DockerClient.TagImage(before.Service.Image, docker.TagImageOptions{Repo: repo, Tag: "upgrade-test", Force: true}); err != nil {
+		t.Fatalf("expected the image to be tagged, got %v", err)
+	}
+	defer util.DockerClient.RemoveImage(image)
+
+	do := def.NowDo()
+	do.Name = chainName
+	do.Image = image
+	if err := UpgradeChain(do); err != nil {
+		t.Fatalf("expected the chain to be upgraded, got %v", err)
+	}
+
+	var upgraded struct {
+		Previous       string `toml:"previous_image"`
+		PreviousDigest string `toml:"previous_image_digest"`
+		Service        struct {
+			Image string `toml:"image"`
+		} `toml:"service"`
+	}
+	if _, err := toml.DecodeFile(filepath.Join(common.ChainsPath, chainName+".toml"), &upgraded); err != nil {
+		t.Fatalf("expected the chain definition file to be read, got %v", err)
+	}
+	if upgraded.Service.Image != image || upgraded.Previous != before.Service.Image || upgraded.PreviousDigest == "" {
+		t.Fatalf("expected %s with the previous image %s and its digest recorded, got %+v", image, before.Service.Image, upgraded)
+	}
+
+	do = def.NowDo()
+	do.Name = chainName
+	do.Rollback = true
+	if err := UpgradeChain(do); err != nil {
+		t.Fatalf("expected the chain to be rolled back, got %v", err)
+	}
+	after, err := loaders.LoadChainDefinition(chainName, false)
+	if err != nil {
+		t.Fatalf("expected chain definition to load, got %v", err)
+	}
+	if after.Service.Image != before.Service.Image || after.PreviousImage != image {
+		t.Fatalf("expected %s with the previous image %s, got %s and %s", before.Service.Image, image, after.Service.Image, after.PreviousImage)
+	}
+	if n := util.HowManyContainersRunning(chainName, def.TypeChain); n != 1 {
+		t.Fatalf("expecting 1 chain container running after the rollback, got %v", n)
+	}
+}
+
+func TestWriteChainDefinitionTOML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_chains")
+	if err != nil {
+		t.Fatalf("expected a temp dir, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { common.ChainsPath = path }(common.ChainsPath)
+	common.ChainsPath = dir
+
+	chain := def.BlankChain()
+	chain.Name = "simplechain"
+	chain.ChainID = "simplechain"
+	chain.PreviousImage = "quay.io/eris/erisdb:0.11.3"
+	chain.PreviousImageDigest = "quay.io/eris/erisdb@sha256:1111"
+	chain.Service.Image = "quay.io/eris/erisdb:0.11.4"
+	chain.Hooks.PostStart = []*def.Hook{{Command: "echo started", Container: true}}
+	chain.Hooks.PreStop = []*def.Hook{{Action: "dns deregister", OnFailure: def.HookWarn}}
+	if err := WriteChainDefinitionFile(chain, filepath.Join(dir, "simplechain.toml")); err != nil {
+		t.Fatalf("expected the chain definition file to be written, got %v", err)
+	}
+
+	read, err := loaders.ReadChainDefinition("simplechain")
+	if err != nil {
+		t.Fatalf("expected the chain definition file to be read, got %v", err)
+	}
+	if read.Service.Image != chain.Service.Image {
+		t.Fatalf("expected image %s, got %s", chain.Service.Image, read.Service.Image)
+	}
+	if read.PreviousImage != chain.PreviousImage || read.PreviousImageDigest != chain.PreviousImageDigest {
+		t.Fatalf("expected the previous image %s (%s), got %s (%s)", chain.PreviousImage, chain.PreviousImageDigest, read.PreviousImage, read.PreviousImageDigest)
+	}
+	if !reflect.DeepEqual(read.Hooks, chain.Hooks) {
+		t.Fatalf("expected hooks %+v, got %+v", chain.Hooks, read.Hooks)
+	}
+}
+
+func TestWaitForProgress(t *testing.T) {
+	heights := []int{5, 5, 6}
+	height := func() (int, error) {
+		if len(heights) == 0 {
+			return 0, errNodeStopped
+		}
+		h := heights[0]
+		heights = heights[1:]
+		return h, nil
+	}
+	if err := waitForProgress(height, time.Second, time.Millisecond); err != nil {
+		t.Fatalf("expected the chain to make progress, got %v", err)
+	}
+
+	stuck := func() (int, error) { return 5, nil }
+	if err := waitForProgress(stuck, 10*time.Millisecond, time.Millisecond); err == nil {
+		t.Fatalf("expected a stuck chain to fail, got nil")
+	}
+
+	unreachable := func() (int, error) { return 0, fmt.Errorf("connection refused") }
+	if err := waitForProgress(unreachable, 10*time.Millisecond, time.Millisecond); err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected an unreachable chain to fail, got %v", err)
+	}
+
+	stopped := func() (int, error) { return 0, errNodeStopped }
+	if err := waitForProgress(stopped, time.Hour, time.Millisecond); err != errNodeStopped {
+		t.Fatalf("expected a stopped chain to fail right away, got %v", err)
+	}
+}
+
+func TestQueryNodeStatus(t *testing.T) {
+	for _, results := range []map[string]string{
+		// Older tendermint versions return [TYPE, RESULT] pairs.
+		{
+			"/status":     `[32, {"node_info": {"moniker": "marmot", "network": "simplechain"}, "latest_block_height": 42, "latest_block_time": 1461000000000000000}]`,
+			"/net_info":   `[33, {"listening": true, "peers": [{"is_outbound": true}, {"is_outbound": false}]}]`,
+			"/validators": `[34, {"block_height": 42, "validators": [{"address": "37236df2", "pub_key": [1, "CB36"], "voting_power": 10}]}]`,
+		},
+		{
+			"/status":     `{"node_info": {"moniker": "marmot", "network": "simplechain"}, "latest_block_height": 42, "latest_block_time": "2016-04-18T17:20:00Z"}`,
+			"/net_info":   `{"listening": true, "peers": [{}, {}]}`,
+			"/validators": `{"block_height": 42, "validators": [{"address": "37236df2", "voting_power": 10}]}`,
+		},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, ok := results[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": "", "result": %s, "error": ""}`, result)
+		}))
+
+		rpc := strings.TrimPrefix(server.URL, "http://")
+		status, err := QueryNodeStatus(rpc)
+		server.Close()
+		if err != nil {
+			t.Fatalf("expected status to be queried, got %v", err)
+		}
+
+		expected := &NodeStatus{
+			RPC:        rpc,
+			ChainID:    "simplechain",
+			Moniker:    "marmot",
+			Height:     42,
+			BlockTime:  time.Unix(1461000000, 0).UTC(),
+			Peers:      2,
+			Validators: []*StatusValidator{{Address: "37236df2", VotingPower: 10}},
+		}
+		if !reflect.DeepEqual(status, expected) {
+			t.Fatalf("expected %+v, got %+v", expected, status)
+		}
+	}
+}
+
+func TestQueryNodeStatusBad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jsonrpc": "2.0", "id": "", "result": null, "error": "marmots are asleep"}`)
+	}))
+	rpc := strings.TrimPrefix(server.URL, "http://")
+	if _, err := QueryNodeStatus(rpc); err == nil || !strings.Contains(err.Error(), "marmots are asleep") {
+		t.Fatalf("expected the RPC error, got %v", err)
+	}
+
+	server.Close()
+	if _, err := QueryNodeStatus(rpc); err == nil {
+		t.Fatalf("expected an unreachable RPC to fail, got nil")
+	}
+}
+
+func TestPrintNodeStatuses(t *testing.T) {
+	statuses := []*NodeStatus{
+		{Node: 1, RPC: "127.0.0.1:46657", ChainID: "simplechain", Height: 42, Peers: 1,
+			Validators: []*StatusValidator{{Address: "37236df2", VotingPower: 10}}},
+		{Node: 2, Error: "not running"},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := printNodeStatuses(buf, statuses, false); err != nil {
+		t.Fatalf("expected status to be printed, got %v", err)
+	}
+	for _, expected := range []string{"simplechain", "42", "127.0.0.1:46657", "not running", "37236DF2"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected %q in the table, got %q", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := printNodeStatuses(buf, statuses, true); err != nil {
+		t.Fatalf("expected status to be printed, got %v", err)
+	}
+	var decoded []*NodeStatus
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[0].Height != 42 || decoded[1].Error != "not running" {
+		t.Fatalf("expected the statuses in JSON, got %v, %s", err, buf.String())
+	}
+}
+
 func TestChainsNewDirGenesis(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -349,6 +873,246 @@ func TestChainsNewKeysImported(t *testing.T) {
 	}
 }
 
+func TestChainsNewNodes(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	const chain = "test-nodes"
+
+	do := def.NowDo()
+	do.Name = chain
+	do.N = 3
+	do.Operations.PublishAllPorts = true
+	if err := NewChain(do); err != nil {
+		t.Fatalf("expected a new chain network to be created, got %v", err)
+	}
+
+	if n := util.HowManyContainersRunning(chain, def.TypeChain); n != 3 {
+		t.Fatalf("expecting 3 chain containers running, got %v", n)
+	}
+	if n := util.HowManyContainersExisting(chain, def.TypeData); n != 3 {
+		t.Fatalf("expecting 3 data containers, got %v", n)
+	}
+
+	// The other nodes are stopped even if the first one isn't running.
+	first := def.BlankOperation()
+	first.SrvContainerName = util.NumberedContainersName(def.TypeChain, chain, 1)
+	if err := perform.DockerStop(def.BlankService(), first, 0); err != nil {
+		t.Fatalf("expected the first node to be stopped, got %v", err)
+	}
+	do = def.NowDo()
+	do.Name = chain
+	if err := KillChain(do); err != nil {
+		t.Fatalf("expected chain network to be stopped, got %v", err)
+	}
+	if n := util.HowManyContainersRunning(chain, def.TypeChain); n != 0 {
+		t.Fatalf("expecting 0 chain containers running, got %v", n)
+	}
+
+	do = def.NowDo()
+	do.Name, do.Rm, do.RmD = chain, true, true
+	if err := KillChain(do); err != nil {
+		t.Fatalf("expected chain network to be removed, got %v", err)
+	}
+	if n := util.HowManyContainersExisting(chain, def.TypeChain); n != 0 {
+		t.Fatalf("expecting 0 chain containers, got %v", n)
+	}
+	if n := util.HowManyContainersExisting(chain, def.TypeData); n != 0 {
+		t.Fatalf("expecting 0 data containers, got %v", n)
+	}
+}
+
+func TestNodeLinksAndSeeds(t *testing.T) {
+	if links, seeds := nodeLinks("net", 1), nodeSeeds(1); links != nil || seeds != "" {
+		t.Fatalf("expected no links and seeds for the first node, got %v, %q", links, seeds)
+	}
+
+	links := nodeLinks("net", 3)
+	if len(links) != 2 || links[0] != "eris_chain_net_1:node1" || links[1] != "eris_chain_net_2:node2" {
+		t.Fatalf("expected links to the first two nodes, got %v", links)
+	}
+	if seeds := nodeSeeds(3); seeds != "node1:46656,node2:46656" {
+		t.Fatalf("expected seeds of the first two nodes, got %q", seeds)
+	}
+}
+
+func TestSetConfigSeeds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seeds")
+	if err != nil {
+		t.Fatalf("can't create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for config, expected := range map[string]string{
+		"moniker = \"a\"\nseeds = \"\"\n\n[p2p]\nseeds = \"x\"\n": "moniker = \"a\"\nseeds = \"node1:46656\"\n\n[p2p]\nseeds = \"x\"\n",
+		"moniker = \"a\"\n[p2p]\n":                                "moniker = \"a\"\nseeds = \"node1:46656\"\n[p2p]\n",
+		"moniker = \"a\"\n":                                       "moniker = \"a\"\nseeds = \"node1:46656\"\n",
+	} {
+		file := filepath.Join(dir, "config.toml")
+		if err := ioutil.WriteFile(file, []byte(config), 0600); err != nil {
+			t.Fatalf("can't write config file: %v", err)
+		}
+		if err := setConfigSeeds(file, "node1:46656"); err != nil {
+			t.Fatalf("expected seeds to be set, got %v", err)
+		}
+		if content, _ := ioutil.ReadFile(file); string(content) != expected {
+			t.Fatalf("expected config %q, got %q", expected, content)
+		}
+	}
+
+	if err := setConfigSeeds(filepath.Join(dir, "missing.toml"), "node1:46656"); !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestValidatorDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validators")
+	if err != nil {
+		t.Fatalf("can't create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, sub := range []string{"net_full_001", "net_full_000", "net_participant_000"} {
+		os.MkdirAll(filepath.Join(dir, sub), 0700)
+	}
+	for _, file := range []string{"net_full_000/priv_validator.json", "net_full_001/priv_validator.json", "genesis.json"} {
+		ioutil.WriteFile(filepath.Join(dir, file), []byte("{}"), 0600)
+	}
+
+	dirs := validatorDirs(dir)
+	if len(dirs) != 2 || dirs[0] != filepath.Join(dir, "net_full_000") || dirs[1] != filepath.Join(dir, "net_full_001") {
+		t.Fatalf("expected 2 validator directories in order, got %v", dirs)
+	}
+}
+
+func TestKeyAddress(t *testing.T) {
+	pub, _ := hex.DecodeString(ini.DefaultPubKeys[0])
+	if address := keyAddress(pub); address != ini.DefaultAddr {
+		t.Fatalf("expected address %v, got %v", ini.DefaultAddr, address)
+	}
+}
+
+func TestParseAccountTypes(t *testing.T) {
+	types, err := loadAccountTypes()
+	if err != nil {
+		t.Fatalf("expected account types to load, got %v", err)
+	}
+
+	numbers, err := parseAccountTypes(types, []string{"full:2", "Participant:0"})
+	if err != nil {
+		t.Fatalf("expected account types to parse, got %v", err)
+	}
+	if len(numbers) != 2 || numbers[0].Type.Name != "Full" || numbers[0].Number != 2 || numbers[1].Number != 0 {
+		t.Fatalf("expected 2 Full and 0 Participant accounts, got %+v", numbers)
+	}
+
+	for _, bad := range []string{"Full", "Full:two", "Full:-1", "Marmot:1"} {
+		if _, err := parseAccountTypes(types, []string{bad}); err == nil {
+			t.Fatalf("%q: expected an error, got nil", bad)
+		}
+	}
+}
+
+func TestMakeChainAccountTypes(t *testing.T) {
+	const chain = "test-make"
+	defer os.RemoveAll(filepath.Join(common.ChainsPath, chain))
+
+	do := def.NowDo()
+	do.Name = chain
+	do.AccountTypes = []string{"Full:2", "Participant:1"}
+	if err := MakeChain(do); err != nil {
+		t.Fatalf("expected chain to be made, got %v", err)
+	}
+
+	genesis := readGenesis(t, filepath.Join(common.ChainsPath, chain, "genesis.json"))
+	if genesis.ChainID != chain || len(genesis.Accounts) != 3 || len(genesis.Validators) != 2 {
+		t.Fatalf("expected 3 accounts and 2 validators, got %+v", genesis)
+	}
+
+	for i, validator := range genesis.Validators {
+		dir := filepath.Join(common.ChainsPath, chain, fmt.Sprintf("%s_full_%03d", chain, i))
+		if dirGenesis := readGenesis(t, filepath.Join(dir, "genesis.json")); !reflect.DeepEqual(dirGenesis, genesis) {
+			t.Fatalf("expected the same genesis file in %s, got %+v", dir, dirGenesis)
+		}
+
+		priv := &privValidator{}
+		content, err := ioutil.ReadFile(filepath.Join(dir, "priv_validator.json"))
+		if err != nil {
+			t.Fatalf("expected priv_validator.json in %s, got %v", dir, err)
+		}
+		if err := json.Unmarshal(content, priv); err != nil {
+			t.Fatalf("expected priv_validator.json to be read, got %v", err)
+		}
+		if !reflect.DeepEqual(priv.PubKey, validator.PubKey) || validator.UnbondTo[0].Address != priv.Address {
+			t.Fatalf("expected validator %+v to have the keys of %+v", validator, priv)
+		}
+		pub, _ := hex.DecodeString(priv.PubKey[1].(string))
+		if address := keyAddress(pub); address != priv.Address {
+			t.Fatalf("expected address %v, got %v", address, priv.Address)
+		}
+	}
+
+	participant := genesis.Accounts[2]
+	if participant.Name != chain+"_participant_000" || participant.Permissions.Base.Perms&permissionBits["root"] != 0 {
+		t.Fatalf("expected a participant without root permissions, got %+v", participant)
+	}
+
+	// Making the chain again from the CSV files gives the same genesis file.
+	do = def.NowDo()
+	do.Name = chain
+	do.Known = true
+	do.ChainMakeVals = filepath.Join(common.ChainsPath, chain, "validators.csv")
+	do.ChainMakeActs = filepath.Join(common.ChainsPath, chain, "accounts.csv")
+	if err := MakeChain(do); err != nil {
+		t.Fatalf("expected chain to be made from known keys, got %v", err)
+	}
+	if known := readGenesis(t, filepath.Join(common.ChainsPath, chain, "genesis.json")); !reflect.DeepEqual(known, genesis) {
+		t.Fatalf("expected genesis %+v, got %+v", genesis, known)
+	}
+}
+
+func TestMakeChainPackages(t *testing.T) {
+	const chain = "test-make-packages"
+	defer os.RemoveAll(filepath.Join(common.ChainsPath, chain))
+
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		do := def.NowDo()
+		do.Name = chain
+		do.ChainType = "simplechain"
+		do.Tarball = ext == ".tar.gz"
+		do.ZipFile = ext == ".zip"
+		if err := MakeChain(do); err != nil {
+			t.Fatalf("expected chain to be made, got %v", err)
+		}
+
+		account := filepath.Join(common.ChainsPath, chain, chain+"_full_000")
+		if _, err := os.Stat(account + ext); err != nil {
+			t.Fatalf("expected a %s package, got %v", ext, err)
+		}
+		if _, err := os.Stat(account); !os.IsNotExist(err) {
+			t.Fatalf("expected the account directory to be removed, got %v", err)
+		}
+	}
+}
+
+func TestMakeChainKnownBad(t *testing.T) {
+	const chain = "test-make-known"
+	defer os.RemoveAll(filepath.Join(common.ChainsPath, chain))
+
+	csv := filepath.Join(os.TempDir(), "test-make-known.csv")
+	defer os.Remove(csv)
+	if err := ioutil.WriteFile(csv, []byte(ini.DefaultAddr+",10\n"), 0600); err != nil {
+		t.Fatalf("can't write csv file: %v", err)
+	}
+
+	do := def.NowDo()
+	do.Name = chain
+	do.Known = true
+	do.ChainMakeVals, do.ChainMakeActs = csv, csv
+	if err := MakeChain(do); err == nil {
+		t.Fatalf("expected validators given by address to fail, got nil")
+	}
+}
+
 func TestLogsChain(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -458,6 +1222,328 @@ func TestRenameChain(t *testing.T) {
 	}
 }
 
+func TestCloneChain(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	const clone = "test-chain-clone"
+	defer os.Remove(filepath.Join(common.ChainsPath, clone+".toml"))
+
+	create(t, chainName)
+
+	// As if the chain was upgraded before.
+	source, err := loaders.ReadChainDefinition(chainName)
+	if err != nil {
+		t.Fatalf("expected the chain definition to be read, got %v", err)
+	}
+	source.PreviousImage = "quay.io/eris/erisdb:0.11.3"
+	source.PreviousImageDigest = "quay.io/eris/erisdb@sha256:1111"
+	if err := WriteChainDefinitionFile(source, filepath.Join(common.ChainsPath, chainName+".toml")); err != nil {
+		t.Fatalf("expected the chain definition to be written, got %v", err)
+	}
+
+	do := def.NowDo()
+	do.Name = chainName
+	do.NewName = clone
+	if err := CloneChain(do); err != nil {
+		t.Fatalf("expected chain to be cloned, got %v", err)
+	}
+	if !util.IsKnownChain(clone) {
+		t.Fatalf("expected the clone to be a known chain")
+	}
+	if n := util.HowManyContainersExisting(clone, def.TypeData); n != 1 {
+		t.Fatalf("expecting 1 data container of the clone, got %v", n)
+	}
+	if n := util.HowManyContainersRunning(chainName, def.TypeChain); n != 1 {
+		t.Fatalf("expecting the cloned chain to be running again, got %v", n)
+	}
+	original, err := loaders.LoadChainDefinition(chainName, false)
+	if err != nil {
+		t.Fatalf("expected the chain definition to load, got %v", err)
+	}
+	cloned, err := loaders.LoadChainDefinition(clone, false)
+	if err != nil {
+		t.Fatalf("expected the clone definition to load, got %v", err)
+	}
+	if cloned.Service.Image != original.Service.Image {
+		t.Fatalf("expected the clone to keep image %v, got %v", original.Service.Image, cloned.Service.Image)
+	}
+	if cloned.PreviousImage != source.PreviousImage || cloned.PreviousImageDigest != source.PreviousImageDigest {
+		t.Fatalf("expected the clone to keep the previous image %v (%v), got %v (%v)", source.PreviousImage, source.PreviousImageDigest, cloned.PreviousImage, cloned.PreviousImageDigest)
+	}
+
+	if err := CloneChain(do); err == nil {
+		t.Fatalf("expected cloning to an existing chain to fail, got nil")
+	}
+
+	kill(t, chainName)
+	start(t, clone)
+	if n := util.HowManyContainersRunning(clone, def.TypeChain); n != 1 {
+		t.Fatalf("expecting 1 chain container of the clone, got %v", n)
+	}
+}
+
+func TestCloneRewrite(t *testing.T) {
+	validator, _ := newAccount("validator", 1000, 100, nil)
+	account, _ := newAccount("account", 2000, 0, nil)
+	genesis, _ := json.Marshal(makeGenesis("staging", []*chainAccount{validator}, []*chainAccount{validator, account}))
+	priv, _ := json.Marshal(&privValidator{
+		Address:    validator.Address,
+		PubKey:     []interface{}{keyTypeEd25519, validator.PubKey},
+		PrivKey:    []interface{}{keyTypeEd25519, validator.PrivKey},
+		LastHeight: 42,
+	})
+
+	files := []struct {
+		name, content string
+	}{
+		{".eris/", ""},
+		{".eris/chains/staging/", ""},
+		{".eris/chains/staging/genesis.json", string(genesis)},
+		{".eris/chains/staging/priv_validator.json", string(priv)},
+		{".eris/chains/staging/config.toml", "moniker = \"marmot\"\n"},
+		{".eris/chains/staging/data/", ""},
+		{".eris/chains/staging/data/state.db", "state"},
+		{".eris/keys/data/key", "key"},
+	}
+	in := new(bytes.Buffer)
+	tw := tar.NewWriter(in)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(file.name, "/") {
+			header.Typeflag = tar.TypeDir
+		}
+		tw.WriteHeader(header)
+		tw.Write([]byte(file.content))
+	}
+	tw.Close()
+
+	newKey, _ := newAccount("", 0, 0, nil)
+	rw := &cloneRewrite{
+		from:    ".eris/chains/staging",
+		to:      ".eris/chains/debug",
+		chainID: "debug",
+		keys:    map[string]*chainAccount{validator.PubKey: newKey},
+		reset:   true,
+	}
+	reader := rw.stream(bytes.NewReader(in.Bytes()))
+	defer reader.Close()
+
+	out := make(map[string][]byte)
+	var names []string
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected a tar stream, got %v", err)
+		}
+		names = append(names, header.Name)
+		out[header.Name], _ = ioutil.ReadAll(tr)
+	}
+
+	expected := []string{
+		".eris/",
+		".eris/chains/debug/",
+		".eris/chains/debug/genesis.json",
+		".eris/chains/debug/priv_validator.json",
+		".eris/chains/debug/config.toml",
+		".eris/keys/data/key",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected files %v, got %v", expected, names)
+	}
+
+	rewritten := &genesisDoc{}
+	if err := json.Unmarshal(out[".eris/chains/debug/genesis.json"], rewritten); err != nil {
+		t.Fatalf("expected genesis file to be rewritten, got %v", err)
+	}
+	if rewritten.ChainID != "debug" {
+		t.Fatalf("expected chain ID debug, got %v", rewritten.ChainID)
+	}
+	if key := rewritten.Validators[0].PubKey[1]; key != newKey.PubKey {
+		t.Fatalf("expected the new validator key %v, got %v", newKey.PubKey, key)
+	}
+	if address := rewritten.Validators[0].UnbondTo[0].Address; address != newKey.Address {
+		t.Fatalf("expected unbonding to the new address %v, got %v", newKey.Address, address)
+	}
+	if rewritten.Accounts[0].Address != newKey.Address || rewritten.Accounts[1].Address != account.Address {
+		t.Fatalf("expected only the validator account address to change, got %v, %v", rewritten.Accounts[0].Address, rewritten.Accounts[1].Address)
+	}
+	if rewritten.Accounts[1].Amount != 2000 {
+		t.Fatalf("expected amounts to be kept, got %v", rewritten.Accounts[1].Amount)
+	}
+
+	var rewrittenPriv privValidator
+	if err := json.Unmarshal(out[".eris/chains/debug/priv_validator.json"], &rewrittenPriv); err != nil {
+		t.Fatalf("expected priv_validator.json to be rewritten, got %v", err)
+	}
+	if rewrittenPriv.Address != newKey.Address || rewrittenPriv.PrivKey[1] != newKey.PrivKey || rewrittenPriv.LastHeight != 0 {
+		t.Fatalf("expected the new key with the last height reset, got %+v", rewrittenPriv)
+	}
+
+	if string(out[".eris/keys/data/key"]) != "key" {
+		t.Fatalf("expected files outside the chain directory to be kept, got %q", out[".eris/keys/data/key"])
+	}
+}
+
+func TestChainBundleUnbundle(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	const joined = "test-chain-joined"
+	defer os.Remove(filepath.Join(common.ChainsPath, joined+".toml"))
+
+	create(t, chainName)
+
+	archive := filepath.Join(erisDir, "bundle.tar.gz")
+	defer os.Remove(archive)
+
+	do := def.NowDo()
+	do.Name = chainName
+	do.BundleFile = archive
+	do.NoPriv = true
+	do.ConfigOpts = []string{"seeds=1.2.3.4:46656"}
+	if err := BundleChain(do); err != nil {
+		t.Fatalf("expected chain to be bundled, got %v", err)
+	}
+
+	b, err := ReadBundle(archive)
+	if err != nil {
+		t.Fatalf("expected the bundle to be read, got %v", err)
+	}
+	if b.Files["priv_validator.json"] != nil || b.Manifest.Validator {
+		t.Fatalf("expected no validator key in the bundle")
+	}
+	if !reflect.DeepEqual(b.Manifest.Seeds, []string{"1.2.3.4:46656"}) {
+		t.Fatalf("expected the seeds set, got %v", b.Manifest.Seeds)
+	}
+
+	do = def.NowDo()
+	do.Source = archive
+	do.NewName = joined
+	if err := UnbundleChain(do); err != nil {
+		t.Fatalf("expected chain to be unbundled, got %v", err)
+	}
+	if !util.IsKnownChain(joined) {
+		t.Fatalf("expected the unbundled chain to be known")
+	}
+	if n := util.HowManyContainersExisting(joined, def.TypeData); n != 1 {
+		t.Fatalf("expecting 1 data container, got %v", n)
+	}
+	if err := UnbundleChain(do); err == nil {
+		t.Fatalf("expected unbundling over an existing chain to fail, got nil")
+	}
+}
+
+func TestReadBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_bundle")
+	if err != nil {
+		t.Fatalf("expected a temp dir, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(files map[string][]byte) string {
+		archive := filepath.Join(dir, "bundle.tar.gz")
+		manifest := &BundleManifest{Name: "simplechain", ChainID: "simplechain", Definition: "definition/simplechain.toml", Checksums: make(map[string]string)}
+		if err := writeBundle(archive, manifest, files); err != nil {
+			t.Fatalf("expected the bundle to be written, got %v", err)
+		}
+		return archive
+	}
+
+	archive := write(map[string][]byte{
+		"definition/simplechain.toml": []byte("name = \"simplechain\"\n"),
+		"chain/genesis.json":          []byte("{}"),
+		"chain/config.toml":           []byte("seeds = \"a:1, b:2\"\n"),
+	})
+	b, err := ReadBundle(archive)
+	if err != nil {
+		t.Fatalf("expected the bundle to be read, got %v", err)
+	}
+	if string(b.Definition) != "name = \"simplechain\"\n" || string(b.Files["genesis.json"]) != "{}" || len(b.Files) != 2 {
+		t.Fatalf("expected the bundled files, got %q %v", b.Definition, b.Files)
+	}
+	if seeds := configSeeds(b.Files["config.toml"]); !reflect.DeepEqual(seeds, []string{"a:1", "b:2"}) {
+		t.Fatalf("expected the seeds, got %v", seeds)
+	}
+
+	archive = write(map[string][]byte{"definition/simplechain.toml": []byte("")})
+	if _, err := ReadBundle(archive); err == nil {
+		t.Fatalf("expected a bundle without genesis.json to fail, got nil")
+	}
+
+	// Corrupt a file after the checksums are taken.
+	b.Manifest.Checksums["chain/genesis.json"] = bundleChecksum([]byte("[]"))
+	out, _ := os.Create(archive)
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	meta, _ := json.Marshal(b.Manifest)
+	writeBundleFile(tw, BundleManifestFile, meta, 0644)
+	writeBundleFile(tw, "chain/genesis.json", []byte("{}"), 0644)
+	tw.Close()
+	gz.Close()
+	out.Close()
+	if _, err := ReadBundle(archive); err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+}
+
+func TestCheckUnbundle(t *testing.T) {
+	if err := checkUnbundle("simplechain", "definition/simplechain.yml"); err != nil {
+		t.Fatalf("expected the bundle to be accepted, got %v", err)
+	}
+	for _, name := range []string{"", "..", "../../.ssh/authorized_keys", "a/b", `a\b`} {
+		if err := checkUnbundle(name, "definition/simplechain.toml"); err == nil {
+			t.Fatalf("expected chain name %q to be rejected, got nil", name)
+		}
+	}
+	for _, definition := range []string{"definition/simplechain", "definition/simplechain.sh"} {
+		if err := checkUnbundle("simplechain", definition); err == nil {
+			t.Fatalf("expected definition file %q to be rejected, got nil", definition)
+		}
+	}
+}
+
+func TestWriteUnbundledDefinition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_bundle")
+	if err != nil {
+		t.Fatalf("expected a temp dir, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { common.ChainsPath = path }(common.ChainsPath)
+	common.ChainsPath = dir
+
+	b := &Bundle{
+		Manifest: &BundleManifest{Name: "simplechain", ChainID: "simplechain", Definition: "definition/simplechain.toml"},
+		Definition: []byte(`name = "simplechain"
+chain_id = "simplechain"
+previous_image = "quay.io/eris/erisdb:0.11.3"
+
+[service]
+image = "quay.io/eris/erisdb:0.11.4"
+
+[[hooks.post_start]]
+command = "echo started"
+container = true
+`),
+	}
+	chain, err := writeUnbundledDefinition(b, "joined")
+	if err != nil {
+		t.Fatalf("expected the definition file to be written, got %v", err)
+	}
+	if chain.Name != "joined" || chain.ChainID != "simplechain" {
+		t.Fatalf("expected name joined and chain ID simplechain, got %s and %s", chain.Name, chain.ChainID)
+	}
+	if chain.PreviousImage != "quay.io/eris/erisdb:0.11.3" || chain.Service.Image != "quay.io/eris/erisdb:0.11.4" {
+		t.Fatalf("expected the images to be kept, got %s and %s", chain.PreviousImage, chain.Service.Image)
+	}
+	hooks := []*def.Hook{{Command: "echo started", Container: true}}
+	if !reflect.DeepEqual(chain.Hooks.PostStart, hooks) {
+		t.Fatalf("expected the hooks to be kept, got %+v", chain.Hooks)
+	}
+}
+
 func TestRmChain(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -491,6 +1577,134 @@ func TestRmChain(t *testing.T) {
 	}
 }
 
+func TestGarbageCollectChains(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	do := def.NowDo()
+	do.Name = "throwaway"
+	if err := ThrowAwayChain(do); err != nil {
+		t.Fatalf("expected a throwaway chain to be created, got %v", err)
+	}
+	name := do.Name
+
+	chain, err := loaders.LoadChainDefinition(name, false)
+	if err != nil {
+		t.Fatalf("expected chain definition to load, got %v", err)
+	}
+	if chain.ChainType != def.ChainTypeThrowaway {
+		t.Fatalf("expected chain type %q, got %q", def.ChainTypeThrowaway, chain.ChainType)
+	}
+
+	// Too recent to be removed.
+	do = def.NowDo()
+	if err := GarbageCollectChains(do); err != nil {
+		t.Fatalf("expected garbage collection to succeed, got %v", err)
+	}
+	if n := util.HowManyContainersExisting(name, def.TypeChain); n != 1 {
+		t.Fatalf("expecting 1 chain container, got %v", n)
+	}
+
+	do.OlderThan = "0s"
+	do.DryRun = true
+	if err := GarbageCollectChains(do); err != nil {
+		t.Fatalf("expected garbage collection to succeed, got %v", err)
+	}
+	if n := util.HowManyContainersExisting(name, def.TypeChain); n != 1 {
+		t.Fatalf("expecting 1 chain container after a dry run, got %v", n)
+	}
+
+	do.DryRun = false
+	if err := GarbageCollectChains(do); err != nil {
+		t.Fatalf("expected garbage collection to succeed, got %v", err)
+	}
+	if n := util.HowManyContainersExisting(name, def.TypeChain); n != 0 {
+		t.Fatalf("expecting 0 chain containers, got %v", n)
+	}
+	if n := util.HowManyContainersExisting(name, def.TypeData); n != 0 {
+		t.Fatalf("expecting 0 data containers, got %v", n)
+	}
+	if _, err := os.Stat(filepath.Join(common.ChainsPath, name+".toml")); !os.IsNotExist(err) {
+		t.Fatalf("expected the definition file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(common.DataContainersPath, name)); !os.IsNotExist(err) {
+		t.Fatalf("expected the data directory to be removed, got %v", err)
+	}
+}
+
+func TestFindLeftovers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_gc")
+	if err != nil {
+		t.Fatalf("expected temp dir to be created, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	chainsDir := filepath.Join(dir, "chains")
+	dataDir := filepath.Join(dir, "data")
+	for _, d := range []string{chainsDir, filepath.Join(dataDir, "pkg_aaaa"), filepath.Join(dataDir, "pkg_bbbb")} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("expected dir to be created, got %v", err)
+		}
+	}
+	for name, chainType := range map[string]string{"pkg_bbbb": def.ChainTypeThrowaway, "mychain": ""} {
+		contents := fmt.Sprintf("name = %q\nchain_id = %q\nchain_type = %q\n", name, name, chainType)
+		if err := ioutil.WriteFile(filepath.Join(chainsDir, name+".toml"), []byte(contents), 0644); err != nil {
+			t.Fatalf("expected definition file to be written, got %v", err)
+		}
+	}
+
+	files, err := throwawayDefinitions(chainsDir)
+	if err != nil {
+		t.Fatalf("expected definition files to be read, got %v", err)
+	}
+	if len(files) != 1 || files["pkg_bbbb"] == "" {
+		t.Fatalf("expected only pkg_bbbb to be a throwaway chain, got %v", files)
+	}
+
+	now := time.Now()
+	label := func(name string) map[string]string {
+		return map[string]string{
+			def.Namespace + ":" + def.LabelThrowaway: "true",
+			def.Namespace + ":" + def.LabelShortName: name,
+		}
+	}
+	containers := []docker.APIContainers{
+		{ID: "1", Names: []string{"/eris_chain_pkg_aaaa_1"}, Created: now.Add(-2 * time.Hour).Unix(), Labels: label("pkg_aaaa")},
+		{ID: "2", Names: []string{"/eris_data_pkg_aaaa_1"}, Created: now.Add(-3 * time.Hour).Unix(), Labels: label("pkg_aaaa")},
+		{ID: "3", Names: []string{"/eris_chain_mychain_1"}, Created: now.Add(-3 * time.Hour).Unix(), Labels: map[string]string{def.Namespace + ":" + def.LabelShortName: "mychain"}},
+		{ID: "4", Names: []string{"/eris_chain_pkg_cccc_1"}, Created: now.Add(-2 * time.Hour).Unix(), Status: "Up 2 hours", Labels: label("pkg_cccc")},
+		{ID: "5", Names: []string{"/eris_data_pkg_cccc_1"}, Created: now.Add(-2 * time.Hour).Unix(), Status: "Exited (0) 2 hours ago", Labels: label("pkg_cccc")},
+	}
+
+	leftovers := findLeftovers(containers, files, dataDir)
+	if len(leftovers) != 3 {
+		t.Fatalf("expected 3 leftovers, got %v", len(leftovers))
+	}
+	if leftovers["pkg_aaaa"].Running || !leftovers["pkg_cccc"].Running {
+		t.Fatalf("expected only pkg_cccc to be running, got %v and %v", leftovers["pkg_aaaa"], leftovers["pkg_cccc"])
+	}
+	a := leftovers["pkg_aaaa"]
+	if len(a.Containers) != 2 || a.Definition != "" || a.DataDir != filepath.Join(dataDir, "pkg_aaaa") {
+		t.Fatalf("expected 2 containers and a data dir for pkg_aaaa, got %v", a)
+	}
+	if a.Created.Unix() != now.Add(-3*time.Hour).Unix() {
+		t.Fatalf("expected pkg_aaaa to be created with its oldest container, got %v", a.Created)
+	}
+	if b := leftovers["pkg_bbbb"]; len(b.Containers) != 0 || b.Definition == "" || b.DataDir == "" {
+		t.Fatalf("expected a definition file and a data dir for pkg_bbbb, got %v", b)
+	}
+
+	selected := selectLeftovers(leftovers, time.Hour, now, false)
+	if len(selected) != 1 || selected[0].Name != "pkg_aaaa" {
+		t.Fatalf("expected only pkg_aaaa to be old enough and stopped, got %v", selected)
+	}
+	if selected = selectLeftovers(leftovers, 0, now, false); len(selected) != 2 || selected[1].Name != "pkg_bbbb" {
+		t.Fatalf("expected both stopped chains sorted by name, got %v", selected)
+	}
+	if selected = selectLeftovers(leftovers, time.Hour, now, true); len(selected) != 2 || selected[1].Name != "pkg_cccc" {
+		t.Fatalf("expected the running chain to be removed with force, got %v", selected)
+	}
+}
+
 func TestServiceLinkNoChain(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -910,6 +2124,18 @@ func exec(t *testing.T, chain string, args []string) string {
 	return buf.String()
 }
 
+func readGenesis(t *testing.T, file string) *genesisDoc {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("expected genesis file %s, got %v", file, err)
+	}
+	genesis := &genesisDoc{}
+	if err := json.Unmarshal(content, genesis); err != nil {
+		t.Fatalf("expected genesis file %s to be read, got %v", file, err)
+	}
+	return genesis
+}
+
 func mockChainDefinitionFile(name string) error {
 	definition := loaders.MockChainDefinition(name, name, false)
 
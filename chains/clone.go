@@ -0,0 +1,411 @@
+package chains
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// CloneChain copies the chain definition and the data containers of
+// the chain into a new, independent chain. The data is streamed from
+// container to container. A running chain is stopped for the copy and
+// started again afterwards.
+//
+// With a new chain ID or new validator keys the blocks of the chain
+// (signed for the old chain ID and validators) are not copied: the
+// clone starts over from the rewritten genesis file, with the accounts
+// and validators of the original one.
+//
+//  do.Name    - name of the chain to clone (required)
+//  do.NewName - name of the new chain (required)
+//  do.ChainID - chain ID of the new chain (optional; defaults to the chain ID of the cloned chain)
+//  do.NewKeys - give the validator nodes of the new chain new keys (optional)
+//  do.Timeout - number of seconds to wait for the chain to stop (optional)
+//
+func CloneChain(do *definitions.Do) (err error) {
+	if do.Name == do.NewName {
+		return fmt.Errorf("Cannot clone a chain to the same name")
+	}
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	if !util.IsDataContainer(chain.Name) {
+		return fmt.Errorf("There is no data container for %s. Only chains with data containers can be cloned", chain.Name)
+	}
+	if util.IsKnownChain(do.NewName) || util.IsDataContainer(do.NewName) {
+		return fmt.Errorf("Chain %s already exists. Choose another name or remove it with [eris chains rm --data %s]", do.NewName, do.NewName)
+	}
+	if do.ChainID != "" && path.Base(do.ChainID) != do.ChainID {
+		return fmt.Errorf("Invalid chain ID %q", do.ChainID)
+	}
+
+	rewrite := &cloneRewrite{
+		from:    path.Join(path.Base(ErisContainerRoot), "chains", path.Base(chainDir(chain))),
+		chainID: do.ChainID,
+		reset:   do.ChainID != "" || do.NewKeys,
+	}
+	id := do.ChainID
+	if id == "" {
+		id = path.Base(chainDir(chain))
+	}
+	rewrite.to = path.Join(path.Base(ErisContainerRoot), "chains", id)
+
+	nodes := Nodes(chain.Name)
+	if do.NewKeys {
+		if rewrite.keys, err = newValidatorKeys(chain, nodes); err != nil {
+			return err
+		}
+	}
+
+	ext := filepath.Ext(util.GetFileByNameAndType("chains", chain.Name))
+	if ext == "" {
+		ext = ".toml"
+	}
+	fileName := filepath.Join(ChainsPath, do.NewName+ext)
+	defer func() {
+		if err != nil {
+			log.WithField("=>", do.NewName).Info("Cleaning up after failed clone")
+			for number := 1; number <= nodes; number++ {
+				ops := definitions.BlankOperation()
+				ops.SrvContainerName = util.NumberedContainersName(definitions.TypeData, do.NewName, number)
+				perform.DockerRemove(nil, ops, false, true, false)
+			}
+			os.Remove(fileName)
+		}
+	}()
+
+	if err := withChainStopped(chain, do.Timeout, func() error {
+		for number := 1; number <= nodes; number++ {
+			if err := cloneData(chain.Name, do.NewName, number, rewrite); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Written last: an existing definition file makes the chain known.
+	clone, err := loaders.LoadChainDefinition(chain.Name, false)
+	if err != nil {
+		return err
+	}
+	// Unlike a renamed chain, the clone keeps the image it was cloned
+	// with (and the previous image recorded by an upgrade).
+	clone.Name = do.NewName
+	clone.Service.Name = ""
+	clone.ChainID = id
+	if err := WriteChainDefinitionFile(clone, fileName); err != nil {
+		return err
+	}
+
+	if !rewrite.reset {
+		log.WithField("=>", do.NewName).Warnf("The clone shares the chain ID and validator keys of %s. Keep it off the network of %s", chain.Name, chain.Name)
+	}
+	log.WithFields(log.Fields{
+		"from":     chain.Name,
+		"to":       do.NewName,
+		"chain id": id,
+	}).Warn("Chain cloned")
+	do.Result = do.NewName
+	return nil
+}
+
+// cloneRewrite changes the chain directory in the data containers of a
+// chain being cloned.
+type cloneRewrite struct {
+	// Chain directories relative to the parent of ErisContainerRoot
+	// (paths of a data container tar stream).
+	from, to string
+	// New chain ID ("" to keep it).
+	chainID string
+	// New validator keys by the old public key.
+	keys map[string]*chainAccount
+	// Skip the block store and state.
+	reset bool
+}
+
+// file returns the file of the tar stream, possibly renamed or
+// rewritten. Files to leave out have an empty name.
+func (rw *cloneRewrite) file(name string, content func() ([]byte, error)) (string, []byte, error) {
+	trimmed := strings.TrimSuffix(name, "/")
+	if trimmed != rw.from && !strings.HasPrefix(trimmed, rw.from+"/") {
+		return name, nil, nil
+	}
+
+	relative := strings.TrimPrefix(strings.TrimPrefix(trimmed, rw.from), "/")
+	if rw.reset && (relative == "data" || strings.HasPrefix(relative, "data/")) {
+		return "", nil, nil
+	}
+	renamed := rw.to + strings.TrimPrefix(name, rw.from)
+
+	var rewritten []byte
+	switch relative {
+	case "genesis.json":
+		if rw.chainID == "" && rw.keys == nil {
+			return renamed, nil, nil
+		}
+		in, err := content()
+		if err != nil {
+			return "", nil, err
+		}
+		if rewritten, err = rewriteGenesis(in, rw.chainID, rw.keys); err != nil {
+			return "", nil, err
+		}
+	case "priv_validator.json":
+		if !rw.reset {
+			return renamed, nil, nil
+		}
+		in, err := content()
+		if err != nil {
+			return "", nil, err
+		}
+		if rewritten, err = rewritePrivValidator(in, rw.keys); err != nil {
+			return "", nil, err
+		}
+	}
+	return renamed, rewritten, nil
+}
+
+// stream returns the data container tar stream in with the chain
+// directory rewritten.
+func (rw *cloneRewrite) stream(in io.Reader) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		tr := tar.NewReader(in)
+		tw := tar.NewWriter(writer)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+
+			name, content, err := rw.file(header.Name, func() ([]byte, error) {
+				return ioutil.ReadAll(tr)
+			})
+			if err != nil {
+				writer.CloseWithError(fmt.Errorf("Cannot rewrite %s: %v", header.Name, err))
+				return
+			}
+			if name == "" {
+				continue
+			}
+
+			header.Name = name
+			var body io.Reader = tr
+			if content != nil {
+				header.Size = int64(len(content))
+				body = bytes.NewReader(content)
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(tw, body); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		writer.CloseWithError(tw.Close())
+	}()
+	return reader
+}
+
+// cloneData creates the number-th data container of the new chain with
+// the rewritten contents of the one of the cloned chain.
+func cloneData(from, to string, number int, rw *cloneRewrite) error {
+	source := util.FindNumberedContainer(definitions.TypeData, from, number)
+	if source == nil {
+		return fmt.Errorf("There is no data container for node %d of %s", number, from)
+	}
+
+	ops := loaders.LoadDataDefinition(to)
+	ops.DataContainerName = util.NumberedContainersName(definitions.TypeData, to, number)
+	ops.Labels = util.SetLabel(ops.Labels, definitions.LabelNumber, strconv.Itoa(number))
+	if err := perform.DockerCreateData(ops); err != nil {
+		return fmt.Errorf("Error creating data container %v.", err)
+	}
+	target := util.FindNumberedContainer(definitions.TypeData, to, number)
+	if target == nil {
+		return fmt.Errorf("There is no data container for node %d of %s", number, to)
+	}
+
+	download, writer := io.Pipe()
+	defer download.Close()
+	go func() {
+		opts := docker.DownloadFromContainerOptions{
+			OutputStream: writer,
+			Path:         ErisContainerRoot,
+		}
+		writer.CloseWithError(util.DockerClient.DownloadFromContainer(source.ContainerID, opts))
+	}()
+
+	reader := rw.stream(download)
+	defer reader.Close()
+
+	log.WithFields(log.Fields{
+		"from": source.FullName,
+		"to":   target.FullName,
+	}).Info("Copying data container")
+	opts := docker.UploadToContainerOptions{
+		InputStream: reader,
+		Path:        path.Dir(ErisContainerRoot),
+	}
+	if err := util.DockerClient.UploadToContainer(target.ContainerID, opts); err != nil {
+		return err
+	}
+
+	// Required because `docker cp` (UploadToContainer) goes in as root.
+	ops.Args = []string{"chown", "--recursive", "eris", ErisContainerRoot}
+	if _, err := perform.DockerRunData(ops, nil); err != nil {
+		return fmt.Errorf("Error changing owner: %v\n", err)
+	}
+	return nil
+}
+
+// newValidatorKeys makes new keys for the validator of every node of
+// the chain, by the public key of its priv_validator.json file.
+func newValidatorKeys(chain *definitions.Chain, nodes int) (map[string]*chainAccount, error) {
+	keys := make(map[string]*chainAccount)
+	for number := 1; number <= nodes; number++ {
+		doCat := definitions.NowDo()
+		doCat.Name = chain.Name
+		doCat.Operations.DataContainerName = util.NumberedContainersName(definitions.TypeData, chain.Name, number)
+		doCat.Operations.Args = []string{"cat", path.Join(chainDir(chain), "priv_validator.json")}
+		out, err := data.ExecData(doCat)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read the validator key of node %d: %v", number, err)
+		}
+
+		var validator privValidator
+		if err := json.Unmarshal(out.Bytes(), &validator); err != nil || len(validator.PubKey) != 2 {
+			return nil, fmt.Errorf("Cannot read the validator key of node %d", number)
+		}
+		key, _ := validator.PubKey[1].(string)
+		if keys[strings.ToUpper(key)], err = newAccount("", 0, 0, nil); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// rewriteGenesis sets the chain ID of the genesis file and replaces
+// the validators with new keys (and the accounts and unbonding
+// addresses of their old addresses). Other fields are kept.
+func rewriteGenesis(content []byte, chainID string, keys map[string]*chainAccount) ([]byte, error) {
+	var genesis map[string]interface{}
+	if err := decodeJSON(content, &genesis); err != nil {
+		return nil, err
+	}
+	if chainID != "" {
+		genesis["chain_id"] = chainID
+	}
+
+	addresses := make(map[string]string)
+	validators, _ := genesis["validators"].([]interface{})
+	for _, v := range validators {
+		validator, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key := keys[strings.ToUpper(pubKeyHex(validator["pub_key"]))]
+		if key == nil {
+			continue
+		}
+		old, _ := hex.DecodeString(pubKeyHex(validator["pub_key"]))
+		addresses[keyAddress(old)] = key.Address
+		validator["pub_key"] = []interface{}{keyTypeEd25519, key.PubKey}
+
+		unbondTo, _ := validator["unbond_to"].([]interface{})
+		for _, u := range unbondTo {
+			replaceAddress(u, addresses)
+		}
+	}
+	accounts, _ := genesis["accounts"].([]interface{})
+	for _, account := range accounts {
+		replaceAddress(account, addresses)
+	}
+
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// rewritePrivValidator replaces the key of the priv_validator.json file
+// with its new key, if there is one, and resets the last signed height.
+func rewritePrivValidator(content []byte, keys map[string]*chainAccount) ([]byte, error) {
+	var validator map[string]interface{}
+	if err := decodeJSON(content, &validator); err != nil {
+		return nil, err
+	}
+	if key := keys[strings.ToUpper(pubKeyHex(validator["pub_key"]))]; key != nil {
+		validator["address"] = key.Address
+		validator["pub_key"] = []interface{}{keyTypeEd25519, key.PubKey}
+		validator["priv_key"] = []interface{}{keyTypeEd25519, key.PrivKey}
+	}
+	validator["last_height"] = 0
+	validator["last_round"] = 0
+	validator["last_step"] = 0
+
+	out, err := json.MarshalIndent(validator, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// decodeJSON decodes content keeping numbers as they are.
+func decodeJSON(content []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// pubKeyHex returns the key of a [TYPE, "HEX"] public key.
+func pubKeyHex(pubKey interface{}) string {
+	pair, ok := pubKey.([]interface{})
+	if !ok || len(pair) != 2 {
+		return ""
+	}
+	key, _ := pair[1].(string)
+	return key
+}
+
+// replaceAddress replaces the address field of the object if it's one
+// of the addresses.
+func replaceAddress(v interface{}, addresses map[string]string) {
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		return
+	}
+	address, _ := object["address"].(string)
+	if replacement, ok := addresses[strings.ToUpper(address)]; ok {
+		object["address"] = replacement
+	}
+}
@@ -0,0 +1,373 @@
+package chains
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/BurntSushi/toml"
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// chainConfigFiles are the files of the chain directory in the data
+// container [eris chains config] works on, in the order keys are looked
+// up in.
+var chainConfigFiles = []string{"config.toml", "server_conf.toml"}
+
+// GetChainConfig displays the values of keys of the chain's config.toml
+// or server_conf.toml files. Keys of tables are dotted (e.g. bind.port).
+//
+//  do.Name            - name of the chain (required)
+//  do.Operations.Args - keys to display (required)
+//
+func GetChainConfig(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	warnFirstNode(chain)
+
+	files, dir, err := exportChainConfig(chain, 1)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	for _, key := range do.Operations.Args {
+		value, file, err := lookupConfig(files, key)
+		if err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{
+			"key":  key,
+			"file": file,
+		}).Debug("Found config value")
+		fmt.Fprintln(config.GlobalConfig.Writer, value)
+	}
+	return nil
+}
+
+// SetChainConfig sets keys of the config.toml or server_conf.toml files
+// of every node of the chain, keeping the comments and the layout of the
+// files. Keys are set in the file they are found in, or in config.toml.
+// Values which aren't TOML booleans, numbers, strings, or arrays are set
+// as strings.
+//
+//  do.Name       - name of the chain (required)
+//  do.ConfigOpts - <key>=<value> pairs to set (required)
+//  do.Restart    - restart the chain afterwards (optional)
+//
+func SetChainConfig(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+
+	for number := 1; number <= Nodes(chain.Name); number++ {
+		if err := setNodeConfig(chain, number, do.ConfigOpts); err != nil {
+			return err
+		}
+	}
+
+	return restartAfterConfig(do)
+}
+
+func setNodeConfig(chain *definitions.Chain, number int, opts []string) error {
+	files, dir, err := exportChainConfig(chain, number)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	changed := make(map[string][]byte)
+	for _, opt := range opts {
+		spl := strings.SplitN(opt, "=", 2)
+		if len(spl) != 2 || strings.TrimSpace(spl[0]) == "" {
+			return fmt.Errorf("Config options should be <key>=<value> pairs. Got %s", opt)
+		}
+		key, value := strings.TrimSpace(spl[0]), strings.TrimSpace(spl[1])
+
+		// Keys set nowhere yet go to config.toml.
+		_, file, err := lookupConfig(files, key)
+		if _, ok := err.(configKeyNotSetError); ok {
+			file = chainConfigFiles[0]
+		} else if err != nil {
+			return err
+		}
+		content := util.SetTOMLValue(files[file], key, tomlLiteral(value))
+		if err := validTOML(content); err != nil {
+			return fmt.Errorf("Cannot set %s in %s: %v", key, file, err)
+		}
+		files[file], changed[file] = content, content
+
+		log.WithFields(log.Fields{
+			"node": number,
+			"file": file,
+		}).Warnf("Setting %s", key)
+	}
+
+	return importChainConfig(chain, number, changed)
+}
+
+// EditChainConfig opens the config.toml (or server_conf.toml) file of the
+// chain in the editor and copies it back into the data container if it's
+// changed and still valid TOML.
+//
+//  do.Name    - name of the chain (required)
+//  do.Type    - file to edit: config (default) or server_conf (optional)
+//  do.Restart - restart the chain afterwards (optional)
+//
+func EditChainConfig(do *definitions.Do) error {
+	file := chainConfigFiles[0]
+	if do.Type != "" {
+		file = strings.TrimSuffix(do.Type, ".toml") + ".toml"
+	}
+	known := false
+	for _, f := range chainConfigFiles {
+		known = known || f == file
+	}
+	if !known {
+		return fmt.Errorf("Unknown config file %q. Use config or server_conf", do.Type)
+	}
+
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	warnFirstNode(chain)
+
+	files, dir, err := exportChainConfig(chain, 1)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	original, ok := files[file]
+	if !ok {
+		return fmt.Errorf("The chain %s has no %s file", chain.Name, file)
+	}
+	local := filepath.Join(dir, file)
+	if err := Editor(local); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(local)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(content, original) {
+		log.WithField("file", file).Warn("No changes made")
+		return nil
+	}
+	if err := validTOML(content); err != nil {
+		return fmt.Errorf("Not copying %s back into the chain: %v", file, err)
+	}
+
+	if err := importChainConfig(chain, 1, map[string][]byte{file: content}); err != nil {
+		return err
+	}
+	return restartAfterConfig(do)
+}
+
+func warnFirstNode(chain *definitions.Chain) {
+	if Nodes(chain.Name) > 1 {
+		log.WithField("=>", chain.Name).Warn("Using the config of the first node of the chain network")
+	}
+}
+
+func restartAfterConfig(do *definitions.Do) error {
+	if !do.Restart {
+		log.WithField("=>", do.Name).Warn("Restart the chain for the changes to take effect")
+		return nil
+	}
+	doRestart := definitions.NowDo()
+	doRestart.Name = do.Name
+	doRestart.Timeout = do.Timeout
+	return RestartChain(doRestart)
+}
+
+// chainDir returns the directory of the chain files in its data containers.
+func chainDir(chain *definitions.Chain) string {
+	id := chain.ChainID
+	if id == "" {
+		id = chain.Name
+	}
+	return path.Join(ErisContainerRoot, "chains", id)
+}
+
+// exportChainConfig copies the config files of the number-th node of the
+// chain out of its data container into a temporary directory. It returns
+// the contents of the files by file name and the directory, which the
+// caller removes.
+func exportChainConfig(chain *definitions.Chain, number int) (map[string][]byte, string, error) {
+	files, dir, err := exportChainFiles(chain, number, chainConfigFiles)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(files) == 0 {
+		os.RemoveAll(dir)
+		return nil, "", fmt.Errorf("The chain %s has no config files in %s", chain.Name, chainDir(chain))
+	}
+	return files, dir, nil
+}
+
+// exportChainFiles copies the files of the chain directory of the
+// number-th node of the chain which exist out of its data container into
+// a temporary directory, like exportChainConfig.
+func exportChainFiles(chain *definitions.Chain, number int, names []string) (map[string][]byte, string, error) {
+	dataContainerName := util.NumberedContainersName(definitions.TypeData, chain.Name, number)
+
+	// Find out which of the files exist, since exporting a missing one
+	// is fatal.
+	doLs := definitions.NowDo()
+	doLs.Name = chain.Name
+	doLs.Operations.DataContainerName = dataContainerName
+	doLs.Operations.Args = []string{"ls", chainDir(chain)}
+	buf, err := data.ExecData(doLs)
+	if err != nil {
+		return nil, "", err
+	}
+	existing := strings.Fields(buf.String())
+
+	dir, err := ioutil.TempDir("", "eris_chain_config")
+	if err != nil {
+		return nil, "", err
+	}
+
+	files := make(map[string][]byte)
+	for _, file := range names {
+		found := false
+		for _, f := range existing {
+			found = found || f == file
+		}
+		if !found {
+			continue
+		}
+
+		doExport := definitions.NowDo()
+		doExport.Name = chain.Name
+		doExport.Operations.DataContainerName = dataContainerName
+		doExport.Source = path.Join(chainDir(chain), file)
+		doExport.Destination = dir
+		if err := data.ExportData(doExport); err != nil {
+			os.RemoveAll(dir)
+			return nil, "", err
+		}
+		if files[file], err = ioutil.ReadFile(filepath.Join(dir, file)); err != nil {
+			os.RemoveAll(dir)
+			return nil, "", err
+		}
+	}
+	return files, dir, nil
+}
+
+// importChainConfig copies the files into the data container of the
+// number-th node of the chain.
+func importChainConfig(chain *definitions.Chain, number int, files map[string][]byte) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	dir, err := ioutil.TempDir("", "eris_chain_config")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	for file, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), content, 0644); err != nil {
+			return err
+		}
+	}
+
+	doImport := definitions.NowDo()
+	doImport.Name = chain.Name
+	doImport.Operations.DataContainerName = util.NumberedContainersName(definitions.TypeData, chain.Name, number)
+	doImport.Source = dir
+	doImport.Destination = chainDir(chain)
+	return data.ImportData(doImport)
+}
+
+// lookupConfig returns the value of the dotted key as a TOML literal
+// (strings unquoted), and the first of chainConfigFiles it's set in.
+func lookupConfig(files map[string][]byte, key string) (string, string, error) {
+	for _, file := range chainConfigFiles {
+		content, ok := files[file]
+		if !ok {
+			continue
+		}
+		value, found, err := tomlValue(content, key)
+		if err != nil {
+			return "", "", fmt.Errorf("Cannot read %s: %v", file, err)
+		}
+		if !found {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			return v, file, nil
+		case map[string]interface{}:
+			return "", "", fmt.Errorf("The key %s is a table in %s. Use the keys of the table", key, file)
+		}
+		buf := new(bytes.Buffer)
+		if err := toml.NewEncoder(buf).Encode(map[string]interface{}{"v": value}); err != nil {
+			return "", "", err
+		}
+		return strings.TrimSpace(strings.TrimPrefix(buf.String(), "v = ")), file, nil
+	}
+	return "", "", configKeyNotSetError(key)
+}
+
+// configKeyNotSetError is returned by lookupConfig if the key is set in
+// none of the chainConfigFiles.
+type configKeyNotSetError string
+
+func (key configKeyNotSetError) Error() string {
+	return fmt.Sprintf("The key %s is not set in %s", string(key), strings.Join(chainConfigFiles, " or "))
+}
+
+// tomlValue returns the value of the dotted key of the TOML content.
+func tomlValue(content []byte, key string) (interface{}, bool, error) {
+	var tree map[string]interface{}
+	if _, err := toml.Decode(string(content), &tree); err != nil {
+		return nil, false, err
+	}
+
+	var value interface{} = tree
+	for _, part := range strings.Split(key, ".") {
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false, nil
+		}
+		if value, ok = table[part]; !ok {
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// tomlLiteral returns the value as is if it's a TOML literal or
+// quoted as a string otherwise.
+func tomlLiteral(value string) string {
+	var probe map[string]interface{}
+	if _, err := toml.Decode("v = "+value, &probe); err == nil {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+func validTOML(content []byte) error {
+	var tree map[string]interface{}
+	_, err := toml.Decode(string(content), &tree)
+	return err
+}
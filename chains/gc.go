@@ -0,0 +1,226 @@
+package chains
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/BurntSushi/toml"
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// Leftover is what is left of a throwaway chain: its containers, its
+// definition file in ChainsPath, and its directory in DataContainersPath.
+type Leftover struct {
+	Name       string
+	Created    time.Time
+	Containers []string
+	Definition string
+	DataDir    string
+	// true if any of the containers is running
+	Running bool
+
+	ids []string
+}
+
+// GarbageCollectChains removes the leftovers of throwaway chains (see
+// ThrowAwayChain) which package runs that crashed or were interrupted
+// didn't clean up. Leftovers are found by the throwaway label of the
+// containers and the throwaway chain_type of the definition files.
+// Chains with running containers are skipped (a package run may still
+// be using them), unless do.Force is given.
+//
+//  do.OlderThan - only remove chains made longer ago than this duration; 1h by default (optional)
+//  do.Force     - also remove chains with running containers (optional)
+//  do.DryRun    - display the leftovers without removing them (optional)
+//
+func GarbageCollectChains(do *definitions.Do) error {
+	olderThan := time.Hour
+	if do.OlderThan != "" {
+		var err error
+		if olderThan, err = time.ParseDuration(do.OlderThan); err != nil {
+			return fmt.Errorf("Invalid --older-than duration %q: %v", do.OlderThan, err)
+		}
+	}
+
+	containers, err := util.DockerClient.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("Cannot list containers: %v", err)
+	}
+	files, err := throwawayDefinitions(ChainsPath)
+	if err != nil {
+		return err
+	}
+
+	leftovers := selectLeftovers(findLeftovers(containers, files, DataContainersPath), olderThan, time.Now(), do.Force)
+	if do.DryRun || len(leftovers) == 0 {
+		printLeftovers(leftovers)
+		return nil
+	}
+
+	for _, leftover := range leftovers {
+		log.WithField("=>", leftover.Name).Warn("Removing throwaway chain")
+		for i, id := range leftover.ids {
+			log.WithField("=>", leftover.Containers[i]).Info("Removing container")
+			opts := docker.RemoveContainerOptions{
+				ID:            id,
+				RemoveVolumes: true,
+				Force:         true,
+			}
+			if err := util.DockerClient.RemoveContainer(opts); err != nil {
+				return fmt.Errorf("Cannot remove container %s: %v", leftover.Containers[i], err)
+			}
+		}
+		if leftover.Definition != "" {
+			log.WithField("file", leftover.Definition).Info("Removing definition file")
+			if err := os.Remove(leftover.Definition); err != nil {
+				return err
+			}
+		}
+		if leftover.DataDir != "" {
+			log.WithField("dir", leftover.DataDir).Info("Removing data directory")
+			if err := os.RemoveAll(leftover.DataDir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// throwawayDefinitions returns the chain definition files in dir which
+// have the throwaway chain_type, by chain name. Throwaway chains are
+// always written in TOML.
+func throwawayDefinitions(dir string) (map[string]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	throwaway := make(map[string]string)
+	for _, file := range files {
+		var chain struct {
+			ChainType string `toml:"chain_type"`
+		}
+		if _, err := toml.DecodeFile(file, &chain); err != nil || chain.ChainType != definitions.ChainTypeThrowaway {
+			continue
+		}
+		throwaway[strings.TrimSuffix(filepath.Base(file), ".toml")] = file
+	}
+	return throwaway, nil
+}
+
+// findLeftovers groups the throwaway containers, the throwaway
+// definition files, and their data directories in dataDir by chain.
+// The creation time of a chain is the earliest creation time of its
+// containers and modification time of its definition file.
+func findLeftovers(containers []docker.APIContainers, files map[string]string, dataDir string) map[string]*Leftover {
+	leftovers := make(map[string]*Leftover)
+	leftover := func(name string, created time.Time) *Leftover {
+		l, ok := leftovers[name]
+		if !ok {
+			l = &Leftover{Name: name, Created: created}
+			leftovers[name] = l
+		}
+		if created.Before(l.Created) {
+			l.Created = created
+		}
+		return l
+	}
+
+	for _, c := range containers {
+		if c.Labels[definitions.Namespace+":"+definitions.LabelThrowaway] != "true" {
+			continue
+		}
+		name := c.Labels[definitions.Namespace+":"+definitions.LabelShortName]
+		if name == "" {
+			continue
+		}
+		l := leftover(name, time.Unix(c.Created, 0))
+		l.ids = append(l.ids, c.ID)
+		if strings.HasPrefix(c.Status, "Up") {
+			l.Running = true
+		}
+		var containerName string
+		if len(c.Names) != 0 {
+			containerName = strings.TrimPrefix(c.Names[0], "/")
+		}
+		l.Containers = append(l.Containers, containerName)
+	}
+
+	for name, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		leftover(name, info.ModTime()).Definition = file
+	}
+
+	for name, l := range leftovers {
+		dir := filepath.Join(dataDir, name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			l.DataDir = dir
+		}
+	}
+	return leftovers
+}
+
+// selectLeftovers returns the leftovers of chains made at least
+// olderThan before now, sorted by name. Leftovers with running
+// containers are only returned if force is true.
+func selectLeftovers(leftovers map[string]*Leftover, olderThan time.Duration, now time.Time, force bool) []*Leftover {
+	var selected []*Leftover
+	for _, leftover := range leftovers {
+		if now.Sub(leftover.Created) < olderThan {
+			log.WithField("=>", leftover.Name).Debug("Throwaway chain too recent to remove")
+			continue
+		}
+		if leftover.Running && !force {
+			log.WithField("=>", leftover.Name).Warn("Throwaway chain still running. Use --force to remove it")
+			continue
+		}
+		selected = append(selected, leftover)
+	}
+	sort.Sort(leftoversByName(selected))
+	return selected
+}
+
+type leftoversByName []*Leftover
+
+func (l leftoversByName) Len() int           { return len(l) }
+func (l leftoversByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l leftoversByName) Less(i, j int) bool { return l[i].Name < l[j].Name }
+
+// printLeftovers displays the leftovers of throwaway chains.
+func printLeftovers(leftovers []*Leftover) {
+	if len(leftovers) == 0 {
+		log.Warn("No throwaway chains to remove")
+		return
+	}
+
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetBorder(false)
+	table.SetColumnSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"CHAIN", "CREATED", "CONTAINERS", "DEFINITION", "DATA DIR"})
+	for _, leftover := range leftovers {
+		var definition string
+		if leftover.Definition != "" {
+			definition = filepath.Base(leftover.Definition)
+		}
+		table.Append([]string{leftover.Name, leftover.Created.Local().Format(time.RFC822),
+			strconv.Itoa(len(leftover.Containers)), definition, leftover.DataDir})
+	}
+	table.Render()
+}
@@ -0,0 +1,706 @@
+package chains
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/BurntSushi/toml"
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/agl/ed25519"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/decred/dcrd/crypto/ripemd160"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// Genesis files and priv_validator.json files [eris chains make] writes
+// are the ones eris:db reads: keys are ed25519 keys and addresses are the
+// RIPEMD160 hashes of the binary encoded public keys.
+
+// keyTypeEd25519 is the type byte of ed25519 keys in eris:db files.
+const keyTypeEd25519 = 1
+
+// Token amounts of accounts listed in known CSV files without an amount.
+const (
+	defaultTokens = 9999999999
+	defaultBond   = 9999999998
+)
+
+// permissionBits are the eris:db base permission flags by name.
+var permissionBits = map[string]uint64{
+	"root":            1 << 0,
+	"send":            1 << 1,
+	"call":            1 << 2,
+	"create_contract": 1 << 3,
+	"create_account":  1 << 4,
+	"bond":            1 << 5,
+	"name":            1 << 6,
+	"has_base":        1 << 7,
+	"set_base":        1 << 8,
+	"unset_base":      1 << 9,
+	"set_global":      1 << 10,
+	"has_role":        1 << 11,
+	"add_role":        1 << 12,
+	"remove_role":     1 << 13,
+}
+
+// accountType is a kind of account [eris chains make] makes keys for.
+// Account types are read from toml files in AccountsTypePath and
+// override the built-in ones of the same name.
+type accountType struct {
+	Name          string         `toml:"name"`
+	Definition    string         `toml:"definition"`
+	TypicalUser   string         `toml:"typical_user"`
+	DefaultNumber int            `toml:"default_number"`
+	DefaultTokens int64          `toml:"default_tokens"`
+	DefaultBond   int64          `toml:"default_bond"`
+	Perms         map[string]int `toml:"perms"`
+}
+
+// chainType is a named set of account type numbers. Chain types are read
+// from toml files in ChainTypePath and override the built-in ones.
+type chainType struct {
+	Name         string         `toml:"name"`
+	Description  string         `toml:"description"`
+	AccountTypes map[string]int `toml:"account_types"`
+}
+
+func allPerms(except ...string) map[string]int {
+	perms := make(map[string]int)
+	for name := range permissionBits {
+		perms[name] = 1
+	}
+	for _, name := range except {
+		perms[name] = 0
+	}
+	return perms
+}
+
+var builtinAccountTypes = []*accountType{
+	{
+		Name:          "Full",
+		Definition:    "Users who have a key which is registered with root permissions and also has a validator bond",
+		TypicalUser:   "Running a local development chain",
+		DefaultNumber: 1,
+		DefaultTokens: 99999999999999,
+		DefaultBond:   9999999999,
+		Perms:         allPerms(),
+	},
+	{
+		Name:          "Validator",
+		Definition:    "Users who have a key which is bonded and only able to validate the chain",
+		TypicalUser:   "Organizations running the chain's nodes",
+		DefaultTokens: 9999999999,
+		DefaultBond:   9999999998,
+		Perms:         map[string]int{"bond": 1},
+	},
+	{
+		Name:          "Root",
+		Definition:    "Users who have a key which has administrative permissions on the chain",
+		TypicalUser:   "Chain administrators",
+		DefaultTokens: 9999999999,
+		Perms:         allPerms("bond"),
+	},
+	{
+		Name:          "Developer",
+		Definition:    "Users who are able to create contracts and send transactions",
+		TypicalUser:   "Application developers",
+		DefaultTokens: 9999999999,
+		Perms: map[string]int{
+			"send":            1,
+			"call":            1,
+			"create_contract": 1,
+			"create_account":  1,
+			"name":            1,
+			"has_role":        1,
+		},
+	},
+	{
+		Name:          "Participant",
+		Definition:    "Users who are able to send transactions and call contracts",
+		TypicalUser:   "Application users",
+		DefaultTokens: 9999999999,
+		Perms: map[string]int{
+			"send":     1,
+			"call":     1,
+			"name":     1,
+			"has_role": 1,
+		},
+	},
+}
+
+var builtinChainTypes = []*chainType{
+	{
+		Name:         "simplechain",
+		Description:  "A single full account",
+		AccountTypes: map[string]int{"Full": 1},
+	},
+	{
+		Name:         "adminchain",
+		Description:  "A full account and a root account to administer the chain",
+		AccountTypes: map[string]int{"Full": 1, "Root": 1},
+	},
+	{
+		Name:         "demochain",
+		Description:  "A chain to show applications on",
+		AccountTypes: map[string]int{"Full": 1, "Developer": 1, "Participant": 5},
+	},
+	{
+		Name:         "sprawlchain",
+		Description:  "A chain with many validators and users",
+		AccountTypes: map[string]int{"Root": 1, "Validator": 7, "Developer": 5, "Participant": 20},
+	},
+}
+
+type genesisDoc struct {
+	ChainID    string              `json:"chain_id"`
+	Accounts   []*genesisAccount   `json:"accounts"`
+	Validators []*genesisValidator `json:"validators"`
+}
+
+type genesisAccount struct {
+	Address     string              `json:"address"`
+	Amount      int64               `json:"amount"`
+	Name        string              `json:"name"`
+	Permissions *accountPermissions `json:"permissions"`
+}
+
+type genesisValidator struct {
+	PubKey   []interface{}       `json:"pub_key"`
+	Amount   int64               `json:"amount"`
+	Name     string              `json:"name"`
+	UnbondTo []*genesisUnbonding `json:"unbond_to"`
+}
+
+type genesisUnbonding struct {
+	Address string `json:"address"`
+	Amount  int64  `json:"amount"`
+}
+
+type accountPermissions struct {
+	Base  basePermissions `json:"base"`
+	Roles []string        `json:"roles"`
+}
+
+type basePermissions struct {
+	Perms  uint64 `json:"perms"`
+	SetBit uint64 `json:"set_bit"`
+}
+
+type privValidator struct {
+	Address    string        `json:"address"`
+	PubKey     []interface{} `json:"pub_key"`
+	PrivKey    []interface{} `json:"priv_key"`
+	LastHeight int           `json:"last_height"`
+	LastRound  int           `json:"last_round"`
+	LastStep   int           `json:"last_step"`
+}
+
+// chainAccount is an account of a chain being made. Accounts listed in
+// known CSV files by their address have no keys.
+type chainAccount struct {
+	Name    string
+	Address string
+	PubKey  string
+	PrivKey string
+	Tokens  int64
+	Bond    int64
+	Perms   *accountPermissions
+}
+
+// keyAddress returns the eris:db address of the ed25519 public key.
+func keyAddress(pubKey []byte) string {
+	hasher := ripemd160.New()
+	hasher.Write([]byte{keyTypeEd25519, 0x01, byte(len(pubKey))})
+	hasher.Write(pubKey)
+	return strings.ToUpper(hex.EncodeToString(hasher.Sum(nil)))
+}
+
+// permissions converts named permissions to eris:db base permissions.
+// Every permission named is set, to its value.
+func permissions(perms map[string]int) (*accountPermissions, error) {
+	base := basePermissions{}
+	for name, value := range perms {
+		bit, ok := permissionBits[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown permission %q", name)
+		}
+		base.SetBit |= bit
+		if value != 0 {
+			base.Perms |= bit
+		}
+	}
+	return &accountPermissions{Base: base, Roles: []string{}}, nil
+}
+
+// newAccount makes the keys of a new account.
+func newAccount(name string, tokens, bond int64, perms *accountPermissions) (*chainAccount, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &chainAccount{
+		Name:    name,
+		Address: keyAddress(pub[:]),
+		PubKey:  strings.ToUpper(hex.EncodeToString(pub[:])),
+		PrivKey: strings.ToUpper(hex.EncodeToString(priv[:])),
+		Tokens:  tokens,
+		Bond:    bond,
+		Perms:   perms,
+	}, nil
+}
+
+// loadAccountTypes returns the built-in account types overridden by the
+// ones in AccountsTypePath, keyed by lower case name.
+func loadAccountTypes() (map[string]*accountType, error) {
+	types := make(map[string]*accountType)
+	for _, typ := range builtinAccountTypes {
+		types[strings.ToLower(typ.Name)] = typ
+	}
+
+	files, _ := filepath.Glob(filepath.Join(AccountsTypePath, "*.toml"))
+	for _, file := range files {
+		typ := &accountType{}
+		if _, err := toml.DecodeFile(file, typ); err != nil {
+			return nil, fmt.Errorf("Cannot read account type file %s: %v", file, err)
+		}
+		if typ.Name == "" {
+			typ.Name = strings.TrimSuffix(filepath.Base(file), ".toml")
+		}
+		types[strings.ToLower(typ.Name)] = typ
+	}
+	return types, nil
+}
+
+// loadChainType returns the chain type of the name, either from
+// ChainTypePath or a built-in one.
+func loadChainType(name string) (*chainType, error) {
+	file := filepath.Join(ChainTypePath, name+".toml")
+	if _, err := os.Stat(file); err == nil {
+		typ := &chainType{}
+		if _, err := toml.DecodeFile(file, typ); err != nil {
+			return nil, fmt.Errorf("Cannot read chain type file %s: %v", file, err)
+		}
+		return typ, nil
+	}
+
+	for _, typ := range builtinChainTypes {
+		if strings.EqualFold(typ.Name, name) {
+			return typ, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown chain type %q. Add it to %s", name, ChainTypePath)
+}
+
+// accountNumber is the number of accounts of an account type to make.
+type accountNumber struct {
+	Type   *accountType
+	Number int
+}
+
+// parseAccountTypes parses Name:Number pairs of the --account-types flag.
+func parseAccountTypes(types map[string]*accountType, pairs []string) ([]accountNumber, error) {
+	var numbers []accountNumber
+	for _, pair := range pairs {
+		spl := strings.Split(pair, ":")
+		if len(spl) != 2 {
+			return nil, fmt.Errorf("Account types should be <type>:<number> pairs. Got %s", pair)
+		}
+		typ, ok := types[strings.ToLower(strings.TrimSpace(spl[0]))]
+		if !ok {
+			return nil, fmt.Errorf("Unknown account type %q. Add it to %s", spl[0], AccountsTypePath)
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(spl[1]))
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("Invalid number of %s accounts %q", typ.Name, spl[1])
+		}
+		numbers = append(numbers, accountNumber{typ, number})
+	}
+	return numbers, nil
+}
+
+// chainTypeNumbers returns the account type numbers of the chain type,
+// sorted by account type name.
+func chainTypeNumbers(types map[string]*accountType, chain *chainType) ([]accountNumber, error) {
+	var pairs []string
+	for name, number := range chain.AccountTypes {
+		pairs = append(pairs, fmt.Sprintf("%s:%d", name, number))
+	}
+	sort.Strings(pairs)
+	return parseAccountTypes(types, pairs)
+}
+
+// askAccountTypes asks for the number of accounts of every account type.
+func askAccountTypes(types map[string]*accountType, in io.Reader, out io.Writer) ([]accountNumber, error) {
+	var names []string
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var numbers []accountNumber
+	reader := bufio.NewReader(in)
+	for _, name := range names {
+		typ := types[name]
+		fmt.Fprintf(out, "%s: %s.\nHow many keys do you want for the %s account type? [%d] ", typ.Name, typ.Definition, typ.Name, typ.DefaultNumber)
+
+		answer, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		number := typ.DefaultNumber
+		if answer = strings.TrimSpace(answer); answer != "" {
+			if number, err = strconv.Atoi(answer); err != nil || number < 0 {
+				return nil, fmt.Errorf("Invalid number of %s accounts %q", typ.Name, answer)
+			}
+		}
+		numbers = append(numbers, accountNumber{typ, number})
+	}
+	return numbers, nil
+}
+
+// makeAccounts makes the keys of the accounts of the chain. Accounts
+// of types with a default bond are validators.
+func makeAccounts(chain string, numbers []accountNumber) ([]*chainAccount, error) {
+	var accounts []*chainAccount
+	for _, n := range numbers {
+		perms, err := permissions(n.Type.Perms)
+		if err != nil {
+			return nil, fmt.Errorf("Account type %s: %v", n.Type.Name, err)
+		}
+		for i := 0; i < n.Number; i++ {
+			name := strings.ToLower(fmt.Sprintf("%s_%s_%03d", chain, n.Type.Name, i))
+			account, err := newAccount(name, n.Type.DefaultTokens, n.Type.DefaultBond, perms)
+			if err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, account)
+		}
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("Cannot make a chain without accounts")
+	}
+	return accounts, nil
+}
+
+// knownAccounts reads the validators and accounts of the comma separated
+// lists of CSV files. Rows are <key>,<amount>,<name>,<perms>,<set bit>
+// where only the key is required. Accounts may be given by their address
+// instead of their public key.
+func knownAccounts(validatorFiles, accountFiles string) (validators, accounts []*chainAccount, err error) {
+	if validators, err = readKnown(validatorFiles, true); err != nil {
+		return nil, nil, err
+	}
+	if accounts, err = readKnown(accountFiles, false); err != nil {
+		return nil, nil, err
+	}
+	if len(validators) == 0 {
+		return nil, nil, fmt.Errorf("Cannot make a chain without validators")
+	}
+	return validators, accounts, nil
+}
+
+func readKnown(files string, validators bool) ([]*chainAccount, error) {
+	var accounts []*chainAccount
+	for _, file := range strings.Split(files, ",") {
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		reader := csv.NewReader(f)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		rows, err := reader.ReadAll()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read %s: %v", file, err)
+		}
+
+		for i, row := range rows {
+			account, err := knownAccount(row, validators)
+			if err != nil {
+				return nil, fmt.Errorf("%s, line %d: %v", file, i+1, err)
+			}
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func knownAccount(row []string, validator bool) (*chainAccount, error) {
+	field := func(i int) string {
+		if i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	account := &chainAccount{Name: field(2)}
+	key, err := hex.DecodeString(field(0))
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("Invalid key %q", field(0))
+	case len(key) == ed25519.PublicKeySize:
+		account.PubKey = strings.ToUpper(field(0))
+		account.Address = keyAddress(key)
+	case len(key) == ripemd160.Size && !validator:
+		account.Address = strings.ToUpper(field(0))
+	default:
+		return nil, fmt.Errorf("Expected a public key, got %q", field(0))
+	}
+
+	amount := int64(defaultTokens)
+	if validator {
+		amount = defaultBond
+	}
+	if field(1) != "" {
+		if amount, err = strconv.ParseInt(field(1), 10, 64); err != nil {
+			return nil, fmt.Errorf("Invalid amount %q", field(1))
+		}
+	}
+	if validator {
+		account.Bond = amount
+	} else {
+		account.Tokens = amount
+	}
+
+	account.Perms = &accountPermissions{Roles: []string{}}
+	for i, bits := range []*uint64{&account.Perms.Base.Perms, &account.Perms.Base.SetBit} {
+		if field(3+i) == "" {
+			continue
+		}
+		if *bits, err = strconv.ParseUint(field(3+i), 10, 64); err != nil {
+			return nil, fmt.Errorf("Invalid permissions %q", field(3+i))
+		}
+	}
+	return account, nil
+}
+
+// makeGenesis assembles the genesis file of the chain. Validators
+// unbond to their own addresses.
+func makeGenesis(chainID string, validators, accounts []*chainAccount) *genesisDoc {
+	genesis := &genesisDoc{
+		ChainID:    chainID,
+		Accounts:   []*genesisAccount{},
+		Validators: []*genesisValidator{},
+	}
+	for _, account := range accounts {
+		genesis.Accounts = append(genesis.Accounts, &genesisAccount{
+			Address:     account.Address,
+			Amount:      account.Tokens,
+			Name:        account.Name,
+			Permissions: account.Perms,
+		})
+	}
+	for _, validator := range validators {
+		genesis.Validators = append(genesis.Validators, &genesisValidator{
+			PubKey: []interface{}{keyTypeEd25519, validator.PubKey},
+			Amount: validator.Bond,
+			Name:   validator.Name,
+			UnbondTo: []*genesisUnbonding{
+				{Address: validator.Address, Amount: validator.Bond},
+			},
+		})
+	}
+	return genesis
+}
+
+func bonded(accounts []*chainAccount) []*chainAccount {
+	var validators []*chainAccount
+	for _, account := range accounts {
+		if account.Bond > 0 {
+			validators = append(validators, account)
+		}
+	}
+	return validators
+}
+
+func writeJSON(file string, v interface{}, perm os.FileMode) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(content, '\n'), perm)
+}
+
+// writeChainFiles writes the genesis file and the account lists of the
+// chain to dir, and for every account a directory named after it with
+// the genesis file and its priv_validator.json file, which can be given
+// to [eris chains new --dir]. With do.Tarball or do.ZipFile the account
+// directories are packaged instead.
+func writeChainFiles(do *definitions.Do, dir string, genesis *genesisDoc, accounts []*chainAccount) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(dir, "genesis.json"), genesis, 0600); err != nil {
+		return err
+	}
+
+	keys := make(map[string]map[string]string)
+	var accountsCSV, validatorsCSV, addressesCSV [][]string
+	for _, account := range accounts {
+		keys[account.Name] = map[string]string{
+			"address": account.Address,
+			"pubKey":  account.PubKey,
+			"privKey": account.PrivKey,
+		}
+		perms := strconv.FormatUint(account.Perms.Base.Perms, 10)
+		setBit := strconv.FormatUint(account.Perms.Base.SetBit, 10)
+		accountsCSV = append(accountsCSV, []string{account.PubKey, strconv.FormatInt(account.Tokens, 10), account.Name, perms, setBit})
+		if account.Bond > 0 {
+			validatorsCSV = append(validatorsCSV, []string{account.PubKey, strconv.FormatInt(account.Bond, 10), account.Name, perms, setBit})
+		}
+		addressesCSV = append(addressesCSV, []string{account.Address, account.Name})
+	}
+	if err := writeJSON(filepath.Join(dir, "accounts.json"), keys, 0600); err != nil {
+		return err
+	}
+	for file, rows := range map[string][][]string{
+		"accounts.csv":   accountsCSV,
+		"validators.csv": validatorsCSV,
+		"addresses.csv":  addressesCSV,
+	} {
+		if err := writeCSV(filepath.Join(dir, file), rows); err != nil {
+			return err
+		}
+	}
+
+	for _, account := range accounts {
+		accountDir := filepath.Join(dir, account.Name)
+		if err := os.MkdirAll(accountDir, 0700); err != nil {
+			return err
+		}
+		if err := writeJSON(filepath.Join(accountDir, "genesis.json"), genesis, 0600); err != nil {
+			return err
+		}
+		priv := &privValidator{
+			Address: account.Address,
+			PubKey:  []interface{}{keyTypeEd25519, account.PubKey},
+			PrivKey: []interface{}{keyTypeEd25519, account.PrivKey},
+		}
+		if err := writeJSON(filepath.Join(accountDir, "priv_validator.json"), priv, 0600); err != nil {
+			return err
+		}
+
+		switch {
+		case do.Tarball:
+			if err := packageDir(accountDir, accountDir+".tar.gz", writeTarball); err != nil {
+				return err
+			}
+		case do.ZipFile:
+			if err := packageDir(accountDir, accountDir+".zip", writeZip); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeCSV(file string, rows [][]string) error {
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	writer.WriteAll(rows)
+	return writer.Error()
+}
+
+// packageDir packages the files of dir into the archive with write
+// and removes dir.
+func packageDir(dir, archive string, write func(io.Writer, string, []os.FileInfo) error) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(archive, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := write(out, dir, files); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func writeTarball(out io.Writer, dir string, files []os.FileInfo) error {
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(file, "")
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeZip(out io.Writer, dir string, files []os.FileInfo) error {
+	zw := zip.NewWriter(out)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(file.Name())
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func printAccounts(accounts []*chainAccount, dir string) {
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetBorder(false)
+	table.SetColumnSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"NAME", "ADDRESS", "TOKENS", "BOND"})
+	for _, account := range accounts {
+		table.Append([]string{account.Name, account.Address, strconv.FormatInt(account.Tokens, 10), strconv.FormatInt(account.Bond, 10)})
+	}
+	table.Render()
+
+	log.WithField("dir", dir).Warn("Chain files written")
+}
@@ -9,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/eris-ltd/eris-cli/config"
 	"github.com/eris-ltd/eris-cli/data"
@@ -17,119 +18,77 @@ import (
 	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/services"
 	"github.com/eris-ltd/eris-cli/util"
-	"github.com/eris-ltd/eris-cli/version"
 
 	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/ipfs"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
 )
 
-// MakeChain runs the `eris-cm make` command in a docker container.
+// MakeChain makes the keys, genesis files, and priv_validator.json
+// files of a new chain in ChainsPath/NAME. It doesn't need Docker.
 // It returns an error. Note that if do.Known, do.AccountTypes
-// or do.ChainType are not set the command will run via interactive
-// shell.
+// or do.ChainType are not set the numbers of accounts are asked for
+// interactively.
 //
 //  do.Name          - name of the chain to be created (required)
-//  do.Known         - will use the mintgen tool to parse csv's and create a genesis.json (requires do.ChainMakeVals and do.ChainMakeActs) (optional)
+//  do.Known         - use csv's of known keys to create a genesis.json (requires do.ChainMakeVals and do.ChainMakeActs) (optional)
 //  do.ChainMakeVals - csv file to use for validators (optional)
 //  do.ChainMakeActs - csv file to use for accounts (optional)
-//  do.AccountTypes  - use eris-cm make account-types paradigm (example: Root:1,Participants:25,...) (optional)
-//  do.ChainType     - use eris-cm make chain-types paradigm (example: simplechain) (optional)
+//  do.AccountTypes  - account types paradigm (example: Root:1,Participants:25,...) (optional)
+//  do.ChainType     - chain types paradigm (example: simplechain) (optional)
 //  do.Tarball       - instead of outputing raw files in directories, output packages of tarbals (optional)
 //  do.ZipFile       - similar to do.Tarball except uses zipfiles (optional)
-//  do.Verbose       - verbose output (optional)
-//  do.Debug         - debug output (optional)
+//  do.Output        - display the accounts made (optional)
 //
 func MakeChain(do *definitions.Do) error {
-	do.Service.Name = do.Name
-	do.Service.Image = path.Join(version.ERIS_REG_DEF, version.ERIS_IMG_CM)
-	do.Service.User = "eris"
-	do.Service.AutoData = true
-	do.Service.Links = []string{fmt.Sprintf("%s:%s", util.ServiceContainersName("keys"), "keys")}
-	do.Service.Environment = []string{
-		fmt.Sprintf("ERIS_KEYS_PATH=http://keys:%d", 4767), // note, needs to be made aware of keys port...
-		fmt.Sprintf("ERIS_CHAINMANAGER_ACCOUNTTYPES=%s", strings.Join(do.AccountTypes, ",")),
-		fmt.Sprintf("ERIS_CHAINMANAGER_CHAINTYPE=%s", do.ChainType),
-		fmt.Sprintf("ERIS_CHAINMANAGER_TARBALLS=%v", do.Tarball),
-		fmt.Sprintf("ERIS_CHAINMANAGER_ZIPFILES=%v", do.ZipFile),
-		fmt.Sprintf("ERIS_CHAINMANAGER_OUTPUT=%v", do.Output),
-		fmt.Sprintf("ERIS_CHAINMANAGER_VERBOSE=%v", do.Verbose),
-		fmt.Sprintf("ERIS_CHAINMANAGER_DEBUG=%v", do.Debug),
-	}
-
-	do.Operations.ContainerType = "service"
-	do.Operations.SrvContainerName = util.ServiceContainersName(do.Name)
-	do.Operations.DataContainerName = util.DataContainersName(do.Name)
-	if do.RmD {
-		do.Operations.Remove = true
-	}
-
-	if do.Known {
-		log.Debug("Using MintGen rather than eris:cm")
-		do.Service.EntryPoint = "mintgen"
-		do.Service.Command = fmt.Sprintf("known %s --csv=%s,%s > %s", do.Name, do.ChainMakeVals, do.ChainMakeActs, path.Join(ErisContainerRoot, "chains", do.Name, "genesis.json"))
-	} else {
-		log.Debug("Using eris:cm rather than MintGen")
-		do.Service.EntryPoint = fmt.Sprintf("eris-cm make %s", do.Name)
-	}
-
-	if !do.Known && len(do.AccountTypes) == 0 && do.ChainType == "" {
-		do.Operations.Interactive = true
-		do.Operations.Args = strings.Split(do.Service.EntryPoint, " ")
-	}
+	dir := filepath.Join(ChainsPath, do.Name)
 
 	if do.Known {
-		do.Operations.Args = append(do.Operations.Args, strings.Split(do.Service.Command, " ")...)
-		do.Service.WorkDir = path.Join(ErisContainerRoot, "chains", do.Name)
-	}
-
-	doData := definitions.NowDo()
-	doData.Name = do.Name
-
-	doData.Operations.DataContainerName = util.DataContainersName(do.Name)
-	doData.Operations.ContainerType = "service"
-
-	doData.Source = AccountsTypePath
-	doData.Destination = path.Join(ErisContainerRoot, "chains", "account-types")
-	if err := data.ImportData(doData); err != nil {
-		return err
-	}
-	doData.Source = ChainTypePath
-	doData.Destination = path.Join(ErisContainerRoot, "chains", "chain-types")
-	if err := data.ImportData(doData); err != nil {
-		return err
-	}
-	chnPath := filepath.Join(ChainsPath, do.Name)
-	if _, err := os.Stat(chnPath); !os.IsNotExist(err) {
-		doData.Operations.Args = []string{"mkdir", "--parents", path.Join(ErisContainerRoot, "chains", do.Name)}
-		if _, err := data.ExecData(doData); err != nil {
+		log.Debug("Using known keys")
+		validators, accounts, err := knownAccounts(do.ChainMakeVals, do.ChainMakeActs)
+		if err != nil {
 			return err
 		}
-		doData.Operations.Args = []string{}
-		doData.Source = chnPath
-		doData.Destination = path.Join(ErisContainerRoot, "chains", do.Name)
-		if err := data.ImportData(doData); err != nil {
+		if err := os.MkdirAll(dir, 0700); err != nil {
 			return err
 		}
+		log.WithField("dir", dir).Warn("Writing genesis file")
+		return writeJSON(filepath.Join(dir, "genesis.json"), makeGenesis(do.Name, validators, accounts), 0600)
 	}
 
-	buf, err := perform.DockerExecService(do.Service, do.Operations)
+	types, err := loadAccountTypes()
 	if err != nil {
 		return err
 	}
-
-	io.Copy(config.GlobalConfig.Writer, buf)
-
-	doData.Source = path.Join(ErisContainerRoot, "chains")
-	doData.Destination = ErisRoot
-	if err := data.ExportData(doData); err != nil {
+	var numbers []accountNumber
+	switch {
+	case len(do.AccountTypes) != 0:
+		numbers, err = parseAccountTypes(types, do.AccountTypes)
+	case do.ChainType != "":
+		var chain *chainType
+		if chain, err = loadChainType(do.ChainType); err == nil {
+			numbers, err = chainTypeNumbers(types, chain)
+		}
+	default:
+		numbers, err = askAccountTypes(types, os.Stdin, config.GlobalConfig.InteractiveWriter)
+	}
+	if err != nil {
 		return err
 	}
 
-	if !do.RmD {
-		return data.RmData(doData)
+	log.WithField("=>", do.Name).Warn("Making keys")
+	accounts, err := makeAccounts(do.Name, numbers)
+	if err != nil {
+		return err
+	}
+	if err := writeChainFiles(do, dir, makeGenesis(do.Name, bonded(accounts), accounts), accounts); err != nil {
+		return err
 	}
 
+	if do.Output {
+		printAccounts(accounts, dir)
+	}
 	return nil
 }
 
@@ -192,7 +151,7 @@ func InspectChain(do *definitions.Do) error {
 }
 
 // LogsChain returns the logs of a chains' service container
-// for display by the user.
+// (or of all node containers of a chain network) for display by the user.
 //
 //  do.Name    - name of the chain (required)
 //  do.Follow  - follow the logs until the user sends SIGTERM (optional)
@@ -204,11 +163,33 @@ func LogsChain(do *definitions.Do) error {
 		return err
 	}
 
-	err = perform.DockerLogs(chain.Service, chain.Operations, do.Follow, do.Tail)
-	if err != nil {
-		return err
+	nodes := networkOperations(chain)
+	if len(nodes) == 1 {
+		return perform.DockerLogs(chain.Service, chain.Operations, do.Follow, do.Tail)
 	}
 
+	if !do.Follow {
+		for _, ops := range nodes {
+			log.WithField("=>", ops.SrvContainerName).Warn("Chain node logs")
+			if err := perform.DockerLogs(chain.Service, ops, false, do.Tail); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Followed logs of all nodes are displayed as they come.
+	errs := make(chan error, len(nodes))
+	for _, ops := range nodes {
+		go func(ops *definitions.Operation) {
+			errs <- perform.DockerLogs(chain.Service, ops, true, do.Tail)
+		}(ops)
+	}
+	for range nodes {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -247,15 +228,44 @@ To find known chains use: eris chains ls --known`)
 // scoping function which is used by other portions of the
 // platform where a --chain flag may otherwise be used.
 //
-//  do.Name - the name of the chain to checkout; if blank will "uncheckout" current chain (optional)
+//  do.Name  - the name of the chain to checkout; if blank will "uncheckout" current chain; if "-" will checkout the previous chain (optional)
+//  do.Local - checkout the chain for the working directory only, in a .eris-chain file (optional)
 //
 func CheckoutChain(do *definitions.Do) error {
+	if do.Local {
+		if do.Name == "-" {
+			return fmt.Errorf("Cannot checkout the previous chain with --local")
+		}
+		if do.Name == "" {
+			do.Result = "nil"
+		}
+		return util.ChangeLocalHead(do.Name)
+	}
+
+	if do.Name == "-" {
+		previous, err := util.PreviousHead()
+		if err != nil {
+			return err
+		}
+		if previous != "" && !util.IsKnownChain(previous) {
+			return fmt.Errorf("The previously checked out chain %s is not known anymore", previous)
+		}
+		do.Name = previous
+		if previous != "" {
+			log.Warn(previous)
+		}
+	}
+
+	if file := util.FindLocalHead(); file != "" {
+		log.WithField("file", file).Warn("The chain checked out in this directory takes precedence")
+	}
+
 	if do.Name == "" {
 		do.Result = "nil"
 		return util.NullHead()
 	}
 
-	curHead, _ := util.GetHead()
+	curHead, _ := util.GetGlobalHead()
 	if do.Name == curHead {
 		do.Result = "no change"
 		return nil
@@ -277,6 +287,50 @@ func CurrentChain(do *definitions.Do) error {
 	log.Warn(head)
 	do.Result = head
 
+	if file := util.FindLocalHead(); file != "" {
+		if local, _ := util.ReadLocalHead(file); local != "" {
+			log.WithField("file", file).Info("Checked out in the directory")
+		}
+	}
+
+	return nil
+}
+
+// ChainHistory displays the recently checked out chains, most recent
+// first, with the time of the checkout.
+//
+//  do.Lines - number of checkouts to display; all if 0 (optional)
+//
+func ChainHistory(do *definitions.Do) error {
+	history, err := util.ReadHeadHistory()
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetBorder(false)
+	table.SetColumnSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"CHAIN", "CHECKED OUT"})
+	var n int
+	for _, entry := range history {
+		if do.Lines > 0 && n == do.Lines {
+			break
+		}
+		if entry.Name == "" && entry.Time.IsZero() {
+			continue
+		}
+		name, when := entry.Name, "unknown"
+		if name == "" {
+			name = "(none)"
+		}
+		if !entry.Time.IsZero() {
+			when = entry.Time.Local().Format(time.RFC822)
+		}
+		table.Append([]string{name, when})
+		n++
+	}
+	table.Render()
 	return nil
 }
 
@@ -300,6 +354,13 @@ func CatChain(do *definitions.Do) error {
 		// [pv]: can't have 0.0.0.0 on OSX or Windows.
 		do.Operations.Args = []string{"mintinfo", "--node-addr", "http://0.0.0.0:46657", "validators"}
 	case "toml":
+		if do.Format != "" {
+			chainDef, err := loaders.ReadChainDefinition(do.Name)
+			if err != nil {
+				return err
+			}
+			return EncodeChainDefinition(config.GlobalConfig.Writer, chainDef, do.Format)
+		}
 		cat, err := ioutil.ReadFile(filepath.Join(ChainsPath, do.Name+".toml"))
 		if err != nil {
 			return err
@@ -352,6 +413,17 @@ func EditChain(do *definitions.Do) error {
 	return Editor(chainDefFile)
 }
 
+// renameChainDefinition prepares the loaded chain definition to be
+// written under the new name.
+func renameChainDefinition(chainDef *definitions.Chain, name string) {
+	chainDef.Name = name
+	// Generally we won't want to use Service.Name
+	// as it will be confused with the Name.
+	chainDef.Service.Name = ""
+	// Service.Image should be taken from the default.toml.
+	chainDef.Service.Image = ""
+}
+
 // XXX: What's going on here? => [csk]: magic
 func RenameChain(do *definitions.Do) error {
 	if do.Name == do.NewName {
@@ -399,12 +471,7 @@ func RenameChain(do *definitions.Do) error {
 			newFile = filepath.Join(ChainsPath, do.NewName)
 		}
 
-		chainDef.Name = newNameBase
-		// Generally we won't want to use Service.Name
-		// as it will be confused with the Name.
-		chainDef.Service.Name = ""
-		// Service.Image should be taken from the default.toml.
-		chainDef.Service.Image = ""
+		renameChainDefinition(chainDef, newNameBase)
 		err = WriteChainDefinitionFile(chainDef, newFile)
 		if err != nil {
 			return err
@@ -428,6 +495,43 @@ func RenameChain(do *definitions.Do) error {
 	return nil
 }
 
+// DiffChain compares the chain container with the container the chain
+// definition file describes, field by field. It returns services.ErrDrift
+// if they differ (unless do.Fix is set).
+//
+//  do.Name    - name of the chain (required)
+//  do.Run     - the chain was started using erisdb's api
+//  do.Fix     - recreate the container from the definition file on drift
+//  do.Timeout - number of seconds to wait for the container to stop
+//
+func DiffChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	if util.FindChainContainer(do.Name, true) == nil {
+		return fmt.Errorf("Chain %s has no container. Start it with [eris chains start %s]", do.Name, do.Name)
+	}
+
+	// Configure the container the same way startChain does.
+	chain.Service.Command = loaders.ErisChainStart
+	chain.Service.Environment = append(chain.Service.Environment, "CHAIN_ID="+chain.ChainID)
+	if do.Run {
+		chain.Service.Environment = append(chain.Service.Environment, "ERISDB_API=true")
+	}
+
+	drift, err := perform.DockerDiff(chain.Service, chain.Operations)
+	if err != nil {
+		return err
+	}
+	return services.ReportDrift(&definitions.ServiceDefinition{
+		Name:       chain.Name,
+		Service:    chain.Service,
+		Operations: chain.Operations,
+		Hooks:      chain.Hooks,
+	}, drift, do)
+}
+
 func UpdateChain(do *definitions.Do) error {
 	chain, err := loaders.LoadChainDefinition(do.Name, false)
 	if err != nil {
@@ -442,7 +546,12 @@ func UpdateChain(do *definitions.Do) error {
 		chain.Service.Command = loaders.ErisChainStart
 	}
 
-	err = perform.DockerRebuild(chain.Service, chain.Operations, do.Pull, do.Timeout)
+	err = services.RebuildContainer(&definitions.ServiceDefinition{
+		Name:       chain.Name,
+		Service:    chain.Service,
+		Operations: chain.Operations,
+		Hooks:      chain.Hooks,
+	}, do.Pull, do.Timeout)
 	if err != nil {
 		return err
 	}
@@ -456,8 +565,10 @@ func RmChain(do *definitions.Do) error {
 	}
 
 	if IsChainExisting(chain) {
-		if err = perform.DockerRemove(chain.Service, chain.Operations, do.RmD, do.Volumes, do.Force); err != nil {
-			return err
+		for _, ops := range networkOperations(chain) {
+			if err = perform.DockerRemove(chain.Service, ops, do.RmD, do.Volumes, do.Force); err != nil {
+				return err
+			}
 		}
 	} else {
 		log.Info("Chain container does not exist")
@@ -489,6 +600,25 @@ func GraduateChain(do *definitions.Do) error {
 	return nil
 }
 
+// GraphChain displays the dependency graph of chains: the chains
+// and services [eris chains start --all] would bring up.
+//
+//  do.Operations.Args - names of the chains (all known chains if empty)
+//  do.GraphFormat     - text (default) or dot
+//
+func GraphChain(do *definitions.Do) error {
+	names := do.Operations.Args
+	if len(names) == 0 {
+		names = util.GetGlobalLevelConfigFilesByType("chains", false)
+	}
+
+	g := services.NewGraph("")
+	for _, name := range names {
+		g.AddChain(name)
+	}
+	return services.WriteGraph(g, do)
+}
+
 func exportFile(chainName string) (string, error) {
 	fileName := util.GetFileByNameAndType("chains", chainName)
 
@@ -0,0 +1,190 @@
+package chains
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// Chain networks made with [eris chains new --nodes N] are chains with N
+// containers: the number-th node of the chain runs in the number-th chain
+// container with a data container of its own (eris_chain_NAME_1,
+// eris_data_NAME_1, eris_chain_NAME_2, etc.). The first node is an
+// ordinary chain; the others link to the nodes before them and have them
+// as seeds.
+
+// peerPort is the port chain nodes connect to each other on.
+const peerPort = 46656
+
+// Nodes returns the number of nodes of the chain: 1 for ordinary chains.
+func Nodes(name string) int {
+	nodes := util.HowManyContainersExisting(name, definitions.TypeData)
+	if chains := util.HowManyContainersExisting(name, definitions.TypeChain); chains > nodes {
+		nodes = chains
+	}
+	if nodes < 1 {
+		return 1
+	}
+	return nodes
+}
+
+// nodeOperations returns the operations of the number-th node of the chain.
+// Nodes other than the first one publish their ports on random host ports,
+// so that they don't collide with the first node.
+func nodeOperations(chain *definitions.Chain, number int) *definitions.Operation {
+	ops := *chain.Operations
+	ops.SrvContainerName = util.NumberedContainersName(definitions.TypeChain, chain.Name, number)
+	ops.DataContainerName = util.NumberedContainersName(definitions.TypeData, chain.Name, number)
+	ops.SrvContainerID = ""
+	ops.DataContainerID = ""
+
+	ops.Labels = make(map[string]string)
+	for k, v := range chain.Operations.Labels {
+		ops.Labels[k] = v
+	}
+	ops.Labels = util.SetLabel(ops.Labels, definitions.LabelNumber, strconv.Itoa(number))
+
+	if number > 1 {
+		ops.PublishAllPorts = true
+	}
+	return &ops
+}
+
+// networkOperations returns the operations of all nodes of the chain.
+func networkOperations(chain *definitions.Chain) []*definitions.Operation {
+	var nodes []*definitions.Operation
+	for number := 1; number <= Nodes(chain.Name); number++ {
+		nodes = append(nodes, nodeOperations(chain, number))
+	}
+	return nodes
+}
+
+// nodeService returns the service of the number-th node of the chain.
+func nodeService(chain *definitions.Chain, number int) *definitions.Service {
+	srv := *chain.Service
+	srv.Links = append(append([]string{}, chain.Service.Links...), nodeLinks(chain.Name, number)...)
+	return &srv
+}
+
+// nodeLinks returns the links of the number-th node to the nodes before it.
+func nodeLinks(name string, number int) []string {
+	var links []string
+	for peer := 1; peer < number; peer++ {
+		links = append(links, fmt.Sprintf("%s:%s", util.NumberedContainersName(definitions.TypeChain, name, peer), nodeHost(peer)))
+	}
+	return links
+}
+
+// nodeSeeds returns the seeds setting of the number-th node.
+func nodeSeeds(number int) string {
+	var seeds []string
+	for peer := 1; peer < number; peer++ {
+		seeds = append(seeds, fmt.Sprintf("%s:%d", nodeHost(peer), peerPort))
+	}
+	return strings.Join(seeds, ",")
+}
+
+func nodeHost(number int) string {
+	return fmt.Sprintf("node%d", number)
+}
+
+// setConfigSeeds sets the top level seeds setting of the config.toml
+// file, adding it if it's missing.
+func setConfigSeeds(file, seeds string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, util.SetTOMLValue(content, "seeds", strconv.Quote(seeds)), 0600)
+}
+
+// validatorDirs returns the sorted subdirectories of dir which have
+// a priv_validator.json file in them, such as the ones [eris chains make]
+// creates for each account.
+func validatorDirs(dir string) []string {
+	var dirs []string
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, entry.Name(), "priv_validator.json")); err == nil {
+			dirs = append(dirs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// setupNetwork sets up do.N nodes of the chain sharing a genesis file,
+// one for each validator directory in do.Path. If do.Path is not given,
+// the validator keys and the genesis file are made with [eris chains make].
+func setupNetwork(do *definitions.Do, cmd string) error {
+	dir := do.Path
+	if dir != "" {
+		if src, err := os.Stat(dir); err != nil || !src.IsDir() {
+			if dir, err = util.ChainsPathChecker(dir); err != nil {
+				return err
+			}
+		}
+	} else {
+		log.WithField("validators", do.N).Warn("Making validator keys and the genesis file")
+		doMake := definitions.NowDo()
+		doMake.Name = do.Name
+		doMake.AccountTypes = []string{fmt.Sprintf("Full:%d", do.N)}
+		if err := MakeChain(doMake); err != nil {
+			return err
+		}
+		dir = filepath.Join(ChainsPath, do.Name)
+	}
+
+	dirs := validatorDirs(dir)
+	if len(dirs) < int(do.N) {
+		return fmt.Errorf("Cannot make a network of %d nodes: found %d validator directories in %s", do.N, len(dirs), dir)
+	}
+
+	for number := 1; number <= int(do.N); number++ {
+		log.WithFields(log.Fields{
+			"=>":   do.Name,
+			"node": number,
+			"dir":  dirs[number-1],
+		}).Warn("Setting up chain node")
+
+		nodeDo := *do
+		nodeOps := *do.Operations
+		nodeDo.Operations = &nodeOps
+		nodeDo.Path = dirs[number-1]
+		if err := setupNode(&nodeDo, cmd, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configOptions returns the list of <key>=<value> config options
+// as flags for the chain container.
+func configOptions(opts []string) (string, error) {
+	buf := new(bytes.Buffer)
+	for _, cv := range opts {
+		spl := strings.Split(cv, "=")
+		if len(spl) != 2 {
+			return "", fmt.Errorf("Config options should be <key>=<value> pairs. Got %s", cv)
+		}
+		buf.WriteString(fmt.Sprintf(" --%s=%s", spl[0], spl[1]))
+	}
+	return buf.String(), nil
+}
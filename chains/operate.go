@@ -8,11 +8,13 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/eris-ltd/eris-cli/config"
 	"github.com/eris-ltd/eris-cli/data"
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/hooks"
 	"github.com/eris-ltd/eris-cli/loaders"
 	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/services"
@@ -44,6 +46,74 @@ func InstallChain(do *definitions.Do) error {
 	return setupChain(do, loaders.ErisChainInstall)
 }
 
+// RestartChain restarts the chain, waiting for it to become ready.
+// If the chain is recreated, running services depending on it
+// are restarted as well, so that their links get refreshed.
+//
+//  do.Name     - name of the chain to restart (required)
+//  do.All      - restart the service dependencies of the chain first
+//  do.Recreate - recreate the chain container instead of restarting it
+//  do.Pull     - pull an updated image before recreating (implies do.Recreate)
+//  do.Env      - environment variables to add to the chain (implies do.Recreate)
+//  do.Links    - links to add to the chain (implies do.Recreate)
+//  do.Run      - if recreated, turn the chain on using erisdb's api
+//  do.Timeout  - number of seconds to wait for the chain to stop
+//
+func RestartChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+
+	// Configure the container the same way startChain does.
+	chain.Service.Command = loaders.ErisChainStart
+	util.Merge(chain.Operations, do.Operations)
+	chain.Service.Environment = append(chain.Service.Environment, "CHAIN_ID="+chain.ChainID)
+	chain.Service.Environment = append(chain.Service.Environment, do.Env...)
+	if do.Run {
+		chain.Service.Environment = append(chain.Service.Environment, "ERISDB_API=true")
+	}
+	chain.Service.Links = append(chain.Service.Links, do.Links...)
+
+	if len(do.Env) != 0 || len(do.Links) != 0 {
+		do.Recreate = true
+	}
+
+	var group []*definitions.ServiceDefinition
+	if do.All && chain.Dependencies != nil {
+		for _, srvName := range chain.Dependencies.Services {
+			s, err := services.BuildServicesGroup(srvName)
+			if err != nil {
+				return err
+			}
+			group = append(group, s...)
+		}
+	}
+	group = append(group, &definitions.ServiceDefinition{
+		Name:       chain.Name,
+		Service:    chain.Service,
+		Operations: chain.Operations,
+		Machine:    chain.Machine,
+		Hooks:      chain.Hooks,
+	})
+
+	if err := services.CheckMachineRequirements(group); err != nil {
+		return err
+	}
+
+	recreated, err := services.RestartGroup(group, do.Recreate, do.Pull, do.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if err := services.RestartDependents(recreated, do.Timeout); err != nil {
+		return err
+	}
+
+	do.Result = "success"
+	return nil
+}
+
 func KillChain(do *definitions.Do) error {
 	chain, err := loaders.LoadChainDefinition(do.Name, false)
 	if err != nil {
@@ -54,17 +124,36 @@ func KillChain(do *definitions.Do) error {
 		do.Timeout = 0 //overrides 10 sec default
 	}
 
-	if IsChainRunning(chain) {
-		if err := perform.DockerStop(chain.Service, chain.Operations, do.Timeout); err != nil {
+	nodes := networkOperations(chain)
+
+	// Every node container is checked and stopped with the hooks
+	// on its own, since any of them may be running.
+	var running bool
+	for _, ops := range nodes {
+		if _, up := perform.ContainerRunning(ops); !up {
+			log.WithField("=>", ops.SrvContainerName).Debug("Node not running. Skipping")
+			continue
+		}
+		running = true
+		if err := hooks.Run(definitions.HookPreStop, chain.Name, chain.Hooks, chain.Service, ops); err != nil {
 			return err
 		}
-	} else {
+		if err := perform.DockerStop(chain.Service, ops, do.Timeout); err != nil {
+			return err
+		}
+		if err := hooks.Run(definitions.HookPostStop, chain.Name, chain.Hooks, chain.Service, ops); err != nil {
+			return err
+		}
+	}
+	if !running {
 		log.Info("Chain not currently running. Skipping")
 	}
 
 	if do.Rm {
-		if err := perform.DockerRemove(chain.Service, chain.Operations, do.RmD, do.Volumes, do.Force); err != nil {
-			return err
+		for _, ops := range nodes {
+			if err := perform.DockerRemove(chain.Service, ops, do.RmD, do.Volumes, do.Force); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -81,9 +170,12 @@ func ExecChain(do *definitions.Do) (buf *bytes.Buffer, err error) {
 	return startChain(do, true)
 }
 
-// Throw away chains are used for eris contracts
+// Throw away chains are used for eris contracts. Their definition
+// files have the throwaway chain_type and their containers are labeled,
+// so that [eris chains gc] can find what is left of them.
 func ThrowAwayChain(do *definitions.Do) error {
 	do.Name = do.Name + "_" + strings.Split(uuid.New(), "-")[0]
+	do.Chain.ChainType = definitions.ChainTypeThrowaway
 	do.Path = filepath.Join(ChainsPath, "default")
 	log.WithFields(log.Fields{
 		"=>":   do.Name,
@@ -116,6 +208,14 @@ func startChain(do *definitions.Do, exec bool) (buf *bytes.Buffer, err error) {
 		return nil, nil
 	}
 
+	// preflight checks from the [machine] section
+	if !exec && chain.Machine != nil && !IsChainRunning(chain) {
+		if unmet := util.UnmetRequirements(chain.Machine.Requires, do.Operations); len(unmet) != 0 {
+			do.Result = "error"
+			return nil, util.RequirementsReport(map[string][]string{chain.Name: unmet})
+		}
+	}
+
 	// boot the dependencies (eg. keys, logsrotate)
 	if err := bootDependencies(chain, do); err != nil {
 		return nil, err
@@ -164,7 +264,21 @@ func startChain(do *definitions.Do, exec bool) (buf *bytes.Buffer, err error) {
 
 		buf, err = perform.DockerExecService(chain.Service, chain.Operations)
 	} else {
+		// Hooks only run if the container is actually started.
+		running := IsChainRunning(chain)
+		if !running {
+			if err := hooks.Run(definitions.HookPreStart, chain.Name, chain.Hooks, chain.Service, chain.Operations); err != nil {
+				do.Result = "error"
+				return nil, err
+			}
+		}
 		err = perform.DockerRunService(chain.Service, chain.Operations)
+		if err == nil {
+			err = startNodes(chain)
+		}
+		if err == nil && !running {
+			err = hooks.Run(definitions.HookPostStart, chain.Name, chain.Hooks, chain.Service, chain.Operations)
+		}
 	}
 	if err != nil {
 		do.Result = "error"
@@ -174,6 +288,18 @@ func startChain(do *definitions.Do, exec bool) (buf *bytes.Buffer, err error) {
 	return buf, nil
 }
 
+// startNodes starts the nodes of a chain network other than the first one.
+func startNodes(chain *definitions.Chain) error {
+	for number := 2; number <= Nodes(chain.Name); number++ {
+		ops := nodeOperations(chain, number)
+		log.WithField("=>", ops.SrvContainerName).Info("Starting chain node")
+		if err := perform.DockerRunService(nodeService(chain, number), ops); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // boot chain dependencies
 // TODO: this currently only supports simple services (with no further dependencies)
 func bootDependencies(chain *definitions.Chain, do *definitions.Do) error {
@@ -226,7 +352,16 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	if do.Name == "" {
 		return fmt.Errorf("setupChain requires a chainame")
 	}
-	containerName := util.ChainContainersName(do.Name)
+	if do.N > 1 {
+		return setupNetwork(do, cmd)
+	}
+	return setupNode(do, cmd, 1)
+}
+
+// setupNode sets up the number-th node of the chain (see setupNetwork).
+func setupNode(do *definitions.Do, cmd string, number int) (err error) {
+	containerName := util.NumberedContainersName(definitions.TypeChain, do.Name, number)
+	dataContainerName := util.NumberedContainersName(definitions.TypeData, do.Name, number)
 	if do.ChainID == "" {
 		do.ChainID = do.Name
 	}
@@ -251,10 +386,15 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	}
 
 	// ensure/create data container
-	if util.IsDataContainer(do.Name) {
-		log.WithField("=>", do.Name).Debug("Chain data container already exists")
+	if util.FindNumberedContainer(definitions.TypeData, do.Name, number) != nil {
+		log.WithField("=>", dataContainerName).Debug("Chain data container already exists")
 	} else {
 		ops := loaders.LoadDataDefinition(do.Name)
+		ops.DataContainerName = dataContainerName
+		ops.Labels = util.SetLabel(ops.Labels, definitions.LabelNumber, strconv.Itoa(number))
+		if do.Chain.ChainType == definitions.ChainTypeThrowaway {
+			ops.Labels = util.SetLabel(ops.Labels, definitions.LabelThrowaway, "true")
+		}
 		if err := perform.DockerCreateData(ops); err != nil {
 			return fmt.Errorf("Error creating data container =>\t%v", err)
 		}
@@ -280,6 +420,9 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	// copy do.Path, do.GenesisFile, do.ConfigFile, do.Priv, do.CSV into container
 	containerDst := path.Join(ErisContainerRoot, "chains", do.ChainID) // path in container
 	dst := filepath.Join(DataContainersPath, do.Name, containerDst)    // path on host
+	if number > 1 {
+		dst = filepath.Join(DataContainersPath, do.Name, nodeHost(number), containerDst)
+	}
 
 	log.WithFields(log.Fields{
 		"container path": containerDst,
@@ -326,6 +469,17 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 		return err
 	}
 
+	// nodes of a network connect to the nodes before them
+	configOpts := do.ConfigOpts
+	if number > 1 {
+		seeds := nodeSeeds(number)
+		if err := setConfigSeeds(filepath.Join(dst, "config.toml"), seeds); os.IsNotExist(err) {
+			configOpts = append(append([]string{}, configOpts...), "seeds="+seeds)
+		} else if err != nil {
+			return err
+		}
+	}
+
 	// copy from host to container
 	log.WithFields(log.Fields{
 		"from": dst,
@@ -333,7 +487,9 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	}).Debug("Copying files into data container")
 	importDo := definitions.NowDo()
 	importDo.Name = do.Name
-	importDo.Operations = do.Operations
+	importOps := *do.Operations
+	importOps.DataContainerName = dataContainerName
+	importDo.Operations = &importOps
 	importDo.Destination = containerDst
 	importDo.Source = dst
 	if err = data.ImportData(importDo); err != nil {
@@ -341,6 +497,9 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	}
 
 	chain := loaders.MockChainDefinition(do.Name, do.ChainID, false)
+	if do.Chain.ChainType == definitions.ChainTypeThrowaway {
+		chain.ChainType = definitions.ChainTypeThrowaway
+	}
 
 	//set maintainer info
 	chain.Maintainer.Name, chain.Maintainer.Email, err = config.GitConfigUser()
@@ -367,22 +526,17 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	chain.Service.Command = cmd
 
 	// write the list of <key>:<value> config options as flags
-	buf := new(bytes.Buffer)
-	for _, cv := range do.ConfigOpts {
-		spl := strings.Split(cv, "=")
-		if len(spl) != 2 {
-			return fmt.Errorf("Config options should be <key>=<value> pairs. Got %s", cv)
-		}
-		buf.WriteString(fmt.Sprintf(" --%s=%s", spl[0], spl[1]))
+	configFlags, err := configOptions(configOpts)
+	if err != nil {
+		return err
 	}
-	configOpts := buf.String()
 
 	// set chainid and other vars
 	envVars := []string{
 		fmt.Sprintf("CHAIN_ID=%s", do.ChainID),
 		fmt.Sprintf("CONTAINER_NAME=%s", containerName),
 		fmt.Sprintf("CSV=%v", csvPaths),                                          // functionality is deprecated. to remove for 0.11.4
-		fmt.Sprintf("CONFIG_OPTS=%s", configOpts),                                // for config.toml
+		fmt.Sprintf("CONFIG_OPTS=%s", configFlags),                               // for config.toml
 		fmt.Sprintf("NODE_ADDR=%s", do.Gateway),                                  // etcb host
 		fmt.Sprintf("DOCKER_FIX=%s", "                                        "), // https://github.com/docker/docker/issues/14203
 	}
@@ -400,9 +554,8 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	chain.Service.Environment = append(chain.Service.Environment, envVars...)
 	chain.Service.Links = append(chain.Service.Links, do.Links...)
 
-	// TODO: if do.N > 1 ...
-
-	chain.Operations.DataContainerName = util.DataContainersName(do.Name)
+	chain.Operations = nodeOperations(chain, number)
+	chain.Service.Links = append(chain.Service.Links, nodeLinks(do.Name, number)...)
 
 	if err := bootDependencies(chain, do); err != nil {
 		return err
@@ -419,6 +572,7 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 	log.Info("Moving priv_validator.json into eris-keys")
 	doKeys := definitions.NowDo()
 	doKeys.Name = do.Name
+	doKeys.Operations.DataContainerName = dataContainerName
 	doKeys.Operations.Args = []string{"mintkey", "eris", fmt.Sprintf("%s/chains/%s/priv_validator.json", ErisContainerRoot, do.Name)}
 	if out, err := ExecChain(doKeys); err != nil {
 		log.Error(out)
@@ -427,6 +581,7 @@ func setupChain(do *definitions.Do, cmd string) (err error) {
 
 	doChown := definitions.NowDo()
 	doChown.Name = do.Name
+	doChown.Operations.DataContainerName = dataContainerName
 	doChown.Operations.Args = []string{"chown", "--recursive", "eris", ErisContainerRoot}
 	if out2, err2 := ExecChain(doChown); err != nil {
 		log.Error(out2)
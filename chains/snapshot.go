@@ -0,0 +1,365 @@
+package chains
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient/external/github.com/docker/docker/pkg/archive"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// A snapshot of a chain is a directory in SnapshotsPath(NAME) named after
+// its tag with a gzipped tarball of the chain directory of every node's
+// data container (block store, state, and priv_validator.json, so that
+// validators don't refuse to sign heights they have signed before) and
+// a manifest.
+const snapshotManifestFile = "snapshot.json"
+
+var snapshotTag = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Snapshot is the manifest of a chain snapshot.
+type Snapshot struct {
+	Tag     string    `json:"tag"`
+	Chain   string    `json:"chain"`
+	ChainID string    `json:"chain_id"`
+	Created time.Time `json:"created"`
+	Nodes   int       `json:"nodes"`
+}
+
+// SnapshotsPath returns the directory snapshots of the chain are kept in.
+func SnapshotsPath(name string) string {
+	return filepath.Join(ErisRoot, "snapshots", name)
+}
+
+func snapshotPath(name, tag string) (string, error) {
+	if !snapshotTag.MatchString(tag) {
+		return "", fmt.Errorf("Invalid snapshot tag %q. Use letters, digits, dots, dashes, and underscores", tag)
+	}
+	return filepath.Join(SnapshotsPath(name), tag), nil
+}
+
+func nodeArchive(dir string, number int) string {
+	return filepath.Join(dir, "node"+strconv.Itoa(number)+".tar.gz")
+}
+
+// SnapshotChain archives the chain directories of the data containers of
+// the chain under the tag. A running chain is stopped for the snapshot
+// and started again afterwards.
+//
+//  do.Name    - name of the chain (required)
+//  do.Tag     - tag of the snapshot (required)
+//  do.Force   - replace an existing snapshot with the same tag (optional)
+//  do.Timeout - number of seconds to wait for the chain to stop (optional)
+//
+func SnapshotChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	dir, err := snapshotPath(chain.Name, do.Tag)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); err == nil && !do.Force {
+		return fmt.Errorf("Snapshot %s of %s already exists. Use --force to replace it", do.Tag, chain.Name)
+	}
+	if !util.IsDataContainer(chain.Name) {
+		return fmt.Errorf("There is no data container for %s. Only chains with data containers can be snapshotted", chain.Name)
+	}
+
+	// Write to a temporary directory first, so a failed snapshot
+	// doesn't replace a good one.
+	temp := dir + ".new"
+	os.RemoveAll(temp)
+	if err := os.MkdirAll(temp, 0755); err != nil {
+		return err
+	}
+	defer os.RemoveAll(temp)
+
+	snapshot := &Snapshot{
+		Tag:     do.Tag,
+		Chain:   chain.Name,
+		ChainID: chain.ChainID,
+		Created: time.Now().UTC(),
+		Nodes:   Nodes(chain.Name),
+	}
+	if err := withChainStopped(chain, do.Timeout, func() error {
+		for number := 1; number <= snapshot.Nodes; number++ {
+			if err := snapshotNode(chain, number, nodeArchive(temp, number)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(temp, snapshotManifestFile), manifest, 0644); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(temp, dir); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"=>":  chain.Name,
+		"tag": do.Tag,
+	}).Warn("Chain snapshotted")
+	do.Result = dir
+	return nil
+}
+
+// ResetChain restores the chain directories of the data containers of
+// the chain from the snapshot with the tag. A running chain is stopped
+// for the reset and started again afterwards.
+//
+//  do.Name    - name of the chain (required)
+//  do.Tag     - tag of the snapshot (required)
+//  do.Timeout - number of seconds to wait for the chain to stop (optional)
+//
+func ResetChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	snapshot, err := ReadSnapshot(chain.Name, do.Tag)
+	if err != nil {
+		return err
+	}
+	if nodes := Nodes(chain.Name); snapshot.Nodes != nodes {
+		return fmt.Errorf("Snapshot %s of %s has %d nodes, the chain has %d", do.Tag, chain.Name, snapshot.Nodes, nodes)
+	}
+
+	dir, _ := snapshotPath(chain.Name, do.Tag)
+	if err := withChainStopped(chain, do.Timeout, func() error {
+		for number := 1; number <= snapshot.Nodes; number++ {
+			if err := restoreNode(chain, number, nodeArchive(dir, number)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"=>":  chain.Name,
+		"tag": do.Tag,
+	}).Warn("Chain reset")
+	return nil
+}
+
+// ReadSnapshot returns the manifest of the snapshot of the chain.
+func ReadSnapshot(name, tag string) (*Snapshot, error) {
+	dir, err := snapshotPath(name, tag)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, snapshotManifestFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("There is no snapshot %s of %s. Check the tags with [eris chains snapshot ls %s]", tag, name, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(content, snapshot); err != nil {
+		return nil, fmt.Errorf("Cannot read the snapshot manifest: %v", err)
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots returns the snapshots of the chain, oldest first.
+func ListSnapshots(name string) ([]*Snapshot, error) {
+	entries, err := ioutil.ReadDir(SnapshotsPath(name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*Snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() || !snapshotTag.MatchString(entry.Name()) {
+			continue
+		}
+		snapshot, err := ReadSnapshot(name, entry.Name())
+		if err != nil {
+			log.WithField("tag", entry.Name()).Debugf("Skipping snapshot: %v", err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Sort(snapshotsByCreated(snapshots))
+	return snapshots, nil
+}
+
+type snapshotsByCreated []*Snapshot
+
+func (s snapshotsByCreated) Len() int           { return len(s) }
+func (s snapshotsByCreated) Less(i, j int) bool { return s[i].Created.Before(s[j].Created) }
+func (s snapshotsByCreated) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// ListChainSnapshots displays the snapshots of the chain.
+//
+//  do.Name - name of the chain (required)
+//
+func ListChainSnapshots(do *definitions.Do) error {
+	snapshots, err := ListSnapshots(do.Name)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetBorder(false)
+	table.SetColumnSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"TAG", "CREATED", "NODES", "CHAIN ID"})
+	for _, snapshot := range snapshots {
+		table.Append([]string{snapshot.Tag, snapshot.Created.Local().Format(time.RFC822), strconv.Itoa(snapshot.Nodes), snapshot.ChainID})
+	}
+	table.Render()
+	return nil
+}
+
+// RmSnapshot removes the snapshot of the chain with the tag.
+//
+//  do.Name - name of the chain (required)
+//  do.Tag  - tag of the snapshot (required)
+//
+func RmSnapshot(do *definitions.Do) error {
+	if _, err := ReadSnapshot(do.Name, do.Tag); err != nil {
+		return err
+	}
+	dir, _ := snapshotPath(do.Name, do.Tag)
+	log.WithFields(log.Fields{
+		"=>":  do.Name,
+		"tag": do.Tag,
+	}).Warn("Removing snapshot")
+	return os.RemoveAll(dir)
+}
+
+// withChainStopped runs fn with the chain stopped, starting it again
+// afterwards if it was running.
+func withChainStopped(chain *definitions.Chain, timeout uint, fn func() error) error {
+	if !IsChainRunning(chain) {
+		return fn()
+	}
+
+	doStop := definitions.NowDo()
+	doStop.Name = chain.Name
+	doStop.Timeout = timeout
+	if err := KillChain(doStop); err != nil {
+		return err
+	}
+
+	err := fn()
+
+	doStart := definitions.NowDo()
+	doStart.Name = chain.Name
+	if err2 := StartChain(doStart); err2 != nil && err == nil {
+		err = err2
+	}
+	return err
+}
+
+// snapshotNode writes the chain directory of the data container of the
+// number-th node of the chain to the archive.
+func snapshotNode(chain *definitions.Chain, number int, file string) error {
+	temp, err := ioutil.TempDir("", "eris_snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(temp)
+
+	doExport := definitions.NowDo()
+	doExport.Name = chain.Name
+	doExport.Operations.DataContainerName = util.NumberedContainersName(definitions.TypeData, chain.Name, number)
+	doExport.Source = chainDir(chain)
+	doExport.Destination = temp
+	if err := data.ExportData(doExport); err != nil {
+		return err
+	}
+
+	reader, err := util.Tar(filepath.Join(temp, path.Base(chainDir(chain))), archive.Gzip)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// restoreNode replaces the chain directory of the data container of the
+// number-th node of the chain with the contents of the archive.
+func restoreNode(chain *definitions.Chain, number int, file string) error {
+	temp, err := ioutil.TempDir("", "eris_snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(temp)
+
+	in, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := util.Untar(in, chain.Name, temp); err != nil {
+		return fmt.Errorf("Cannot read the snapshot %s: %v", file, err)
+	}
+
+	dataContainerName := util.NumberedContainersName(definitions.TypeData, chain.Name, number)
+	for _, args := range [][]string{
+		{"rm", "--recursive", "--force", chainDir(chain)},
+		{"mkdir", "--parents", chainDir(chain)},
+	} {
+		doExec := definitions.NowDo()
+		doExec.Name = chain.Name
+		doExec.Operations.DataContainerName = dataContainerName
+		doExec.Operations.Args = args
+		if _, err := data.ExecData(doExec); err != nil {
+			return err
+		}
+	}
+
+	doImport := definitions.NowDo()
+	doImport.Name = chain.Name
+	doImport.Operations.DataContainerName = dataContainerName
+	doImport.Source = temp
+	doImport.Destination = chainDir(chain)
+	return data.ImportData(doImport)
+}
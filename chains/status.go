@@ -0,0 +1,261 @@
+package chains
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// rpcPort is the port of the tendermint RPC of eris:db.
+const rpcPort = "46657"
+
+// rpcClient queries the RPC of chain nodes.
+var rpcClient = &http.Client{Timeout: 5 * time.Second}
+
+// NodeStatus is the status of a chain node as reported by its RPC.
+type NodeStatus struct {
+	Node       int                `json:"node"`
+	RPC        string             `json:"rpc"`
+	ChainID    string             `json:"chain_id"`
+	Moniker    string             `json:"moniker"`
+	Height     int                `json:"latest_block_height"`
+	BlockTime  time.Time          `json:"latest_block_time"`
+	Peers      int                `json:"peers"`
+	Validators []*StatusValidator `json:"validators"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// StatusValidator is a validator of the validator set of a chain.
+type StatusValidator struct {
+	Address     string `json:"address"`
+	VotingPower int64  `json:"voting_power"`
+}
+
+// StatusChain displays the latest block height and time, the number of
+// peers, and the validator set of every node of the chain, queried over
+// the RPC port the node publishes on the host.
+//
+//  do.Name     - name of the chain (required)
+//  do.JSON     - write the status in JSON (optional)
+//  do.Watch    - keep displaying the status until interrupted (optional)
+//  do.Interval - number of seconds between status updates with do.Watch (optional)
+//
+func StatusChain(do *definitions.Do) error {
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	if !IsChainRunning(chain) {
+		return fmt.Errorf("Chain %s is not running. Start it with [eris chains start %s]", chain.Name, chain.Name)
+	}
+
+	interval := time.Duration(do.Interval) * time.Second
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+
+	for {
+		if err := printNodeStatuses(config.GlobalConfig.Writer, nodeStatuses(chain.Name), do.JSON); err != nil {
+			return err
+		}
+		if !do.Watch {
+			return nil
+		}
+		time.Sleep(interval)
+		fmt.Fprintln(config.GlobalConfig.Writer)
+	}
+}
+
+// nodeStatuses queries the status of every node of the chain. Nodes
+// which cannot be queried have the error set.
+func nodeStatuses(name string) []*NodeStatus {
+	var statuses []*NodeStatus
+	for number := 1; number <= Nodes(name); number++ {
+		status := &NodeStatus{Node: number}
+
+		c := util.FindNumberedContainer(definitions.TypeChain, name, number)
+		if c == nil {
+			status.Error = "not running"
+			statuses = append(statuses, status)
+			continue
+		}
+
+		rpc, err := util.PublishedPort(c.ContainerID, rpcPort)
+		if err != nil {
+			status.Error = err.Error()
+			statuses = append(statuses, status)
+			continue
+		}
+
+		if status, err = QueryNodeStatus(rpc); err != nil {
+			status = &NodeStatus{RPC: rpc, Error: err.Error()}
+		}
+		status.Node = number
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// QueryNodeStatus queries the status, peers, and validator set of the
+// chain node with the RPC at the HOST:PORT address.
+func QueryNodeStatus(rpc string) (*NodeStatus, error) {
+	var status struct {
+		NodeInfo struct {
+			Moniker string `json:"moniker"`
+			Network string `json:"network"`
+		} `json:"node_info"`
+		Height int             `json:"latest_block_height"`
+		Time   json.RawMessage `json:"latest_block_time"`
+	}
+	if err := rpcGet(rpc, "status", &status); err != nil {
+		return nil, err
+	}
+
+	var netInfo struct {
+		Peers []json.RawMessage `json:"peers"`
+	}
+	if err := rpcGet(rpc, "net_info", &netInfo); err != nil {
+		return nil, err
+	}
+
+	var validators struct {
+		Validators []*StatusValidator `json:"validators"`
+	}
+	if err := rpcGet(rpc, "validators", &validators); err != nil {
+		return nil, err
+	}
+
+	blockTime, err := blockTime(status.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeStatus{
+		RPC:        rpc,
+		ChainID:    status.NodeInfo.Network,
+		Moniker:    status.NodeInfo.Moniker,
+		Height:     status.Height,
+		BlockTime:  blockTime,
+		Peers:      len(netInfo.Peers),
+		Validators: validators.Validators,
+	}, nil
+}
+
+// rpcGet calls the RPC method and decodes its result. Results of
+// older tendermint versions are [TYPE, RESULT] pairs.
+func rpcGet(rpc, method string, result interface{}) error {
+	resp, err := rpcClient.Get("http://" + rpc + "/" + method)
+	if err != nil {
+		return fmt.Errorf("Cannot reach the chain RPC: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("The chain RPC returned %s for %s", resp.Status, method)
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("Cannot read the chain RPC %s response: %v", method, err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("The chain RPC returned an error for %s: %s", method, response.Error)
+	}
+
+	raw := bytes.TrimSpace(response.Result)
+	if len(raw) != 0 && raw[0] == '[' {
+		var pair []json.RawMessage
+		if err := json.Unmarshal(raw, &pair); err != nil || len(pair) != 2 {
+			return fmt.Errorf("Cannot read the chain RPC %s result", method)
+		}
+		raw = pair[1]
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("Cannot read the chain RPC %s result: %v", method, err)
+	}
+	return nil
+}
+
+// blockTime decodes block times, which are nanoseconds since the epoch
+// in older tendermint versions and RFC 3339 strings in newer ones.
+func blockTime(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, nil
+	}
+
+	var nanoseconds int64
+	if err := json.Unmarshal(raw, &nanoseconds); err == nil {
+		return time.Unix(0, nanoseconds).UTC(), nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return time.Time{}, fmt.Errorf("Cannot read the latest block time %s", raw)
+	}
+	return t.UTC(), nil
+}
+
+// printNodeStatuses writes the node statuses and the validator set (as
+// seen by the first node which could be queried) as tables or in JSON.
+func printNodeStatuses(w io.Writer, statuses []*NodeStatus, asJSON bool) error {
+	if asJSON {
+		out, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(out))
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetBorder(false)
+	table.SetColumnSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"NODE", "CHAIN ID", "HEIGHT", "BLOCK TIME", "PEERS", "VALIDATORS", "RPC"})
+
+	var validators []*StatusValidator
+	for _, status := range statuses {
+		if status.Error != "" {
+			table.Append([]string{strconv.Itoa(status.Node), status.Error, "", "", "", "", status.RPC})
+			continue
+		}
+		if validators == nil {
+			validators = status.Validators
+		}
+		var when string
+		if !status.BlockTime.IsZero() {
+			when = status.BlockTime.Local().Format(time.RFC822)
+		}
+		table.Append([]string{strconv.Itoa(status.Node), status.ChainID, strconv.Itoa(status.Height),
+			when, strconv.Itoa(status.Peers), strconv.Itoa(len(status.Validators)), status.RPC})
+	}
+	table.Render()
+
+	if len(validators) != 0 {
+		fmt.Fprintln(w)
+		table = tablewriter.NewWriter(w)
+		table.SetBorder(false)
+		table.SetColumnSeparator(" ")
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.SetHeader([]string{"VALIDATOR", "VOTING POWER"})
+		for _, validator := range validators {
+			table.Append([]string{strings.ToUpper(validator.Address), strconv.FormatInt(validator.VotingPower, 10)})
+		}
+		table.Render()
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package chains
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// UpgradeSnapshot is the tag of the snapshot UpgradeChain takes
+// before switching images and rolls back to on failure.
+const UpgradeSnapshot = "pre-upgrade"
+
+// errNodeStopped is returned by height queries once the node container
+// has exited, so the upgrade fails without waiting for the timeout.
+var errNodeStopped = errors.New("The chain container exited")
+
+// UpgradeChain switches a running chain to another image. The chain is
+// stopped and snapshotted, its containers are recreated from the new
+// image, and the upgrade succeeds once the block height of the first
+// node increases. Otherwise the chain is rolled back to the snapshot and
+// to the previous image. On success the previous image and its digest
+// are recorded in the chain definition file. With do.Rollback the chain
+// is upgraded (the same way) back to the recorded previous image.
+//
+//  do.Name     - name of the chain (required)
+//  do.Image    - image to upgrade the chain to (required unless do.Rollback)
+//  do.Rollback - switch the chain back to its previous image (optional)
+//  do.Timeout  - number of seconds to wait for the chain to stop (optional)
+//  do.Wait     - number of seconds to wait for the block height to increase (optional)
+//
+func UpgradeChain(do *definitions.Do) error {
+	if do.Image == "" && !do.Rollback {
+		return fmt.Errorf("Specify the image to upgrade the chain to with --image")
+	}
+	if do.Image != "" && do.Rollback {
+		return fmt.Errorf("Use either --image or --rollback")
+	}
+	chain, err := loaders.LoadChainDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	image := do.Image
+	if do.Rollback {
+		if chain.PreviousImage == "" {
+			return fmt.Errorf("Chain %s has no previous image to roll back to", chain.Name)
+		}
+		image = chain.PreviousImage
+	}
+	if !IsChainRunning(chain) {
+		return fmt.Errorf("Chain %s is not running. Start it with [eris chains start %s] first", chain.Name, chain.Name)
+	}
+	if chain.Service.Image == image {
+		return fmt.Errorf("Chain %s already runs %s", chain.Name, image)
+	}
+
+	// Make sure the new image is there before touching the chain.
+	if _, err := perform.DockerInspectImage(image); err != nil {
+		return fmt.Errorf("Cannot find the %s image: %v", image, err)
+	}
+
+	c := util.FindNumberedContainer(definitions.TypeChain, chain.Name, 1)
+	if c == nil {
+		return fmt.Errorf("Cannot find the container of chain %s", chain.Name)
+	}
+	container, err := util.DockerClient.InspectContainer(c.ContainerID)
+	if err != nil {
+		return err
+	}
+	previousID, digests := data.ImageDigests(chain.Service.Image, c.ContainerID)
+	previousDigest := previousID
+	if len(digests) != 0 {
+		previousDigest = digests[0]
+	}
+
+	// The definition file is restored byte for byte on rollback.
+	file := util.GetFileByNameAndType("chains", chain.Name)
+	if file == "" {
+		return fmt.Errorf("Cannot find the definition file of chain %s", chain.Name)
+	}
+	original, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	if err := removeChainContainers(chain.Name, do.Timeout); err != nil {
+		return err
+	}
+
+	doSnapshot := definitions.NowDo()
+	doSnapshot.Name = chain.Name
+	doSnapshot.Tag = UpgradeSnapshot
+	doSnapshot.Force = true
+	if err := SnapshotChain(doSnapshot); err != nil {
+		return restartAfterFailedUpgrade(chain.Name, container.HostConfig.PublishAllPorts, fmt.Errorf("Cannot snapshot chain %s: %v", chain.Name, err))
+	}
+
+	definition, err := loaders.ReadChainDefinition(chain.Name)
+	if err != nil {
+		return restartAfterFailedUpgrade(chain.Name, container.HostConfig.PublishAllPorts, err)
+	}
+	definition.Service.Image = image
+	definition.PreviousImage = chain.Service.Image
+	definition.PreviousImageDigest = previousDigest
+	if err := WriteChainDefinitionFile(definition, file); err != nil {
+		return rollbackUpgrade(chain.Name, file, original, container.HostConfig.PublishAllPorts, err)
+	}
+
+	log.WithFields(log.Fields{
+		"=>":    chain.Name,
+		"image": image,
+	}).Warn("Starting the chain with the new image")
+	if err := startUpgradedChain(chain.Name, container.HostConfig.PublishAllPorts); err != nil {
+		return rollbackUpgrade(chain.Name, file, original, container.HostConfig.PublishAllPorts, err)
+	}
+
+	wait := time.Duration(do.Wait) * time.Second
+	if wait == 0 {
+		wait = 60 * time.Second
+	}
+	log.WithField("=>", chain.Name).Warn("Waiting for the chain to make progress")
+	if err := waitForProgress(func() (int, error) {
+		return nodeHeight(chain.Name, 1)
+	}, wait, 2*time.Second); err != nil {
+		return rollbackUpgrade(chain.Name, file, original, container.HostConfig.PublishAllPorts, err)
+	}
+
+	log.WithFields(log.Fields{
+		"=>":       chain.Name,
+		"image":    image,
+		"previous": chain.Service.Image,
+	}).Warn("Chain upgraded")
+	return nil
+}
+
+// removeChainContainers stops the chain and removes the containers of
+// its nodes, leaving the data containers in place.
+func removeChainContainers(name string, timeout uint) error {
+	doKill := definitions.NowDo()
+	doKill.Name = name
+	doKill.Timeout = timeout
+	doKill.Rm = true
+	return KillChain(doKill)
+}
+
+// startUpgradedChain starts the chain from its definition file.
+func startUpgradedChain(name string, publishAllPorts bool) error {
+	doStart := definitions.NowDo()
+	doStart.Name = name
+	doStart.Operations.PublishAllPorts = publishAllPorts
+	return StartChain(doStart)
+}
+
+// restartAfterFailedUpgrade starts the chain again after the upgrade
+// failed before the definition file was changed.
+func restartAfterFailedUpgrade(name string, publishAllPorts bool, cause error) error {
+	if err := startUpgradedChain(name, publishAllPorts); err != nil {
+		return fmt.Errorf("%v. Starting the chain again failed as well: %v", cause, err)
+	}
+	return fmt.Errorf("%v. The chain was started again unchanged", cause)
+}
+
+// rollbackUpgrade restores the definition file and the pre-upgrade
+// snapshot of the chain and starts it again with the previous image.
+func rollbackUpgrade(name, file string, original []byte, publishAllPorts bool, cause error) error {
+	log.WithField("=>", name).Warnf("Upgrade failed, rolling back: %v", cause)
+
+	rollback := func() error {
+		if err := removeChainContainers(name, 0); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(file, original, 0644); err != nil {
+			return err
+		}
+		doReset := definitions.NowDo()
+		doReset.Name = name
+		doReset.Tag = UpgradeSnapshot
+		if err := ResetChain(doReset); err != nil {
+			return err
+		}
+		return startUpgradedChain(name, publishAllPorts)
+	}
+	if err := rollback(); err != nil {
+		return fmt.Errorf("Upgrade of chain %s failed: %v. Rolling back failed as well: %v. Restore it with [eris chains reset %s %s]", name, cause, err, name, UpgradeSnapshot)
+	}
+	return fmt.Errorf("Upgrade of chain %s failed: %v. The chain was rolled back to the previous image and the %s snapshot", name, cause, UpgradeSnapshot)
+}
+
+// nodeHeight returns the latest block height of the number-th node of
+// the chain or errNodeStopped if its container has exited.
+func nodeHeight(name string, number int) (int, error) {
+	c := util.FindNumberedContainer(definitions.TypeChain, name, number)
+	if c == nil {
+		return 0, errNodeStopped
+	}
+	container, err := util.DockerClient.InspectContainer(c.ContainerID)
+	if err != nil {
+		return 0, err
+	}
+	if !container.State.Running {
+		return 0, errNodeStopped
+	}
+
+	// Chains don't always publish their RPC port on the host; the
+	// container address is used then.
+	rpc, err := util.PublishedPort(c.ContainerID, rpcPort)
+	if err != nil {
+		if container.NetworkSettings == nil || container.NetworkSettings.IPAddress == "" {
+			return 0, err
+		}
+		rpc = net.JoinHostPort(container.NetworkSettings.IPAddress, rpcPort)
+	}
+
+	status, err := QueryNodeStatus(rpc)
+	if err != nil {
+		return 0, err
+	}
+	return status.Height, nil
+}
+
+// waitForProgress polls the block height every interval until it is
+// higher than the first height read. It fails once the timeout passes
+// or immediately if height returns errNodeStopped.
+func waitForProgress(height func() (int, error), timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	start := -1
+	var last error
+	for {
+		h, err := height()
+		switch {
+		case err == errNodeStopped:
+			return err
+		case err != nil:
+			last = err
+		case start < 0:
+			start = h
+		case h > start:
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(interval)
+	}
+
+	if start < 0 {
+		return fmt.Errorf("The chain did not answer within %v: %v", timeout, last)
+	}
+	return fmt.Errorf("The block height stayed at %d for %v", start, timeout)
+}
@@ -3,6 +3,7 @@ package chains
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -33,67 +34,78 @@ func WriteChainDefinitionFile(chainDef *def.Chain, fileName string) error {
 		return err
 	}
 
-	switch filepath.Ext(fileName) {
-	case ".json":
+	return EncodeChainDefinition(writer, chainDef, srv.FormatFromExt(fileName))
+}
+
+// EncodeChainDefinition writes the chain definition to writer in
+// the given format: "json", "yaml", or "toml" (default, if empty).
+func EncodeChainDefinition(writer io.Writer, chainDef *def.Chain, format string) error {
+	switch format {
+	case "json":
 		mar, err := json.MarshalIndent(chainDef, "", "  ")
 		if err != nil {
 			return err
 		}
 		mar = append(mar, '\n')
 		writer.Write(mar)
-	case ".yaml":
+	case "yaml", "yml":
 		mar, err := yaml.Marshal(chainDef)
 		if err != nil {
 			return err
 		}
 		mar = append(mar, '\n')
 		writer.Write(mar)
-	default:
+	case "toml", "":
 		writer.Write([]byte("# This is a TOML config file.\n# For more information, see https://github.com/toml-lang/toml\n\n"))
 		enc := toml.NewEncoder(writer)
 		enc.Indent = ""
 		writer.Write([]byte("name = \"" + chainDef.Name + "\"\n"))
 		writer.Write([]byte("chain_id = \"" + chainDef.ChainID + "\"\n"))
+		if chainDef.ChainType != "" {
+			writer.Write([]byte("chain_type = \"" + chainDef.ChainType + "\"\n"))
+		}
+		if chainDef.PreviousImage != "" {
+			writer.Write([]byte("previous_image = \"" + chainDef.PreviousImage + "\"\n"))
+		}
+		if chainDef.PreviousImageDigest != "" {
+			writer.Write([]byte("previous_image_digest = \"" + chainDef.PreviousImageDigest + "\"\n"))
+		}
 		writer.Write([]byte("\n[service]\n"))
 		enc.Encode(chainDef.Service)
+		if chainDef.Dependencies != nil && (len(chainDef.Dependencies.Services) != 0 || len(chainDef.Dependencies.Chains) != 0) {
+			writer.Write([]byte("\n[dependencies]\n"))
+			enc.Encode(chainDef.Dependencies)
+		}
 		writer.Write([]byte("\n[maintainer]\n"))
 		enc.Encode(chainDef.Maintainer)
+		if chainDef.Machine != nil && len(chainDef.Machine.Requires) != 0 {
+			writer.Write([]byte("\n[machine]\n"))
+			enc.Encode(chainDef.Machine)
+		}
+		srv.WriteHooksTOML(writer, chainDef.Hooks)
+	default:
+		return fmt.Errorf("Unknown format %q. Use toml, json, or yaml", format)
 	}
 	return nil
 }
 
+// MakeGenesisFile writes a genesis file with a single validator to
+// ChainsPath/NAME/genesis.json. It doesn't need Docker.
+//
+//  do.Chain.Name - name of the chain (required)
+//  do.Pubkey     - public key of the validator (required)
+//
 func MakeGenesisFile(do *def.Do) error {
-
-	//otherwise it'll start its own keys server that won't have the key needed...
-	do.Name = "keys"
-	IfExit(srv.EnsureRunning(do))
-
-	doThr := def.NowDo()
-	doThr.Chain.ChainType = "throwaway" //for teardown
-	doThr.Name = "default"
-	doThr.Chain.Name = "default" //for teardown
-	doThr.Operations.PublishAllPorts = true
-
-	log.WithField("=>", doThr.Name).Info("Making genesis.json file. Starting chain")
-	if er := NewChain(doThr); er != nil {
-		return fmt.Errorf("error starting chain %v\n", er)
+	validator, err := knownAccount([]string{do.Pubkey}, true)
+	if err != nil {
+		return err
 	}
 
-	doThr.Operations.Args = []string{"mintgen", "known", do.Chain.Name, fmt.Sprintf("--pub=%s", do.Pubkey)}
-
-	// pipe this output to /chains/chainName/genesis.json
-	_, err := ExecChain(doThr)
-	if err != nil {
-		log.Warnf("Executing chain error: %v", err)
-		log.Warn("Cleaning up")
-		doThr.Rm = true
-		doThr.RmD = true
-		if err := CleanUp(doThr); err != nil {
-			return err
-		}
+	dir := filepath.Join(ChainsPath, do.Chain.Name)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
 	}
 
-	doThr.Rm = true
-	doThr.RmD = true
-	return CleanUp(doThr) // doesn't clean up keys but that's ~ ok b/c it's about to be used...
+	log.WithField("=>", do.Chain.Name).Info("Making genesis.json file")
+	return writeJSON(filepath.Join(dir, "genesis.json"), makeGenesis(do.Chain.Name, []*chainAccount{validator}, nil), 0600)
 }
@@ -47,6 +47,7 @@ func buildActionsCommand() {
 	Actions.AddCommand(actionsList)
 	Actions.AddCommand(actionsEdit)
 	Actions.AddCommand(actionsDo)
+	Actions.AddCommand(actionsGraph)
 	Actions.AddCommand(actionsExport)
 	Actions.AddCommand(actionsRename)
 	Actions.AddCommand(actionsRemove)
@@ -110,6 +111,16 @@ $ eris actions do dns register cutemarm 111.111.111.111 -- will populate $1 and
 	Run: DoAction,
 }
 
+var actionsGraph = &cobra.Command{
+	Use:   "graph NAME",
+	Short: "Display the dependency graph of an action.",
+	Long: `Display the services and chains [eris actions do] would bring up
+for an action, marked as running, stopped, or missing (no definition file).`,
+	Example: `$ eris actions graph dns register -- display the dns_register dependency tree
+$ eris actions graph dns register --format dot -- display it in the Graphviz DOT format`,
+	Run: GraphAction,
+}
+
 var actionsEdit = &cobra.Command{
 	Use:   "edit NAME",
 	Short: "Edit an action definition file.",
@@ -148,6 +159,10 @@ func addActionsFlags() {
 	buildFlag(actionsDo, do, "chain", "action")
 	buildFlag(actionsDo, do, "services", "action")
 
+	buildFlag(actionsGraph, do, "chain", "action")
+	buildFlag(actionsGraph, do, "services", "action")
+	actionsGraph.Flags().StringVarP(&do.GraphFormat, "format", "", "text", "graph output format (text or dot)")
+
 	buildFlag(actionsRemove, do, "file", "action")
 
 	actionsList.Flags().BoolVarP(&do.Quiet, "quiet", "", false, "machine readable output; also used in tests")
@@ -190,6 +205,12 @@ func DoAction(cmd *cobra.Command, args []string) {
 	IfExit(act.Do(do))
 }
 
+func GraphAction(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Operations.Args = args
+	IfExit(act.GraphAction(do))
+}
+
 func ExportAction(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 	do.Name = strings.Join(args, "_")
@@ -46,7 +46,9 @@ func buildChainsCommand() {
 	Chains.AddCommand(chainsListAll)
 	Chains.AddCommand(chainsCheckout)
 	Chains.AddCommand(chainsHead)
+	Chains.AddCommand(chainsHistory)
 	Chains.AddCommand(chainsPorts)
+	Chains.AddCommand(chainsStatus)
 	Chains.AddCommand(chainsEdit)
 	Chains.AddCommand(chainsStart)
 	Chains.AddCommand(chainsLogs)
@@ -54,10 +56,23 @@ func buildChainsCommand() {
 	Chains.AddCommand(chainsStop)
 	Chains.AddCommand(chainsExec)
 	Chains.AddCommand(chainsCat)
+	Chains.AddCommand(chainsConfig)
 	Chains.AddCommand(chainsExport)
 	Chains.AddCommand(chainsRename)
+	Chains.AddCommand(chainsClone)
 	Chains.AddCommand(chainsUpdate)
+	Chains.AddCommand(chainsUpgrade)
+	Chains.AddCommand(chainsRestart)
+	Chains.AddCommand(chainsGraph)
+	Chains.AddCommand(chainsBackup)
+	Chains.AddCommand(chainsRestore)
+	Chains.AddCommand(chainsBundle)
+	Chains.AddCommand(chainsUnbundle)
+	Chains.AddCommand(chainsSnapshot)
+	Chains.AddCommand(chainsReset)
+	Chains.AddCommand(chainsDiff)
 	Chains.AddCommand(chainsRemove)
+	Chains.AddCommand(chainsGC)
 	Chains.AddCommand(chainsGraduate)
 	// Chains.AddCommand(chainsMakeGenesis)
 	addChainsFlags()
@@ -93,9 +108,15 @@ The make process will *not* start a chain for you. You will want to use
 for that which will import all of the files which make creates into containers and
 start your shiny new chain.
 
-If you have any questions on eris chains make, please see the eris-cm (chain manager)
-documentation here:
-https://docs.erisindustries.com/documentation/eris-cm/latest/eris-cm/
+Account types (e.g. Full, Validator, Root, Developer, Participant) and chain
+types (e.g. simplechain) are built in and can be added to or overridden with
+toml files in ~/.eris/chains/account-types and ~/.eris/chains/chain-types.
+Accounts of account types with a default bond are the chain's validators.
+
+Make runs natively and doesn't need Docker or the keys service. The keys it
+makes are written to ~/.eris/chains/NAME/accounts.json along with the
+accounts.csv and validators.csv files, which can be given back to make
+with the --known flag.
 `,
 	Example: `$ eris chains make myChain -- will use the chain-making wizard and make your chain named myChain (interactive)
 $ eris chains make myChain --chain-type=simplechain --  will use the chain type definition files to make your chain named myChain (non-interactive)
@@ -131,7 +152,18 @@ unless the --options or --dir flag is passed.
 Will use a default eris:db server config from ~/.eris/chains/default/server_conf.toml
 unless the --serverconf or --dir flag is passed.
 
-If you would like to create a genesis.json then please utilize [eris chains make]`,
+If you would like to create a genesis.json then please utilize [eris chains make]
+
+With the --nodes flag, a local network of N validator nodes sharing a
+genesis.json is created and started. The --dir directory should then hold
+a directory for each validator, as [eris chains make] makes them; without
+--dir, the validator keys and the genesis.json are made for you. Each node
+runs in a container of its own, with a data container of its own, and
+connects to the nodes started before it. The network is listed, stopped,
+removed, and displays logs as a single chain.`,
+	Example: `$ eris chains new simplechain -- create a chain with the default genesis.json
+$ eris chains new testnet --nodes 4 -- create a network of 4 validators
+$ eris chains new testnet --nodes 4 --dir testnet -- use the validators made by [eris chains make testnet]`,
 	Run: NewChain,
 }
 
@@ -203,10 +235,30 @@ passing in a --chain flag. If a --chain is passed to any command accepting
 --chain, the --chain which is passed will overwrite any checked out chain.
 
 If command is given without arguments it will clear the head and there will
-be no chain checked out.`,
+be no chain checked out. [eris chains checkout -] checks out the chain which
+was checked out before the current one.
+
+With --local the chain is checked out for the current directory (and its
+subdirectories) only, in a .eris-chain file which takes precedence over the
+globally checked out chain. This way [eris pkgs do] and $chain resolve to
+different chains in different project directories. [eris chains checkout --local]
+without arguments removes the .eris-chain file.`,
+	Example: `$ eris chains checkout simplechain -- check out simplechain
+$ eris chains checkout - -- go back to the previously checked out chain
+$ eris chains checkout --local testchain -- check out testchain in this directory`,
 	Run: CheckoutChain,
 }
 
+var chainsHistory = &cobra.Command{
+	Use:   "history",
+	Short: "Show recently checked out chains.",
+	Long: `Show recently checked out chains, most recent first.
+
+Chains checked out in directories with [eris chains checkout --local]
+are not recorded.`,
+	Run: ChainHistory,
+}
+
 var chainsPorts = &cobra.Command{
 	Use:   "ports NAME [PORT]...",
 	Short: "Print port mappings.",
@@ -227,6 +279,21 @@ $ eris chains ports myChain -- will display all mappings`,
 	Run: PortsChain,
 }
 
+var chainsStatus = &cobra.Command{
+	Use:   "status NAME",
+	Short: "Display the block height, peers, and validators of a chain.",
+	Long: `Display the block height, peers, and validators of a chain.
+
+The status is queried over the eris:db RPC port (46657) the chain
+publishes on the host: the chain ID, the latest block height and time,
+the number of peers, and the validator set. For chain networks every
+node is queried. Use --watch to keep polling the chain.`,
+	Example: `$ eris chains status simplechain -- display the status once
+$ eris chains status simplechain --json -- display the status in JSON
+$ eris chains status simplechain --watch --interval 5 -- poll every 5 seconds until interrupted`,
+	Run: StatusChain,
+}
+
 var chainsHead = &cobra.Command{
 	Use:   "current",
 	Short: "The currently checked out chain.",
@@ -316,6 +383,27 @@ var chainsRename = &cobra.Command{
 	Run:   RenameChain,
 }
 
+var chainsClone = &cobra.Command{
+	Use:   "clone SRC DST",
+	Short: "Copy a chain into a new independent chain.",
+	Long: `Copy a chain into a new independent chain.
+
+The chain definition file and the data container (of every node, for
+chain networks) are copied to the new chain, which can then be started
+with [eris chains start DST]. A running chain is stopped for the copy
+and started again afterwards.
+
+Without flags the clone is an exact copy of the chain as of now, with
+the same chain ID and validator keys: don't connect it to the network
+of the original chain. With --chain-id or --new-keys the genesis and
+priv_validator.json files of the clone are rewritten and the clone
+starts over from the rewritten genesis state, because the blocks of the
+original chain are signed for its chain ID and validators.`,
+	Example: `$ eris chains clone staging debug -- copy the staging chain as of now
+$ eris chains clone staging debug --chain-id debug --new-keys -- a new chain with the genesis state of staging`,
+	Run: CloneChain,
+}
+
 var chainsRemove = &cobra.Command{
 	Use:   "rm NAME",
 	Short: "Remove an installed chain.",
@@ -326,6 +414,23 @@ remove the chain definition file.`,
 	Run: RmChain,
 }
 
+var chainsGC = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove what is left of throwaway chains.",
+	Long: `Remove what is left of throwaway chains.
+
+Throwaway chains are made by [eris pkgs do] when no chain is given and
+removed when the package run ends. Runs which crashed or were interrupted
+leave chain and data containers, chain definition files, and data
+directories behind. This command finds them by the throwaway label of
+the containers and the throwaway chain_type of the definition files,
+and removes those of chains made longer than --older-than ago. Chains
+whose containers are still running are left alone unless --force is given.`,
+	Example: `$ eris chains gc --dry-run -- list the leftovers
+$ eris chains gc --older-than 10m -- remove throwaway chains made more than 10 minutes ago`,
+	Run: GarbageCollectChains,
+}
+
 var chainsUpdate = &cobra.Command{
 	Use:   "update NAME",
 	Short: "Update an installed chain.",
@@ -345,6 +450,195 @@ by the update command.
 	Run: UpdateChain,
 }
 
+var chainsUpgrade = &cobra.Command{
+	Use:   "upgrade NAME --image IMAGE",
+	Short: "Switch a running chain to another image.",
+	Long: `Switch a running chain to another image, rolling back on failure.
+
+Functionally this command will perform the following sequence:
+
+1. Pull the new image (if it is not found locally).
+2. Stop the chain and remove its containers.
+3. Snapshot the chain data with the pre-upgrade tag.
+4. Start the chain with the new image.
+5. Wait for the block height of the chain to increase.
+
+If the chain doesn't make progress within --wait seconds (or its
+container exits), the chain is reset to the pre-upgrade snapshot
+and started again with the previous image. On success, the previous
+image and its digest are recorded in the chain definition file.
+
+Use --rollback to switch the chain back to the recorded previous image
+the same way.`,
+	Example: `$ eris chains upgrade simplechain --image quay.io/eris/erisdb:0.11.4
+$ eris chains upgrade simplechain --rollback`,
+	Run: UpgradeChain,
+}
+
+var chainsRestart = &cobra.Command{
+	Use:   "restart NAME",
+	Short: "Restart a running chain.",
+	Long: `Restart a running chain without losing the links of its dependents.
+
+The chain is restarted and has to become ready before the command returns.
+With the --all flag the services the chain depends upon are restarted
+first, one at a time, in the order of their dependency graph.
+
+With the --recreate flag (implied by --pull, --env, and --links) the
+chain container is recreated instead. Running services linking to the
+chain are then restarted as well, so that their links point to the
+new container.`,
+	Example: `$ eris chains restart simplechain -- restart the chain
+$ eris chains restart simplechain --pull -- recreate the chain from an updated image`,
+	Run: RestartChain,
+}
+
+var chainsGraph = &cobra.Command{
+	Use:   "graph [NAME...]",
+	Short: "Display the dependency graph of chains.",
+	Long: `Display the dependency graph of chains.
+
+The graph shows the chains and services the chains depend upon, resolved
+from the dependencies field of the chain definition files. Nodes are marked
+as running, stopped, or missing (no definition file); edges show whether
+a dependency is linked to, has its volumes mounted, or both.
+
+If no chains are given, the graph of all known chains is displayed.`,
+	Example: `$ eris chains graph simplechain -- display the simplechain dependency tree
+$ eris chains graph --format dot | dot -Tpng > chains.png -- draw all chains with Graphviz`,
+	Run: GraphChain,
+}
+
+var chainsBackup = &cobra.Command{
+	Use:   "backup NAME [ARCHIVE]",
+	Short: "Back up the data of a chain.",
+	Long: `Back up the data container of a chain to a compressed archive.
+
+Along with the data container contents, the archive holds the chain
+definition file, the image digest, the chain ID, and a manifest with
+the checksums of all files. If no ARCHIVE is given, the archive is
+written to the current directory as NAME_DATE.tar.gz.
+
+To restore the chain use [eris chains restore ARCHIVE].`,
+	Example: `$ eris chains backup simplechain
+$ eris chains backup simplechain /backups/simplechain.tar.gz`,
+	Run: BackupChain,
+}
+
+var chainsRestore = &cobra.Command{
+	Use:   "restore ARCHIVE [NAME]",
+	Short: "Restore a chain from a backup.",
+	Long: `Restore a chain from an archive made with [eris chains backup].
+
+The checksums of the archive are verified before the chain definition
+file and the data container are recreated. If NAME is given, the chain
+is restored under that name (its chain ID does not change). An existing
+chain is only replaced with the --force flag.`,
+	Example: `$ eris chains restore simplechain_2016-03-01_12-00-00.tar.gz
+$ eris chains restore simplechain_2016-03-01_12-00-00.tar.gz drillchain`,
+	Run: RestoreChain,
+}
+
+var chainsBundle = &cobra.Command{
+	Use:   "bundle NAME",
+	Short: "Bundle what others need to join a chain.",
+	Long: `Bundle what others need to join a chain into a compressed archive.
+
+The bundle holds the chain definition file, the genesis.json,
+config.toml, server_conf.toml, and priv_validator.json files of the
+chain, and a manifest with the chain ID, the seeds, and the checksums
+of all files. Leave out the validator key with --no-priv unless the
+bundle is meant for whoever runs this validator. Settings of the
+bundled config.toml (e.g. the seeds to connect to) can be changed with
+--options. If no --output is given, the bundle is written to the
+current directory as NAME.tar.gz.
+
+To set the chain up from the bundle use [eris chains unbundle].`,
+	Example: `$ eris chains bundle simplechain -o simplechain.tar.gz --no-priv
+$ eris chains bundle simplechain --no-priv --options seeds=1.2.3.4:46656`,
+	Run: BundleChain,
+}
+
+var chainsUnbundle = &cobra.Command{
+	Use:   "unbundle BUNDLE [NAME]",
+	Short: "Set a chain up from a bundle.",
+	Long: `Set a chain up from a bundle made with [eris chains bundle].
+
+The checksums of the bundle are verified before the chain definition
+file and the data container with the chain files are created, ready
+for [eris chains start]. If NAME is given, the chain is set up under
+that name. Give your own validator key with --priv; without one (and
+without one in the bundle) the chain makes a new key at start and
+joins as a non-validating node. An existing chain is only replaced
+with the --force flag.`,
+	Example: `$ eris chains unbundle simplechain.tar.gz
+$ eris chains unbundle simplechain.tar.gz --priv ~/priv_validator.json`,
+	Run: UnbundleChain,
+}
+
+var chainsSnapshot = &cobra.Command{
+	Use:   "snapshot NAME TAG",
+	Short: "Snapshot the state of a chain.",
+	Long: `Snapshot the state of a chain under a tag.
+
+The chain is stopped and the chain directory of its data container
+(block store, state, config files, and priv_validator.json) is archived
+to ~/.eris/snapshots/NAME/TAG. A running chain is started again once the
+snapshot is taken. For networks made with [eris chains new --nodes] every
+node is snapshotted.
+
+To bring the chain back to the snapshotted state use
+[eris chains reset NAME TAG].`,
+	Example: `$ eris chains snapshot simplechain deployed -- will snapshot the chain under the deployed tag
+$ eris chains snapshot ls simplechain -- will list the snapshots of the chain
+$ eris chains snapshot rm simplechain deployed -- will remove the snapshot`,
+	Run: SnapshotChain,
+}
+
+var chainsSnapshotList = &cobra.Command{
+	Use:   "ls NAME",
+	Short: "List the snapshots of a chain.",
+	Long:  `List the snapshots of a chain, oldest first.`,
+	Run:   ListChainSnapshots,
+}
+
+var chainsSnapshotRm = &cobra.Command{
+	Use:   "rm NAME TAG",
+	Short: "Remove a snapshot of a chain.",
+	Long:  `Remove a snapshot of a chain.`,
+	Run:   RmChainSnapshot,
+}
+
+var chainsReset = &cobra.Command{
+	Use:   "reset NAME TAG",
+	Short: "Reset a chain to a snapshot.",
+	Long: `Reset the state of a chain to a snapshot made with [eris chains snapshot].
+
+The chain is stopped, the chain directory of its data container is
+replaced with the snapshotted one, and a running chain is started again.
+Resetting is much faster than making a new chain, so it suits test runs
+which need a known chain state (see [eris pkgs do --reset-to]).`,
+	Example: `$ eris chains reset simplechain deployed`,
+	Run:     ResetChain,
+}
+
+var chainsDiff = &cobra.Command{
+	Use:   "diff NAME",
+	Short: "Compare a chain container with its definition file.",
+	Long: `Compare a chain container with its definition file.
+
+Command will compare the image, environment, ports, volumes, links,
+and other settings of the chain container with the ones the chain
+definition file describes now, and display any differences.
+
+The command exits with a non-zero status if the container has drifted
+from its definition file, unless the --fix flag is given, in which case
+the container is recreated from the definition file.`,
+	Example: `$ eris chains diff simplechain -- display differences, exit with 1 on drift
+$ eris chains diff simplechain --fix -- recreate the container on drift`,
+	Run: DiffChain,
+}
+
 var chainsGraduate = &cobra.Command{
 	Use:   "graduate NAME",
 	Short: "Graduate a chain to a service.",
@@ -365,6 +659,28 @@ functions have more convenience functions for working "on" chains themselves.`,
 	Run: GraduateChain,
 }
 
+var chainsConfig = &cobra.Command{
+	Use:   "config NAME get KEY...|set KEY=VALUE...|edit [config|server_conf]",
+	Short: "Read and modify the config files of a chain.",
+	Long: `Read and modify the config.toml and server_conf.toml files of a chain.
+
+The files live in the chain's data container. Get displays values of keys,
+set changes them in place (keeping the comments and the layout of the
+files), and edit opens a file in your editor. Keys of tables are dotted
+(e.g. bind.port) and are looked up in config.toml first. Changes which
+don't leave the files valid TOML are refused.
+
+For networks made with [eris chains new --nodes], set changes the files of
+every node while get and edit use the files of the first one.
+
+The chain reads its config files when it starts, so use the --restart flag
+(or [eris chains restart]) for the changes to take effect.`,
+	Example: `$ eris chains config simplechain get moniker -- will display the moniker of the chain
+$ eris chains config simplechain set log_level=debug fast_sync=false --restart -- will set the keys and restart the chain
+$ eris chains config simplechain edit server_conf -- will open server_conf.toml in your editor`,
+	Run: ConfigChain,
+}
+
 var chainsCat = &cobra.Command{
 	Use:     "cat NAME [config|genesis]",
 	Short:   "Display chain information.",
@@ -372,7 +688,8 @@ var chainsCat = &cobra.Command{
 	Aliases: []string{"plop"},
 	Example: `$ eris chains cat simplechain -- will display the chain definition file
 $ eris chains cat simplechain config -- will display the config.toml file from inside the container
-$ eris chains cat simplechain genesis -- will display the genesis.json file from the container`,
+$ eris chains cat simplechain genesis -- will display the genesis.json file from the container
+$ eris chains cat simplechain --format json -- will display the chain definition in JSON`,
 	Run: CatChain,
 }
 
@@ -396,7 +713,7 @@ func addChainsFlags() {
 	chainsMake.PersistentFlags().StringVarP(&do.ChainType, "chain-type", "", "", "which chain type definition should we use? find these in ~/.eris/chains/chain_types")
 	chainsMake.PersistentFlags().BoolVarP(&do.Tarball, "tar", "", false, "instead of making directories in ~/.eris/chains, make tarballs; incompatible with and overrides zip")
 	chainsMake.PersistentFlags().BoolVarP(&do.ZipFile, "zip", "", false, "instead of making directories in ~/.eris/chains, make zip files")
-	chainsMake.PersistentFlags().BoolVarP(&do.Output, "output", "", true, "should eris-cm provide an output of its job")
+	chainsMake.PersistentFlags().BoolVarP(&do.Output, "output", "", true, "display the accounts made")
 	chainsMake.PersistentFlags().BoolVarP(&do.Known, "known", "", false, "use csv for a set of known keys to assemble genesis.json (requires both --accounts and --validators flags")
 	chainsMake.PersistentFlags().StringVarP(&do.ChainMakeActs, "accounts", "", "", "comma separated list of the accounts.csv files you would like to utilize (requires --known flag)")
 	chainsMake.PersistentFlags().StringVarP(&do.ChainMakeVals, "validators", "", "", "comma separated list of the validators.csv files you would like to utilize (requires --known flag)")
@@ -407,10 +724,10 @@ func addChainsFlags() {
 	// buildFlag(chainsNew, do, "csv", "chain")
 	// buildFlag(chainsNew, do, "serverconf", "chain")
 	// chainsNew.PersistentFlags().StringVarP(&do.GenesisFile, "genesis", "g", "", "genesis.json file")
-	// chainsNew.PersistentFlags().StringSliceVarP(&do.ConfigOpts, "options", "", nil, "space separated <key>=<value> pairs to set in config.toml")
 	// chainsNew.PersistentFlags().StringVarP(&do.Priv, "priv", "", "", "pass in a priv_validator.json file (dev-only!)")
-	// chainsNew.PersistentFlags().UintVarP(&do.N, "N", "", 1, "make a new genesis.json with this many validators and create data containers for each")
 	buildFlag(chainsNew, do, "dir", "chain")
+	chainsNew.PersistentFlags().StringSliceVarP(&do.ConfigOpts, "options", "", nil, "comma separated <key>=<value> pairs to set in config.toml")
+	chainsNew.PersistentFlags().UintVarP(&do.N, "nodes", "", 1, "create a network of this many validator nodes, each with a data container of its own")
 	buildFlag(chainsNew, do, "env", "chain")
 	buildFlag(chainsNew, do, "publish", "chain")
 	buildFlag(chainsNew, do, "links", "chain")
@@ -451,21 +768,74 @@ func addChainsFlags() {
 	buildFlag(chainsRemove, do, "data", "chain")
 	buildFlag(chainsRemove, do, "rm-volumes", "chain")
 
+	chainsGC.Flags().StringVarP(&do.OlderThan, "older-than", "", "1h", "only remove throwaway chains made longer ago than this duration")
+	chainsGC.Flags().BoolVarP(&do.DryRun, "dry-run", "", false, "list the leftovers without removing them")
+	chainsGC.Flags().BoolVarP(&do.Force, "force", "f", false, "also remove throwaway chains which are still running")
+
 	buildFlag(chainsUpdate, do, "pull", "chain")
 	buildFlag(chainsUpdate, do, "timeout", "chain")
 	buildFlag(chainsUpdate, do, "env", "chain")
 	buildFlag(chainsUpdate, do, "links", "chain")
 
+	chainsUpgrade.Flags().StringVarP(&do.Image, "image", "", "", "image to upgrade the chain to")
+	chainsUpgrade.Flags().UintVarP(&do.Wait, "wait", "", 60, "number of seconds to wait for the chain to make progress")
+	chainsUpgrade.Flags().BoolVarP(&do.Rollback, "rollback", "", false, "switch the chain back to its previous image")
+	buildFlag(chainsUpgrade, do, "timeout", "chain")
+
+	buildFlag(chainsDiff, do, "api", "chain")
+	buildFlag(chainsDiff, do, "timeout", "chain")
+	chainsDiff.Flags().BoolVarP(&do.Fix, "fix", "", false, "recreate the container from the definition file if it has drifted")
+
+	chainsRestore.Flags().BoolVarP(&do.Force, "force", "f", false, "replace the existing chain definition file and data container")
+
+	chainsBundle.Flags().StringVarP(&do.BundleFile, "output", "o", "", "bundle to write (defaults to NAME.tar.gz)")
+	chainsBundle.Flags().BoolVarP(&do.NoPriv, "no-priv", "", false, "leave the priv_validator.json file out of the bundle")
+	chainsBundle.Flags().StringSliceVarP(&do.ConfigOpts, "options", "", nil, "comma separated <key>=<value> pairs to set in the bundled config.toml")
+	chainsUnbundle.Flags().StringVarP(&do.Priv, "priv", "", "", "priv_validator.json file to use instead of the bundled one")
+	chainsUnbundle.Flags().BoolVarP(&do.Force, "force", "f", false, "replace the existing chain definition file and data container")
+
+	chainsSnapshot.AddCommand(chainsSnapshotList)
+	chainsSnapshot.AddCommand(chainsSnapshotRm)
+	chainsSnapshot.Flags().BoolVarP(&do.Force, "force", "f", false, "replace an existing snapshot with the same tag")
+	buildFlag(chainsSnapshot, do, "timeout", "chain")
+	buildFlag(chainsReset, do, "timeout", "chain")
+
+	chainsClone.Flags().StringVarP(&do.ChainID, "chain-id", "", "", "chain ID of the new chain (defaults to the chain ID of SRC)")
+	chainsClone.Flags().BoolVarP(&do.NewKeys, "new-keys", "", false, "give the validators of the new chain new keys")
+	buildFlag(chainsClone, do, "timeout", "chain")
+
+	chainsGraph.Flags().StringVarP(&do.GraphFormat, "format", "", "text", "graph output format (text or dot)")
+
+	chainsConfig.Flags().BoolVarP(&do.Restart, "restart", "", false, "restart the chain after changing its config files")
+	buildFlag(chainsConfig, do, "timeout", "chain")
+
+	buildFlag(chainsRestart, do, "api", "chain")
+	buildFlag(chainsRestart, do, "pull", "chain")
+	buildFlag(chainsRestart, do, "timeout", "chain")
+	buildFlag(chainsRestart, do, "env", "chain")
+	buildFlag(chainsRestart, do, "links", "chain")
+	chainsRestart.Flags().BoolVarP(&do.Recreate, "recreate", "", false, "recreate the chain container instead of restarting it")
+	chainsRestart.Flags().BoolVarP(&do.All, "all", "", false, "restart the services the chain depends upon as well")
+
 	buildFlag(chainsStop, do, "rm", "chain")
 	buildFlag(chainsStop, do, "data", "chain")
 	buildFlag(chainsStop, do, "force", "chain")
 	buildFlag(chainsStop, do, "timeout", "chain")
 	buildFlag(chainsStop, do, "volumes", "chain")
 
+	chainsCheckout.Flags().BoolVarP(&do.Local, "local", "", false, "check out the chain for the current directory only")
+	chainsHistory.Flags().IntVarP(&do.Lines, "number", "n", 0, "number of checkouts to show (all by default)")
+
 	buildFlag(chainsListAll, do, "known", "chain")
 	buildFlag(chainsListAll, do, "existing", "chain")
 	buildFlag(chainsListAll, do, "running", "chain")
 	buildFlag(chainsListAll, do, "quiet", "chain")
+
+	chainsStatus.Flags().BoolVarP(&do.JSON, "json", "", false, "display the status in JSON")
+	chainsStatus.Flags().BoolVarP(&do.Watch, "watch", "w", false, "keep polling the chain until interrupted")
+	chainsStatus.Flags().UintVarP(&do.Interval, "interval", "", 2, "number of seconds between polls with --watch")
+
+	chainsCat.Flags().StringVarP(&do.Format, "format", "", "", "display the loaded chain definition in this format (toml, json, or yaml)")
 }
 
 //----------------------------------------------------------------------
@@ -591,6 +961,11 @@ func CurrentChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.CurrentChain(do))
 }
 
+func ChainHistory(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(0, "eq", cmd, args))
+	IfExit(chns.ChainHistory(do))
+}
+
 func CatChain(cmd *cobra.Command, args []string) {
 	// [csk]: if no args should we just start the checkedout chain?
 	IfExit(ArgCheck(1, "ge", cmd, args))
@@ -602,6 +977,29 @@ func CatChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.CatChain(do))
 }
 
+func ConfigChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "ge", cmd, args))
+	do.Name = args[0]
+	switch args[1] {
+	case "get":
+		IfExit(ArgCheck(3, "ge", cmd, args))
+		do.Operations.Args = args[2:]
+		IfExit(chns.GetChainConfig(do))
+	case "set":
+		IfExit(ArgCheck(3, "ge", cmd, args))
+		do.ConfigOpts = args[2:]
+		IfExit(chns.SetChainConfig(do))
+	case "edit":
+		if len(args) > 2 {
+			do.Type = args[2]
+		}
+		IfExit(chns.EditChainConfig(do))
+	default:
+		cmd.Help()
+		IfExit(fmt.Errorf("\nUnknown config subcommand %q. Use get, set, or edit.", args[1]))
+	}
+}
+
 func PortsChain(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 	do.Name = args[0]
@@ -609,6 +1007,12 @@ func PortsChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.PortsChain(do))
 }
 
+func StatusChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.StatusChain(do))
+}
+
 // edit a chain definition file
 func EditChain(cmd *cobra.Command, args []string) {
 	// [csk]: if no args should we just start the checkedout chain?
@@ -675,6 +1079,13 @@ func RenameChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.RenameChain(do))
 }
 
+func CloneChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "eq", cmd, args))
+	do.Name = args[0]
+	do.NewName = args[1]
+	IfExit(chns.CloneChain(do))
+}
+
 func UpdateChain(cmd *cobra.Command, args []string) {
 	// [csk]: if no args should we just start the checkedout chain?
 	IfExit(ArgCheck(1, "ge", cmd, args))
@@ -682,6 +1093,93 @@ func UpdateChain(cmd *cobra.Command, args []string) {
 	IfExit(chns.UpdateChain(do))
 }
 
+func GarbageCollectChains(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(0, "eq", cmd, args))
+	IfExit(chns.GarbageCollectChains(do))
+}
+
+func UpgradeChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.UpgradeChain(do))
+}
+
+func DiffChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.DiffChain(do))
+}
+
+func BackupChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Name = args[0]
+	// keys export binds --dest to do.Destination with a default.
+	do.Destination = ""
+	if len(args) > 1 {
+		do.Destination = args[1]
+	}
+	IfExit(chns.BackupChain(do))
+}
+
+func SnapshotChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "eq", cmd, args))
+	do.Name, do.Tag = args[0], args[1]
+	IfExit(chns.SnapshotChain(do))
+}
+
+func ListChainSnapshots(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.ListChainSnapshots(do))
+}
+
+func RmChainSnapshot(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "eq", cmd, args))
+	do.Name, do.Tag = args[0], args[1]
+	IfExit(chns.RmSnapshot(do))
+}
+
+func ResetChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(2, "eq", cmd, args))
+	do.Name, do.Tag = args[0], args[1]
+	IfExit(chns.ResetChain(do))
+}
+
+func RestoreChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Source = args[0]
+	if len(args) > 1 {
+		do.NewName = args[1]
+	}
+	IfExit(chns.RestoreChain(do))
+}
+
+func BundleChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.BundleChain(do))
+}
+
+func UnbundleChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Source = args[0]
+	if len(args) > 1 {
+		do.NewName = args[1]
+	}
+	IfExit(chns.UnbundleChain(do))
+}
+
+func GraphChain(cmd *cobra.Command, args []string) {
+	do.Operations.Args = args
+	IfExit(chns.GraphChain(do))
+}
+
+func RestartChain(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(chns.RestartChain(do))
+}
+
 func RmChain(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 	do.Name = args[0]
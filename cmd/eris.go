@@ -6,8 +6,10 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/eris-ltd/eris-cli/actions"
 	"github.com/eris-ltd/eris-cli/config"
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/hooks"
 	"github.com/eris-ltd/eris-cli/util"
 	"github.com/eris-ltd/eris-cli/version"
 
@@ -49,6 +51,7 @@ Complete documentation is available at https://docs.erisindustries.com
 			log.SetFormatter(logger.ConsoleFormatter(log.DebugLevel))
 		}
 
+		util.AllowDrift = do.AllowDrift
 		util.DockerConnect(do.Verbose, do.MachineName)
 
 		log.AddHook(CrashReportHook())
@@ -82,6 +85,7 @@ func Execute() {
 
 	InitializeConfig()
 	AddGlobalFlags()
+	hooks.PerformAction = actions.PerformHookAction
 	AddCommands()
 	ErisCmd.Execute()
 }
@@ -96,6 +100,8 @@ func AddCommands() {
 	ErisCmd.AddCommand(Packages)
 	buildKeysCommand()
 	ErisCmd.AddCommand(Keys)
+	buildSecretsCommand()
+	ErisCmd.AddCommand(Secrets)
 	buildActionsCommand()
 	ErisCmd.AddCommand(Actions)
 
@@ -110,6 +116,10 @@ func AddCommands() {
 	buildDataCommand()
 	ErisCmd.AddCommand(Data)
 	ErisCmd.AddCommand(ListEverything)
+	buildStatusCommand()
+	ErisCmd.AddCommand(Status)
+	buildJobsCommand()
+	ErisCmd.AddCommand(Jobs)
 
 	// TODO
 	// buildAgentsCommand()
@@ -142,6 +152,7 @@ func AddGlobalFlags() {
 	ErisCmd.PersistentFlags().BoolVarP(&do.Verbose, "verbose", "v", false, "verbose output")
 	ErisCmd.PersistentFlags().BoolVarP(&do.Debug, "debug", "d", false, "debug level output")
 	ErisCmd.PersistentFlags().StringVarP(&do.MachineName, "machine", "m", "eris", "machine name for docker-machine that is running VM")
+	ErisCmd.PersistentFlags().BoolVarP(&do.AllowDrift, "allow-drift", "", false, "run images which do not match the images lock file")
 }
 
 func InitializeConfig() {
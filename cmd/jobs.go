@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"github.com/eris-ltd/eris-cli/jobs"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
+)
+
+//----------------------------------------------------------------------
+// cli definition
+
+var Jobs = &cobra.Command{
+	Use:   "jobs",
+	Short: "List, schedule, and review one-shot job services.",
+	Long: `List, schedule, and review one-shot job services.
+
+Jobs are services with type = "job" in their definition files. Rather
+than running for a long time, they run to completion with
+[eris services run NAME]. Jobs with a schedule field (a cron
+expression, e.g. "0 3 * * *") are run by [eris jobs scheduler].`,
+	Run: func(cmd *cobra.Command, args []string) { cmd.Help() },
+}
+
+func buildJobsCommand() {
+	Jobs.AddCommand(jobsList)
+	Jobs.AddCommand(jobsHistory)
+	Jobs.AddCommand(jobsScheduler)
+	addJobsFlags()
+}
+
+var jobsList = &cobra.Command{
+	Use:   "ls",
+	Short: "List the known jobs.",
+	Long: `List the known jobs, their schedules, when they run next,
+and the results of their last runs.`,
+	Run: ListJobs,
+}
+
+var jobsHistory = &cobra.Command{
+	Use:   "history [NAME]",
+	Short: "Display past job runs.",
+	Long: `Display past runs of a job (or of all jobs), their triggers,
+durations, results, and the files their output was captured in.`,
+	Example: `$ eris jobs history -- display the runs of all jobs
+$ eris jobs history backup -- display the runs of the backup job`,
+	Run: JobsHistory,
+}
+
+var jobsScheduler = &cobra.Command{
+	Use:   "scheduler",
+	Short: "Run scheduled jobs.",
+	Long: `Run scheduled jobs when their schedules fire.
+
+The scheduler runs in the foreground until it is stopped. Job
+definition files are reloaded every minute, so schedules can be
+changed without restarting the scheduler.`,
+	Run: RunScheduler,
+}
+
+//----------------------------------------------------------------------
+// cli flags
+
+func addJobsFlags() {
+	buildFlag(jobsScheduler, do, "chain", "service")
+}
+
+//----------------------------------------------------------------------
+// cli command wrappers
+
+func ListJobs(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(0, "eq", cmd, args))
+	IfExit(jobs.ListJobs(do))
+}
+
+func JobsHistory(cmd *cobra.Command, args []string) {
+	if len(args) > 0 {
+		do.Name = args[0]
+	}
+	IfExit(jobs.JobsHistory(do))
+}
+
+func RunScheduler(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(0, "eq", cmd, args))
+	IfExit(jobs.Scheduler(do))
+}
@@ -99,6 +99,15 @@ potentially a long-running operation. Used, for example, by
 and
 .Cm eris chains exec
 commands.
+.It Ev ERIS_ALLOW_DRIFT
+If set to
+.Em true ,
+allows running Docker images which do not match the images lock file
+written by
+.Cm eris services lock .
+Same as the
+.Fl -allow-drift
+flag.
 .It Ev DOCKER_HOST
 Docker service host, which
 .Nm
@@ -81,6 +81,7 @@ func addPackagesFlags() {
 	packagesDo.Flags().StringVarP(&do.DefaultAddr, "address", "a", "", "default address to use; operates the same way as the [account] job, only before the epm file is ran")
 	packagesDo.Flags().StringVarP(&do.DefaultFee, "fee", "w", "1234", "default fee to use")
 	packagesDo.Flags().StringVarP(&do.DefaultAmount, "amount", "y", "9999", "default amount to use")
+	packagesDo.Flags().StringVarP(&do.Tag, "reset-to", "", "", "reset the chain to this snapshot before deploying (see [eris chains snapshot])")
 }
 
 //----------------------------------------------------
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/secrets"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
+)
+
+var Secrets = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage secrets supplied to services and chains.",
+	Long: `Manage secrets supplied to services and chains.
+
+Secrets are kept in an encrypted store in $HOME/.eris/secrets.
+The store key is generated on first use and saved alongside the store,
+so anyone who can read that directory can read the secrets: keep it
+readable only by you and leave it out of backups you share. Set the
+ERIS_SECRETS_PASSPHRASE environment variable to derive the key from
+a passphrase (and a random salt kept with the store) instead.
+
+Services and chains refer to secrets by name in their definition files:
+
+  secrets = [ "DB_PASSWORD", "API_TOKEN:TOKEN", "TLS_KEY:tls/key.pem" ]
+
+A secret is exported as an environment variable of the same name,
+as a variable named after the colon, or, if the part after the colon
+is a path, written to a file in the data container (relative paths
+are rooted at /home/eris/.eris). Secret values are never logged.`,
+	Run: func(cmd *cobra.Command, args []string) { cmd.Help() },
+}
+
+func buildSecretsCommand() {
+	Secrets.AddCommand(secretsSet)
+	Secrets.AddCommand(secretsGet)
+	Secrets.AddCommand(secretsList)
+	Secrets.AddCommand(secretsRm)
+}
+
+var secretsSet = &cobra.Command{
+	Use:   "set NAME [VALUE]",
+	Short: "Store a secret.",
+	Long: `Store a secret or overwrite an existing one.
+
+If VALUE is omitted, the value is read from standard input,
+which keeps it out of the shell history.`,
+	Example: `$ eris secrets set DB_PASSWORD hunter2
+$ cat password.txt | eris secrets set DB_PASSWORD`,
+	Run: SetSecret,
+}
+
+var secretsGet = &cobra.Command{
+	Use:   "get NAME",
+	Short: "Print the value of a secret.",
+	Long:  `Print the value of a secret.`,
+	Run:   GetSecret,
+}
+
+var secretsList = &cobra.Command{
+	Use:   "ls",
+	Short: "List the names of stored secrets.",
+	Long:  `List the names of stored secrets. Values are not displayed.`,
+	Run:   ListSecrets,
+}
+
+var secretsRm = &cobra.Command{
+	Use:   "rm NAME [NAME...]",
+	Short: "Remove secrets.",
+	Long:  `Remove secrets from the store.`,
+	Run:   RmSecret,
+}
+
+func SetSecret(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Name = args[0]
+	if len(args) > 1 {
+		do.Operations.Args = args[1:]
+	} else {
+		value, err := ioutil.ReadAll(os.Stdin)
+		IfExit(err)
+		do.Operations.Args = []string{strings.TrimRight(string(value), "\r\n")}
+	}
+	IfExit(secrets.SetSecret(do))
+}
+
+func GetSecret(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(secrets.GetSecret(do))
+}
+
+func ListSecrets(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(0, "eq", cmd, args))
+	IfExit(secrets.ListSecrets(do))
+}
+
+func RmSecret(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Operations.Args = args
+	IfExit(secrets.RmSecret(do))
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/jobs"
 	"github.com/eris-ltd/eris-cli/list"
 	srv "github.com/eris-ltd/eris-cli/services"
 
@@ -34,19 +35,29 @@ image.`,
 func buildServicesCommand() {
 	Services.AddCommand(servicesNew)
 	Services.AddCommand(servicesImport)
+	Services.AddCommand(servicesSearch)
+	Services.AddCommand(servicesLock)
+	Services.AddCommand(servicesInstall)
 	Services.AddCommand(servicesListAll)
 	Services.AddCommand(servicesEdit)
 	Services.AddCommand(servicesStart)
+	Services.AddCommand(servicesRun)
 	Services.AddCommand(servicesLogs)
 	Services.AddCommand(servicesInspect)
 	Services.AddCommand(servicesPorts)
 	Services.AddCommand(servicesExec)
 	Services.AddCommand(servicesStop)
 	Services.AddCommand(servicesExport)
+	Services.AddCommand(servicesBackup)
+	Services.AddCommand(servicesRestore)
 	Services.AddCommand(servicesRename)
 	Services.AddCommand(servicesUpdate)
+	Services.AddCommand(servicesRestart)
+	Services.AddCommand(servicesGraph)
+	Services.AddCommand(servicesDiff)
 	Services.AddCommand(servicesRm)
 	Services.AddCommand(servicesCat)
+	Services.AddCommand(servicesConvert)
 	addServicesFlags()
 }
 
@@ -77,15 +88,105 @@ var servicesImport = &cobra.Command{
 	Run:     ImportService,
 }
 
+var servicesSearch = &cobra.Command{
+	Use:   "search [QUERY]",
+	Short: "Search the known services and the services index.",
+	Long: `Search the installed service definition files and the services
+index for services which match the QUERY. The query is matched (ignoring
+case) against service names, descriptions, tags, and images. All services
+are displayed if no query is given.
+
+The services index is a JSON file given by the --index flag (or the
+ServicesIndex field of eris.toml): either a URL, a local file, or
+a directory containing an index.json file.
+
+To install a service from the index use [eris services install NAME].`,
+	Example: "$ eris services search storage\n" +
+		"$ eris services search --index https://example.com/services/index.json ipfs",
+	Run: SearchServices,
+}
+
+var servicesLock = &cobra.Command{
+	Use:   "lock",
+	Short: "Record the image digests of all services and chains.",
+	Long: `Record the digest of the image of every service and chain definition
+file in the images lock file (~/.eris/images.lock). Images which are not
+found locally are pulled first.
+
+Once locked, images are pulled by their recorded digest, and containers
+are only created from images matching the lock file, so a deployment can
+be reproduced exactly. Use the --allow-drift flag (or set the
+ERIS_ALLOW_DRIFT environment variable to true) to run images which do
+not match. Run [eris services lock] again to update the lock file.
+
+Images referenced by a digest in definition files (image@sha256:...)
+are pinned already and are not recorded.`,
+	Run: LockImages,
+}
+
+var servicesInstall = &cobra.Command{
+	Use:   "install NAME",
+	Short: "Install a service definition file from the services index.",
+	Long: `Install a service definition file from the services index given
+by the --index flag (or the ServicesIndex field of eris.toml) into
+the ~/.eris/services directory.
+
+The file is checked before it is installed. An already installed
+service definition file is only overwritten with the --force flag.`,
+	Example: "$ eris services install ipfs --index ~/services-index",
+	Run:     InstallService,
+}
+
+var servicesBackup = &cobra.Command{
+	Use:   "backup NAME [ARCHIVE]",
+	Short: "Back up the data of a service.",
+	Long: `Back up the data container of a service to a compressed archive.
+
+Along with the data container contents, the archive holds the service
+definition file, the image digest, and a manifest with the checksums
+of all files. If no ARCHIVE is given, the archive is written to the
+current directory as NAME_DATE.tar.gz.
+
+To restore the service use [eris services restore ARCHIVE].`,
+	Example: "$ eris services backup ipfs\n" +
+		"$ eris services backup ipfs /backups/ipfs.tar.gz",
+	Run: BackupService,
+}
+
+var servicesRestore = &cobra.Command{
+	Use:   "restore ARCHIVE [NAME]",
+	Short: "Restore a service from a backup.",
+	Long: `Restore a service from an archive made with [eris services backup].
+
+The checksums of the archive are verified before the service definition
+file and the data container are recreated. If NAME is given, the service
+is restored under that name. An existing service is only replaced with
+the --force flag.`,
+	Example: "$ eris services restore ipfs_2016-03-01_12-00-00.tar.gz\n" +
+		"$ eris services restore ipfs_2016-03-01_12-00-00.tar.gz ipfs_drill",
+	Run: RestoreService,
+}
+
 var servicesNew = &cobra.Command{
 	Use:   "new NAME IMAGE",
 	Short: "Create a new service.",
 	Long: `Create a new service.
 
 Command must be given a NAME and a container IMAGE using the standard
-docker format of [repository/organization/image].`,
+docker format of [repository/organization/image].
+
+The image is inspected (and pulled if it cannot be found locally) to
+prefill the ports, working directory, user, entrypoint, command, and
+environment of the service. Volumes declared by the image are kept
+in the data container; placeholders to bind them to host directories
+are left commented out in the service definition file.
+
+With the --interactive flag each prefilled value is displayed for
+confirmation: press Enter to keep it, type a new value to change it,
+or a dash (-) to clear it.`,
 	Example: "$ eris services new eth eris/eth\n" +
-		"$ eris services new mint tutum.co/tendermint/tendermint",
+		"$ eris services new mint tutum.co/tendermint/tendermint\n" +
+		"$ eris services new ipfs eris/ipfs --interactive",
 	Run: NewService,
 }
 
@@ -119,6 +220,23 @@ To view a service's logs use: [eris services logs NAME].`,
 	Run: StartService,
 }
 
+var servicesRun = &cobra.Command{
+	Use:   "run NAME",
+	Short: "Run a job to completion.",
+	Long: `Run a job service to completion.
+
+Command will start the dependencies of the job service (a service
+with type = "job" in its definition file), run the job container
+until it exits, and remove it. The job output is displayed and
+captured in the jobs directory of the eris root; the run is recorded
+in the jobs history (see [eris jobs history]).
+
+The command exits with the exit code of the job container.`,
+	Example: `$ eris services run backup -- run the backup job
+$ eris services run report --chain simplechain -- run the report job against simplechain`,
+	Run: RunService,
+}
+
 var servicesInspect = &cobra.Command{
 	Use:   "inspect NAME [KEY]",
 	Short: "Machine readable service operation details.",
@@ -183,9 +301,8 @@ var servicesRename = &cobra.Command{
 }
 
 var servicesUpdate = &cobra.Command{
-	Use:     "update NAME",
-	Aliases: []string{"restart"},
-	Short:   "Update an installed service.",
+	Use:   "update NAME",
+	Short: "Update an installed service.",
 	Long: `Update an installed service, or install it if it has not been installed.
 
 Functionally this command will perform the following sequence of steps:
@@ -201,6 +318,59 @@ by the [eris update] command.`,
 	Run: UpdateService,
 }
 
+var servicesRestart = &cobra.Command{
+	Use:   "restart NAME [NAME...]",
+	Short: "Restart running services.",
+	Long: `Restart running services without losing the links of their dependents.
+
+Services are restarted one at a time in the order of their dependency
+graph; each service has to become ready before the next one is touched.
+Services running several instances are restarted one instance at a time.
+
+With the --recreate flag (implied by --pull, --env, and --links) the
+containers are recreated instead. Running services linking to the
+recreated containers are then restarted as well, so that their links
+point to the new containers.`,
+	Example: `$ eris services restart ipfs -- restart the ipfs service
+$ eris services restart ipfs --pull -- recreate ipfs from an updated image
+$ eris services restart mint --all -- restart mint and its dependencies`,
+	Run: RestartService,
+}
+
+var servicesGraph = &cobra.Command{
+	Use:   "graph [NAME...]",
+	Short: "Display the dependency graph of services.",
+	Long: `Display the dependency graph of services.
+
+The graph shows the services and chains [eris services start] would
+bring up, resolved from the dependencies and chain fields of the service
+definition files. Nodes are marked as running, stopped, or missing (no
+definition file); edges show whether a dependency is linked to, has its
+volumes mounted, or both.
+
+If no services are given, the graph of all known services is displayed.`,
+	Example: `$ eris services graph ipfs -- display the ipfs dependency tree
+$ eris services graph --format dot | dot -Tpng > services.png -- draw all services with Graphviz`,
+	Run: GraphService,
+}
+
+var servicesDiff = &cobra.Command{
+	Use:   "diff NAME",
+	Short: "Compare a service container with its definition file.",
+	Long: `Compare a service container with its definition file.
+
+Command will compare the image, environment, ports, volumes, links,
+and other settings of the service container with the ones the service
+definition file describes now, and display any differences.
+
+The command exits with a non-zero status if the container has drifted
+from its definition file, unless the --fix flag is given, in which case
+the container is recreated from the definition file.`,
+	Example: `$ eris services diff ipfs -- display differences, exit with 1 on drift
+$ eris services diff ipfs --fix -- recreate the container on drift`,
+	Run: DiffService,
+}
+
 var servicesRm = &cobra.Command{
 	Use:   "rm NAME",
 	Short: "Remove an installed service.",
@@ -216,10 +386,26 @@ var servicesCat = &cobra.Command{
 	Short: "Display the service definition file.",
 	Long: `Display the service definition file.
 
-Command will cat local service definition file.`,
+Command will cat local service definition file.
+
+With the --format flag, the service definition file is loaded
+and displayed in the requested format (toml, json, or yaml).`,
+	Example: `$ eris services cat ipfs -- will display the service definition file as is
+$ eris services cat ipfs --format json -- will display the service definition in JSON`,
 	Run: CatService,
 }
 
+var servicesConvert = &cobra.Command{
+	Use:   "convert NAME",
+	Short: "Convert the service definition file to another format.",
+	Long: `Convert the service definition file to another format.
+
+Command will rewrite the service definition file in place
+in the format given by the --to flag (toml, json, or yaml).`,
+	Example: `$ eris services convert ipfs --to yaml -- will replace ipfs.toml with ipfs.yaml`,
+	Run:     ConvertService,
+}
+
 //----------------------------------------------------------------------
 // cli flags
 
@@ -238,6 +424,13 @@ func addServicesFlags() {
 	buildFlag(servicesUpdate, do, "env", "service")
 	buildFlag(servicesUpdate, do, "links", "service")
 
+	buildFlag(servicesRestart, do, "pull", "service")
+	buildFlag(servicesRestart, do, "timeout", "service")
+	buildFlag(servicesRestart, do, "env", "service")
+	buildFlag(servicesRestart, do, "links", "service")
+	servicesRestart.Flags().BoolVarP(&do.Recreate, "recreate", "", false, "recreate the containers instead of restarting them")
+	servicesRestart.Flags().BoolVarP(&do.All, "all", "a", false, "restart the dependencies of the services as well")
+
 	buildFlag(servicesRm, do, "force", "service")
 	buildFlag(servicesRm, do, "file", "service")
 	buildFlag(servicesRm, do, "data", "service")
@@ -248,6 +441,10 @@ func addServicesFlags() {
 	buildFlag(servicesStart, do, "links", "service")
 	buildFlag(servicesStart, do, "chain", "service")
 
+	buildFlag(servicesRun, do, "env", "service")
+	buildFlag(servicesRun, do, "links", "service")
+	buildFlag(servicesRun, do, "chain", "service")
+
 	buildFlag(servicesStop, do, "rm", "service")
 	buildFlag(servicesStop, do, "volumes", "service")
 	buildFlag(servicesStop, do, "data", "service")
@@ -261,6 +458,24 @@ func addServicesFlags() {
 	buildFlag(servicesListAll, do, "running", "service")
 	buildFlag(servicesListAll, do, "quiet", "service")
 
+	servicesRestore.Flags().BoolVarP(&do.Force, "force", "f", false, "replace the existing service definition file and data container")
+
+	servicesSearch.Flags().StringVarP(&do.Index, "index", "", "", "services index URL, file, or directory")
+	servicesInstall.Flags().StringVarP(&do.Index, "index", "", "", "services index URL, file, or directory")
+	servicesInstall.Flags().BoolVarP(&do.Force, "force", "f", false, "overwrite the installed service definition file")
+
+	servicesNew.Flags().BoolVarP(&do.Operations.Interactive, "interactive", "i", false, "confirm each value prefilled from the image")
+
+	buildFlag(servicesDiff, do, "chain", "service")
+	buildFlag(servicesDiff, do, "timeout", "service")
+	servicesDiff.Flags().BoolVarP(&do.Fix, "fix", "", false, "recreate the container from the definition file if it has drifted")
+
+	buildFlag(servicesGraph, do, "chain", "service")
+	servicesGraph.Flags().StringVarP(&do.GraphFormat, "format", "", "text", "graph output format (text or dot)")
+
+	servicesCat.Flags().StringVarP(&do.Format, "format", "", "", "display the loaded service definition in this format (toml, json, or yaml)")
+	servicesConvert.Flags().StringVarP(&do.ConvertTo, "to", "", "toml", "format to convert the service definition file to (toml, json, or yaml)")
+
 }
 
 //----------------------------------------------------------------------
@@ -272,6 +487,14 @@ func StartService(cmd *cobra.Command, args []string) {
 	IfExit(srv.StartService(do))
 }
 
+func RunService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	exitCode, err := jobs.RunJob(do)
+	IfExit(err)
+	os.Exit(exitCode)
+}
+
 func LogService(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(1, "ge", cmd, args))
 	do.Name = args[0]
@@ -312,6 +535,41 @@ func ImportService(cmd *cobra.Command, args []string) {
 	IfExit(srv.ImportService(do))
 }
 
+func BackupService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Name = args[0]
+	// keys export binds --dest to do.Destination with a default.
+	do.Destination = ""
+	if len(args) > 1 {
+		do.Destination = args[1]
+	}
+	IfExit(srv.BackupService(do))
+}
+
+func RestoreService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Source = args[0]
+	if len(args) > 1 {
+		do.NewName = args[1]
+	}
+	IfExit(srv.RestoreService(do))
+}
+
+func SearchServices(cmd *cobra.Command, args []string) {
+	do.Operations.Args = args
+	IfExit(srv.SearchServices(do))
+}
+
+func LockImages(cmd *cobra.Command, args []string) {
+	IfExit(srv.LockImages(do))
+}
+
+func InstallService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(srv.InstallService(do))
+}
+
 func NewService(cmd *cobra.Command, args []string) {
 	IfExit(ArgCheck(2, "ge", cmd, args))
 	do.Name = args[0]
@@ -365,6 +623,23 @@ func UpdateService(cmd *cobra.Command, args []string) {
 	IfExit(srv.UpdateService(do))
 }
 
+func RestartService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "ge", cmd, args))
+	do.Operations.Args = args
+	IfExit(srv.RestartService(do))
+}
+
+func DiffService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(srv.DiffService(do))
+}
+
+func GraphService(cmd *cobra.Command, args []string) {
+	do.Operations.Args = args
+	IfExit(srv.GraphService(do))
+}
+
 func ListAllServices(cmd *cobra.Command, args []string) {
 	//if no flags are set, list all the things
 	//otherwise, allow only a single flag
@@ -401,3 +676,9 @@ func CatService(cmd *cobra.Command, args []string) {
 	do.Name = args[0]
 	IfExit(srv.CatService(do))
 }
+
+func ConvertService(cmd *cobra.Command, args []string) {
+	IfExit(ArgCheck(1, "eq", cmd, args))
+	do.Name = args[0]
+	IfExit(srv.ConvertService(do))
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/eris-ltd/eris-cli/status"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/spf13/cobra"
+)
+
+var Status = &cobra.Command{
+	Use:   "status [chains|services] [NAME...]",
+	Short: "Check the health of services and chains.",
+	Long: `Check the health of services and chains for monitoring tools.
+
+For each service or chain the command checks that its container exists
+and runs, how many times it was restarted, its last exit code, and, if
+the definition file has a ready field, the result of running that
+readiness check command in the container. If no names are given, all
+existing service and chain containers are checked.
+
+The command prints a summary and exits with the worst status found,
+following the Nagios plugin conventions:
+
+  0 (OK)   - all containers are running (and ready)
+  1 (WARN) - a container was restarted or is paused
+  2 (CRIT) - a container is missing, stopped, restarting, or not ready`,
+	Example: `$ eris status -- check all service and chain containers
+$ eris status services ipfs keys -- check the ipfs and keys services
+$ eris status chains --json -- check all chains, report in JSON`,
+	Run: CheckStatus,
+}
+
+func buildStatusCommand() {
+	addStatusFlags()
+}
+
+func addStatusFlags() {
+	Status.Flags().BoolVarP(&do.JSON, "json", "", false, "write the report in JSON")
+}
+
+func CheckStatus(cmd *cobra.Command, args []string) {
+	do.Operations.Args = args
+	level, err := status.Status(do)
+	if err != nil {
+		log.Error(err)
+		os.Exit(status.HealthCrit)
+	}
+	os.Exit(level)
+}
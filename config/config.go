@@ -32,6 +32,11 @@ type ErisConfig struct {
 	DockerHost     string `json:"DockerHost,omitempty" yaml:"DockerHost,omitempty" toml:"DockerHost,omitempty"`
 	DockerCertPath string `json:"DockerCertPath,omitempty" yaml:"DockerCertPath,omitempty" toml:"DockerCertPath,omitempty"`
 	CrashReport    string `json:"CrashReport,omitempty" yaml:"CrashReport,omitempty" toml:"CrashReport,omitempty"`
+	ServicesIndex  string `json:"ServicesIndex,omitempty" yaml:"ServicesIndex,omitempty" toml:"ServicesIndex,omitempty"`
+
+	// "hardened" drops all Linux capabilities of service and chain
+	// containers except those explicitly added
+	SecurityProfile string `json:"SecurityProfile,omitempty" yaml:"SecurityProfile,omitempty" toml:"SecurityProfile,omitempty"`
 
 	Verbose bool
 }
@@ -127,6 +132,10 @@ func GetConfigValue(key string) string {
 		return GlobalConfig.Config.DockerCertPath
 	case "CrashReport":
 		return GlobalConfig.Config.CrashReport
+	case "ServicesIndex":
+		return GlobalConfig.Config.ServicesIndex
+	case "SecurityProfile":
+		return GlobalConfig.Config.SecurityProfile
 	default:
 		return ""
 	}
@@ -0,0 +1,470 @@
+package data
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// Layout of a backup archive (a gzipped tarball). The manifest comes
+// first, so the archive can be verified in a single pass.
+const (
+	BackupManifestFile  = "manifest.json"
+	BackupDefinitionDir = "definition"
+	BackupDataDir       = "data"
+)
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	// name of the service or chain backed up
+	Name string `json:"name"`
+	// service or chain
+	Type    string    `json:"type"`
+	Created time.Time `json:"created"`
+
+	// path of the definition file in the archive
+	Definition string `json:"definition"`
+	// image the service or chain was running and its ID and digests
+	// at the time of the backup
+	Image        string   `json:"image"`
+	ImageID      string   `json:"image_id,omitempty"`
+	ImageDigests []string `json:"image_digests,omitempty"`
+	// chain_id of a backed up chain
+	ChainID string `json:"chain_id,omitempty"`
+
+	// SHA-256 checksums of all files in the archive (except for
+	// the manifest itself) by their path in the archive
+	Checksums map[string]string `json:"checksums"`
+}
+
+// Backup is a verified backup archive read by ReadBackup.
+type Backup struct {
+	Manifest   *BackupManifest
+	Definition []byte
+
+	// data container contents, as a tarball relative to
+	// the parent of ErisContainerRoot
+	data string
+}
+
+// WriteBackup writes a backup archive of the data container of the
+// service or chain described by the manifest. The definition file
+// and its checksum are added to the archive.
+//
+//  manifest.Name - name of the data container (required)
+//  archive       - path of the archive to create (required)
+//  definition    - path of the definition file (required)
+//
+func WriteBackup(manifest *BackupManifest, archive, definition string) error {
+	if !util.IsDataContainer(manifest.Name) {
+		return fmt.Errorf("There is no data container for %s. Only %ss with data containers can be backed up", manifest.Name, manifest.Type)
+	}
+
+	log.WithField("=>", manifest.Name).Info("Backing up data container")
+	data, checksums, err := downloadData(manifest.Name)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(data)
+
+	def, err := ioutil.ReadFile(definition)
+	if err != nil {
+		return err
+	}
+	manifest.Definition = path.Join(BackupDefinitionDir, filepath.Base(definition))
+	checksums[manifest.Definition] = checksum(def)
+	manifest.Checksums = checksums
+	if manifest.Created.IsZero() {
+		manifest.Created = time.Now().UTC()
+	}
+
+	out, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	meta, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, BackupManifestFile, meta); err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, manifest.Definition, def); err != nil {
+		return err
+	}
+
+	in, err := os.Open(data)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(BackupDataDir, header.Name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"=>":    manifest.Name,
+		"files": len(checksums),
+	}).Info("Backup written")
+	return nil
+}
+
+// ReadBackup reads the backup archive and verifies the checksums of
+// all files listed in its manifest. The data container contents are
+// kept in a temporary file until the backup is closed.
+func ReadBackup(archive string) (*Backup, error) {
+	in, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a backup archive: %v", archive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != BackupManifestFile {
+		return nil, fmt.Errorf("%s is not a backup archive: %s is missing", archive, BackupManifestFile)
+	}
+	b := &Backup{Manifest: &BackupManifest{}}
+	if err := json.NewDecoder(tr).Decode(b.Manifest); err != nil {
+		return nil, fmt.Errorf("Cannot read the backup manifest: %v", err)
+	}
+
+	data, err := ioutil.TempFile("", "eris_backup_")
+	if err != nil {
+		return nil, err
+	}
+	b.data = data.Name()
+	defer data.Close()
+	tw := tar.NewWriter(data)
+
+	seen := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			b.Close()
+			return nil, err
+		}
+
+		// Entries are streamed to the temporary file, hashing them
+		// on the way; only the definition file is kept in memory.
+		name := header.Name
+		hash := sha256.New()
+		switch {
+		case name == b.Manifest.Definition:
+			if b.Definition, err = ioutil.ReadAll(io.TeeReader(tr, hash)); err != nil {
+				b.Close()
+				return nil, err
+			}
+		case strings.HasPrefix(name, BackupDataDir+"/"):
+			header.Name = strings.TrimPrefix(name, BackupDataDir+"/")
+			if err := tw.WriteHeader(header); err != nil {
+				b.Close()
+				return nil, err
+			}
+			if _, err := io.Copy(io.MultiWriter(tw, hash), tr); err != nil {
+				b.Close()
+				return nil, err
+			}
+		default:
+			if _, err := io.Copy(hash, tr); err != nil {
+				b.Close()
+				return nil, err
+			}
+		}
+
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			if sum, ok := b.Manifest.Checksums[name]; !ok || sum != hex.EncodeToString(hash.Sum(nil)) {
+				b.Close()
+				return nil, fmt.Errorf("Checksum mismatch for %s. The backup archive is corrupted", name)
+			}
+			seen[name] = true
+		}
+	}
+	if err := tw.Close(); err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	var missing []string
+	for name := range b.Manifest.Checksums {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		b.Close()
+		sort.Strings(missing)
+		return nil, fmt.Errorf("The backup archive is missing files listed in its manifest:\n  %s", strings.Join(missing, "\n  "))
+	}
+
+	log.WithFields(log.Fields{
+		"=>":    b.Manifest.Name,
+		"files": len(seen),
+	}).Info("Backup verified")
+	return b, nil
+}
+
+// Restore recreates the data container with the contents of the backup.
+// An existing data container is only replaced if force is set. The rename
+// function (can be nil) changes paths of the restored files; name is
+// the name of the data container to create.
+func (b *Backup) Restore(name string, force bool, rename func(string) string) error {
+	if util.IsDataContainer(name) {
+		if !force {
+			return fmt.Errorf("The data container for %s already exists. Use --force to replace it", name)
+		}
+		do := definitions.NowDo()
+		do.Name = name
+		do.Volumes = true
+		if err := RmData(do); err != nil {
+			return err
+		}
+	}
+
+	log.WithField("=>", name).Info("Creating data container")
+	ops := loaders.LoadDataDefinition(name)
+	if err := perform.DockerCreateData(ops); err != nil {
+		return fmt.Errorf("Error creating data container %v.", err)
+	}
+	srv := PretendToBeAService(name)
+	service, exists := perform.ContainerExists(srv.Operations)
+	if !exists {
+		return fmt.Errorf("There is no data container for %s.", name)
+	}
+
+	reader, err := b.dataReader(rename)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	log.WithField("=>", util.DataContainersName(name)).Info("Copying into container")
+	opts := docker.UploadToContainerOptions{
+		InputStream: reader,
+		Path:        path.Dir(ErisContainerRoot),
+	}
+	if err := util.DockerClient.UploadToContainer(service.ID, opts); err != nil {
+		return err
+	}
+
+	// Required because `docker cp` (UploadToContainer) goes in as root.
+	ops.Args = []string{"chown", "--recursive", "eris", ErisContainerRoot}
+	if _, err := perform.DockerRunData(ops, nil); err != nil {
+		return fmt.Errorf("Error changing owner: %v\n", err)
+	}
+	return nil
+}
+
+// Close removes the temporary files of the backup.
+func (b *Backup) Close() error {
+	if b.data == "" {
+		return nil
+	}
+	return os.Remove(b.data)
+}
+
+// dataReader returns the data container contents with the paths
+// changed by the rename function.
+func (b *Backup) dataReader(rename func(string) string) (io.ReadCloser, error) {
+	in, err := os.Open(b.data)
+	if err != nil {
+		return nil, err
+	}
+	if rename == nil {
+		return in, nil
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer in.Close()
+		tr := tar.NewReader(in)
+		tw := tar.NewWriter(writer)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			header.Name = rename(header.Name)
+			if err := tw.WriteHeader(header); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+		writer.CloseWithError(tw.Close())
+	}()
+	return reader, nil
+}
+
+// downloadData copies the contents of the ErisContainerRoot of the data
+// container to a temporary tarball and returns its name along with the
+// checksums of the files (by their path in a backup archive).
+func downloadData(name string) (string, map[string]string, error) {
+	srv := PretendToBeAService(name)
+	service, exists := perform.ContainerExists(srv.Operations)
+	if !exists {
+		return "", nil, fmt.Errorf("There is no data container for %s.", name)
+	}
+
+	temp, err := ioutil.TempFile("", "eris_backup_")
+	if err != nil {
+		return "", nil, err
+	}
+	defer temp.Close()
+
+	reader, writer := io.Pipe()
+	defer reader.Close()
+	go func() {
+		opts := docker.DownloadFromContainerOptions{
+			OutputStream: writer,
+			Path:         ErisContainerRoot,
+		}
+		log.WithField("=>", util.DataContainersName(name)).Info("Copying out of container")
+		writer.CloseWithError(util.DockerClient.DownloadFromContainer(service.ID, opts))
+	}()
+
+	checksums := make(map[string]string)
+	tr := tar.NewReader(reader)
+	tw := tar.NewWriter(temp)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			os.Remove(temp.Name())
+			return "", nil, err
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			os.Remove(temp.Name())
+			return "", nil, err
+		}
+
+		hash := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(tw, hash), tr); err != nil {
+			os.Remove(temp.Name())
+			return "", nil, err
+		}
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			checksums[path.Join(BackupDataDir, header.Name)] = hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		os.Remove(temp.Name())
+		return "", nil, err
+	}
+	return temp.Name(), checksums, nil
+}
+
+// ImageDigests returns the ID and the repository digests of the image
+// of the container (or of the named image, if there is no container).
+func ImageDigests(image, containerID string) (string, []string) {
+	if containerID != "" {
+		if container, err := util.DockerClient.InspectContainer(containerID); err == nil {
+			image = container.Image
+		}
+	}
+
+	img, err := util.DockerClient.InspectImage(image)
+	if err != nil {
+		log.WithField("image", image).Debugf("Cannot inspect image: %v", err)
+		return "", nil
+	}
+	return img.ID, img.RepoDigests
+}
+
+// WarnImageDrift warns if the image found locally is not the one
+// in use at the time of the backup.
+func (manifest *BackupManifest) WarnImageDrift() {
+	if manifest.ImageID == "" {
+		return
+	}
+	if id, _ := ImageDigests(manifest.Image, ""); id != manifest.ImageID {
+		log.WithFields(log.Fields{
+			"image":  manifest.Image,
+			"backup": manifest.ImageID,
+			"local":  id,
+		}).Warn("The image differs from the one backed up")
+	}
+}
+
+func writeTarFile(tw *tar.Writer, name string, contents []byte) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(contents)),
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(contents)
+	return err
+}
+
+func checksum(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
@@ -1,7 +1,11 @@
 package data
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -197,6 +201,124 @@ func TestRmData(t *testing.T) {
 	testExist(t, dataName, false)
 }
 
+func TestBackupRestoreData(t *testing.T) {
+	testCreateDataByImport(t, dataName)
+	defer testKillDataCont(t, dataName)
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatalf("expected a temporary directory, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	definition := filepath.Join(dir, dataName+".toml")
+	if err := ioutil.WriteFile(definition, []byte(`name = "`+dataName+`"`), 0644); err != nil {
+		t.Fatalf("expected definition file to be written, got %v", err)
+	}
+
+	archive := filepath.Join(dir, "backup.tar.gz")
+	manifest := &BackupManifest{Name: dataName, Type: definitions.TypeService}
+	if err := WriteBackup(manifest, archive, definition); err != nil {
+		t.Fatalf("expected backup to be written, got %v", err)
+	}
+	if _, ok := manifest.Checksums["data/.eris/test"]; !ok {
+		t.Fatalf("expected the data container file to be checksummed, got %v", manifest.Checksums)
+	}
+
+	b, err := ReadBackup(archive)
+	if err != nil {
+		t.Fatalf("expected backup to be verified, got %v", err)
+	}
+	defer b.Close()
+	if string(b.Definition) != `name = "`+dataName+`"` {
+		t.Fatalf("expected definition file to be restored, got %q", b.Definition)
+	}
+
+	if err := b.Restore(dataName, false, nil); err == nil {
+		t.Fatalf("expected restore over an existing data container to fail, got nil")
+	}
+	if err := b.Restore(newName, false, nil); err != nil {
+		t.Fatalf("expected data container to be restored, got %v", err)
+	}
+	defer testKillDataCont(t, newName)
+
+	do := definitions.NowDo()
+	do.Name = newName
+	do.Source = common.ErisContainerRoot
+	do.Destination = filepath.Join(dir, "export")
+	if err := ExportData(do); err != nil {
+		t.Fatalf("expected restored data to be exported, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "export", "test")); err != nil {
+		t.Fatalf("expected restored file to exist, got %v", err)
+	}
+}
+
+func TestReadBackupCorrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatalf("expected a temporary directory, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"definition/ipfs.toml": `name = "ipfs"`,
+		"data/.eris/test":      "marmots",
+	}
+	checksums := map[string]string{
+		"definition/ipfs.toml": checksum([]byte(`name = "ipfs"`)),
+		"data/.eris/test":      checksum([]byte("marmots")),
+	}
+
+	for _, test := range []struct {
+		name  string
+		files map[string]string
+		sums  map[string]string
+		ok    bool
+	}{
+		{"valid", files, checksums, true},
+		{"changed", map[string]string{"definition/ipfs.toml": `name = "ipfs"`, "data/.eris/test": "beavers"}, checksums, false},
+		{"missing", map[string]string{"definition/ipfs.toml": `name = "ipfs"`}, checksums, false},
+		{"unlisted", files, map[string]string{"definition/ipfs.toml": checksums["definition/ipfs.toml"]}, false},
+	} {
+		archive := filepath.Join(dir, test.name+".tar.gz")
+		testWriteArchive(t, archive, &BackupManifest{Name: "ipfs", Definition: "definition/ipfs.toml", Checksums: test.sums}, test.files)
+
+		b, err := ReadBackup(archive)
+		if test.ok && err != nil {
+			t.Fatalf("%s: expected backup to be verified, got %v", test.name, err)
+		}
+		if !test.ok && err == nil {
+			t.Fatalf("%s: expected verification to fail, got nil", test.name)
+		}
+		if b != nil {
+			b.Close()
+		}
+	}
+}
+
+func testWriteArchive(t *testing.T, archive string, manifest *BackupManifest, files map[string]string) {
+	out, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("expected archive to be created, got %v", err)
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	meta, _ := json.Marshal(manifest)
+	if err := writeTarFile(tw, BackupManifestFile, meta); err != nil {
+		t.Fatalf("expected manifest to be written, got %v", err)
+	}
+	for name, contents := range files {
+		if err := writeTarFile(tw, name, []byte(contents)); err != nil {
+			t.Fatalf("expected %s to be written, got %v", name, err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+}
+
 //creates a new data container w/ dir to be used by a test
 //maybe give create opts? => paths, files, file contents, etc
 func testCreateDataByImport(t *testing.T, name string) {
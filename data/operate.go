@@ -27,25 +27,26 @@ import (
 //  do.Name                       - name of the data container to use (required)
 //  do.Source                     - directory which should be imported (required)
 //  do.Destination                - directory to _unload_ the payload into (required)
+//  do.Operations.DataContainerName - full name of the data container to use (optional; numbered chain nodes)
 //
 func ImportData(do *definitions.Do) error {
 	log.WithFields(log.Fields{
 		"from": do.Source,
 		"to":   do.Destination,
 	}).Debug("Importing")
-	if util.IsDataContainer(do.Name) {
 
-		srv := PretendToBeAService(do.Name)
-		service, exists := perform.ContainerExists(srv.Operations)
+	// Nodes of a chain network have data containers of their own.
+	containerName := util.DataContainersName(do.Name)
+	if util.ContainersShortName(do.Operations.DataContainerName) != "" {
+		containerName = do.Operations.DataContainerName
+	}
+	name := util.ContainerDisassemble(containerName)
 
-		if !exists {
-			return fmt.Errorf("There is no data container for that service.")
-		}
+	if service := util.FindNumberedContainer(definitions.TypeData, name.ShortName, name.Number); service != nil {
 		if err := checkErisContainerRoot(do, "import"); err != nil {
 			return err
 		}
 
-		containerName := util.DataContainersName(do.Name)
 		// os.Chdir(do.Source)
 
 		reader, err := util.Tar(do.Source, 0)
@@ -62,7 +63,7 @@ func ImportData(do *definitions.Do) error {
 
 		log.WithField("=>", containerName).Info("Copying into container")
 		log.WithField("path", do.Source).Debug()
-		if err := util.DockerClient.UploadToContainer(service.ID, opts); err != nil {
+		if err := util.DockerClient.UploadToContainer(service.ContainerID, opts); err != nil {
 			return err
 		}
 
@@ -78,6 +79,7 @@ func ImportData(do *definitions.Do) error {
 	} else {
 		log.WithField("name", do.Name).Info("Data container does not exist.")
 		ops := loaders.LoadDataDefinition(do.Name)
+		ops.DataContainerName = containerName
 		if err := perform.DockerCreateData(ops); err != nil {
 			return fmt.Errorf("Error creating data container %v.", err)
 		}
@@ -105,6 +107,7 @@ func ExecData(do *definitions.Do) (buf *bytes.Buffer, err error) {
 }
 
 //export from: do.Source(in container), to: do.Destination(on host)
+//  do.Operations.DataContainerName - full name of the data container to use (optional; numbered chain nodes)
 func ExportData(do *definitions.Do) error {
 	if util.IsDataContainer(do.Name) {
 		log.WithField("=>", do.Name).Info("Exporting data container")
@@ -116,11 +119,15 @@ func ExportData(do *definitions.Do) error {
 			return err
 		}
 
+		// Nodes of a chain network have data containers of their own.
 		containerName := util.DataContainersName(do.Name)
-		srv := PretendToBeAService(do.Name)
-		service, exists := perform.ContainerExists(srv.Operations)
+		if util.ContainersShortName(do.Operations.DataContainerName) != "" {
+			containerName = do.Operations.DataContainerName
+		}
+		name := util.ContainerDisassemble(containerName)
 
-		if !exists {
+		service := util.FindNumberedContainer(definitions.TypeData, name.ShortName, name.Number)
+		if service == nil {
 			return fmt.Errorf("There is no data container for that service.")
 		}
 
@@ -138,7 +145,7 @@ func ExportData(do *definitions.Do) error {
 		go func() {
 			log.WithField("=>", containerName).Info("Copying out of container")
 			log.WithField("path", do.Source).Debug()
-			IfExit(util.DockerClient.DownloadFromContainer(service.ID, opts)) // TODO: be smarter about catching this error
+			IfExit(util.DockerClient.DownloadFromContainer(service.ContainerID, opts)) // TODO: be smarter about catching this error
 			writer.Close()
 		}()
 
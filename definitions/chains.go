@@ -1,5 +1,9 @@
 package definitions
 
+// ChainTypeThrowaway is the chain_type of the chains made by
+// chains.ThrowAwayChain; their containers get the LabelThrowaway label.
+const ChainTypeThrowaway = "throwaway"
+
 type Chain struct {
 	// name of the chain
 	Name string `json:"name" yaml:"name" toml:"name"`
@@ -7,6 +11,10 @@ type Chain struct {
 	ChainID string `mapstructure:"chain_id" json:"chain_id" yaml:"chain_id" toml:"chain_id"`
 	// type of the chain
 	ChainType string `mapstructure:"chain_type" json:"chain_type" yaml:"chain_type" toml:"chain_type"`
+	// image the chain ran before its last upgrade
+	PreviousImage string `mapstructure:"previous_image" json:"previous_image,omitempty" yaml:"previous_image,omitempty" toml:"previous_image,omitempty"`
+	// digest (or ID, for local images) of the image the chain ran before its last upgrade
+	PreviousImageDigest string `mapstructure:"previous_image_digest" json:"previous_image_digest,omitempty" yaml:"previous_image_digest,omitempty" toml:"previous_image_digest,omitempty"`
 
 	// same fields as in the Service Struct/Service Specification
 	Service      *Service      `json:"service,omitempty" yaml:"service,omitempty" toml:"service,omitempty"`
@@ -14,7 +22,8 @@ type Chain struct {
 	Maintainer   *Maintainer   `json:"maintainer,omitempty" yaml:"maintainer,omitempty" toml:"maintainer,omitempty"`
 	Location     *Location     `json:"location,omitempty" yaml:"location,omitempty" toml:"location,omitempty"`
 	Machine      *Machine      `json:"machine,omitempty" yaml:"machine,omitempty" toml:"machine,omitempty"`
-	Operations   *Operation
+	Hooks        *Hooks        `json:"hooks,omitempty" yaml:"hooks,omitempty" toml:"hooks,omitempty"`
+	Operations   *Operation    `json:"-" yaml:"-" toml:"-"`
 }
 
 func BlankChain() *Chain {
@@ -23,6 +32,7 @@ func BlankChain() *Chain {
 		Maintainer: BlankMaintainer(),
 		Location:   BlankLocation(),
 		Machine:    BlankMachine(),
+		Hooks:      BlankHooks(),
 		Operations: BlankOperation(),
 	}
 }
@@ -16,6 +16,8 @@ const (
 	LabelID        = "ID"
 	LabelTest      = "TEST"
 	LabelTestID    = "TEST_ID"
+	LabelSecrets   = "SECRETS"
+	LabelThrowaway = "THROWAWAY"
 
 	TypeChain   = "chain"
 	TypeService = "service"
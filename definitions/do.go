@@ -6,9 +6,18 @@ type Do struct {
 	Force         bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	File          bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Pull          bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Recreate      bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Restart       bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	NewKeys       bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	NoPriv        bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Fix           bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	JSON          bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	AllowDrift    bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Quiet         bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	All           bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Follow        bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Watch         bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Local         bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Logsrotate    bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Run           bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Rm            bool     `mapstructure:"," json:"," yaml:"," toml:","`
@@ -24,14 +33,20 @@ type Do struct {
 	Output        bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	OutputTable   bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Dump          bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	DryRun        bool     `mapstructure:"," json:"," yaml:"," toml:","`
+	Rollback      bool     `mapstructure:"," json:"," yaml:"," toml:","`
 	Lines         int      `mapstructure:"," json:"," yaml:"," toml:","` // XXX: for tail and logs
 	Timeout       uint     `mapstructure:"," json:"," yaml:"," toml:","`
+	Interval      uint     `mapstructure:"," json:"," yaml:"," toml:","`
+	Wait          uint     `mapstructure:"," json:"," yaml:"," toml:","`
 	N             uint     `mapstructure:"," json:"," yaml:"," toml:","`
 	Address       string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Pubkey        string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Type          string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Task          string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Tag           string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Tail          string   `mapstructure:"," json:"," yaml:"," toml:","`
+	OlderThan     string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Branch        string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ChainName     string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ChainType     string   `mapstructure:"," json:"," yaml:"," toml:","`
@@ -48,6 +63,11 @@ type Do struct {
 	CSV           string   `mapstructure:"," json:"," yaml:"," toml:","`
 	NewName       string   `mapstructure:"," json:"," yaml:"," toml:","`
 	ResultFormt   string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Format        string   `mapstructure:"," json:"," yaml:"," toml:","`
+	GraphFormat   string   `mapstructure:"," json:"," yaml:"," toml:","`
+	ConvertTo     string   `mapstructure:"," json:"," yaml:"," toml:","`
+	BundleFile    string   `mapstructure:"," json:"," yaml:"," toml:","`
+	Index         string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Priv          string   `mapstructure:"," json:"," yaml:"," toml:","`
 	Volume        string   `mapstructure:"," json:"," yaml:"," toml:","`
 	EPMConfigFile string   `mapstructure:"," json:"," yaml:"," toml:","`
@@ -0,0 +1,44 @@
+package definitions
+
+// Lifecycle events hooks are run around.
+const (
+	HookPreStart  = "pre_start"
+	HookPostStart = "post_start"
+	HookPreStop   = "pre_stop"
+	HookPostStop  = "post_stop"
+)
+
+// Failure policies of hooks.
+const (
+	// stop the operation and return the hook error (default)
+	HookAbort = "abort"
+	// log the hook error and carry on
+	HookWarn = "warn"
+)
+
+type Hooks struct {
+	// run before the container is started
+	PreStart []*Hook `mapstructure:"pre_start" json:"pre_start,omitempty" yaml:"pre_start,omitempty" toml:"pre_start,omitempty"`
+	// run after the container is started
+	PostStart []*Hook `mapstructure:"post_start" json:"post_start,omitempty" yaml:"post_start,omitempty" toml:"post_start,omitempty"`
+	// run before the container is stopped
+	PreStop []*Hook `mapstructure:"pre_stop" json:"pre_stop,omitempty" yaml:"pre_stop,omitempty" toml:"pre_stop,omitempty"`
+	// run after the container is stopped
+	PostStop []*Hook `mapstructure:"post_stop" json:"post_stop,omitempty" yaml:"post_stop,omitempty" toml:"post_stop,omitempty"`
+}
+
+type Hook struct {
+	// eris action to perform (e.g. "dns register")
+	Action string `mapstructure:"action" json:"action,omitempty" yaml:"action,omitempty" toml:"action,omitempty"`
+	// inline command run with `sh -c` on the host
+	Command string `mapstructure:"command" json:"command,omitempty" yaml:"command,omitempty" toml:"command,omitempty"`
+	// run the command in a throwaway container of the service (or chain)
+	// image rather than on the host
+	Container bool `mapstructure:"container" json:"container,omitempty" yaml:"container,omitempty" toml:"container,omitempty"`
+	// failure policy: "abort" (default) or "warn"
+	OnFailure string `mapstructure:"on_failure" json:"on_failure,omitempty" yaml:"on_failure,omitempty" toml:"on_failure,omitempty"`
+}
+
+func BlankHooks() *Hooks {
+	return &Hooks{}
+}
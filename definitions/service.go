@@ -23,6 +23,9 @@ type Service struct {
 	VolumesFrom []string `mapstructure:"volumes_from" json:"volumes_from,omitempty" yaml:"volumes_from,omitempty" toml:"volumes_from,omitempty"`
 	// maps directly to docker environment
 	Environment []string `json:"environment,omitempty" yaml:"environment,omitempty" toml:"environment,omitempty"`
+	// names of secrets from the eris secrets store to export as environment
+	// variables ("NAME" or "NAME:VAR") or write to the data container ("NAME:path")
+	Secrets []string `mapstructure:"secrets" json:"secrets,omitempty" yaml:"secrets,omitempty" toml:"secrets,omitempty"`
 	// maps directly to docker env-file
 	EnvFile []string `mapstructure:"env_file" json:"env_file,omitempty" yaml:"env_file,omitempty" toml:"env_file,omitempty"`
 	// maps directly to docker net
@@ -47,9 +50,51 @@ type Service struct {
 	CPUShares int64 `mapstructure:"cpu_shares" json:"cpu_shares,omitempty,omitzero" yaml:"cpu_shares,omitempty" toml:"cpu_shares,omitempty,omitzero"`
 	// maps directly to docker mem_limit
 	MemLimit int64 `mapstructure:"mem_limit" json:"memory,omitempty,omitzero" yaml:"memory,omitempty" toml:"memory,omitempty,omitzero"`
+	// maps directly to docker memory-swap (memory plus swap; -1 for unlimited swap)
+	MemorySwap int64 `mapstructure:"memory_swap" json:"memory_swap,omitempty,omitzero" yaml:"memory_swap,omitempty" toml:"memory_swap,omitempty,omitzero"`
+	// maps directly to docker cpuset-cpus ("0-3" or "0,1")
+	CPUSet string `mapstructure:"cpuset" json:"cpuset,omitempty" yaml:"cpuset,omitempty" toml:"cpuset,omitempty"`
+
+	// maps directly to docker read-only
+	ReadOnly bool `mapstructure:"read_only" json:"read_only,omitempty" yaml:"read_only,omitempty" toml:"read_only,omitempty"`
+	// maps directly to docker security-opt ("seccomp=<profile>", "apparmor=<profile>", "label=<label>")
+	SecurityOpt []string `mapstructure:"security_opt" json:"security_opt,omitempty" yaml:"security_opt,omitempty" toml:"security_opt,omitempty"`
+	// prevent container processes from gaining new privileges (security-opt no-new-privileges)
+	NoNewPrivileges bool `mapstructure:"no_new_privileges" json:"no_new_privileges,omitempty" yaml:"no_new_privileges,omitempty" toml:"no_new_privileges,omitempty"`
+	// maps directly to docker ulimit ("<name>=<soft>[:<hard>]")
+	Ulimits []string `mapstructure:"ulimits" json:"ulimits,omitempty" yaml:"ulimits,omitempty" toml:"ulimits,omitempty"`
+	// maps directly to docker pids-limit
+	PidsLimit int64 `mapstructure:"pids_limit" json:"pids_limit,omitempty,omitzero" yaml:"pids_limit,omitempty" toml:"pids_limit,omitempty,omitzero"`
+	// maps directly to docker tmpfs ("<path>[:<options>]")
+	Tmpfs []string `mapstructure:"tmpfs" json:"tmpfs,omitempty" yaml:"tmpfs,omitempty" toml:"tmpfs,omitempty"`
+	// maps directly to docker userns ("host" to disable user namespace remapping)
+	UserNS string `mapstructure:"user_ns" json:"user_ns,omitempty" yaml:"user_ns,omitempty" toml:"user_ns,omitempty"`
+
+	// custom container labels ("<key>=<value>"); the eris namespace is reserved
+	Labels []string `mapstructure:"labels" json:"labels,omitempty" yaml:"labels,omitempty" toml:"labels,omitempty"`
+	// maps directly to docker add-host ("<host>:<ip>")
+	ExtraHosts []string `mapstructure:"extra_hosts" json:"extra_hosts,omitempty" yaml:"extra_hosts,omitempty" toml:"extra_hosts,omitempty"`
+	// maps directly to docker sysctl ("<name>=<value>")
+	Sysctls []string `mapstructure:"sysctls" json:"sysctls,omitempty" yaml:"sysctls,omitempty" toml:"sysctls,omitempty"`
+	// maps directly to docker device ("<host path>[:<container path>[:<permissions>]]")
+	Devices []string `mapstructure:"devices" json:"devices,omitempty" yaml:"devices,omitempty" toml:"devices,omitempty"`
+	// maps directly to docker shm-size (in bytes)
+	ShmSize int64 `mapstructure:"shm_size" json:"shm_size,omitempty,omitzero" yaml:"shm_size,omitempty" toml:"shm_size,omitempty,omitzero"`
+	// maps directly to docker stop-signal
+	StopSignal string `mapstructure:"stop_signal" json:"stop_signal,omitempty" yaml:"stop_signal,omitempty" toml:"stop_signal,omitempty"`
+	// minimal time to wait for the container to stop before killing it ("30s", "2m")
+	StopGracePeriod string `mapstructure:"stop_grace_period" json:"stop_grace_period,omitempty" yaml:"stop_grace_period,omitempty" toml:"stop_grace_period,omitempty"`
+	// maps directly to docker log-driver
+	LogDriver string `mapstructure:"log_driver" json:"log_driver,omitempty" yaml:"log_driver,omitempty" toml:"log_driver,omitempty"`
+	// maps directly to docker log-opt ("<option>=<value>")
+	LogOpts []string `mapstructure:"log_opts" json:"log_opts,omitempty" yaml:"log_opts,omitempty" toml:"log_opts,omitempty"`
+
+	// command run in the running container by [eris status] to check the
+	// service is ready; the service is ready if the command exits with 0
+	Ready string `mapstructure:"ready" json:"ready,omitempty" yaml:"ready,omitempty" toml:"ready,omitempty"`
 
 	// an env variable to set for when we are running `eris exec` so we can find the main container
-	ExecHost string `mapstructure:"exec_host" json:"memory,omitempty,omitzero" yaml:"memory,omitempty" toml:"memory,omitempty,omitzero"`
+	ExecHost string `mapstructure:"exec_host" json:"exec_host,omitempty" yaml:"exec_host,omitempty" toml:"exec_host,omitempty"`
 }
 
 func BlankService() *Service {
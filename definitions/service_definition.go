@@ -1,5 +1,8 @@
 package definitions
 
+// ServiceTypeJob is the type of one-shot job services.
+const ServiceTypeJob = "job"
+
 type ServiceDefinition struct {
 	// name of the service
 	Name string `json:"name" yaml:"name" toml:"name"`
@@ -8,14 +11,23 @@ type ServiceDefinition struct {
 	// a chain which must be started prior to this service starting. can take a `$chain` string
 	// which would then be passed in via a command line flag
 	Chain string `json:"chain,omitempty" yaml:"chain,omitempty" toml:"chain,omitempty"`
+	// "job" for one-shot services run to completion by [eris services run]
+	Type string `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	// cron expression the jobs scheduler runs the job at
+	Schedule string `json:"schedule,omitempty" yaml:"schedule,omitempty" toml:"schedule,omitempty"`
+	// catalog metadata displayed by [eris services search]
+	Description string   `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
+	Version     string   `json:"version,omitempty" yaml:"version,omitempty" toml:"version,omitempty"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
 
 	Service      *Service      `json:"service" yaml:"service" toml:"service"`
 	Dependencies *Dependencies `json:"dependencies,omitempty", yaml:"dependencies,omitempty" toml:"dependencies,omitempty"`
 	Maintainer   *Maintainer   `json:"maintainer,omitempty" yaml:"maintainer,omitempty" toml:"maintainer,omitempty"`
 	Location     *Location     `json:"location,omitempty" yaml:"location,omitempty" toml:"location,omitempty"`
 	Machine      *Machine      `json:"machine,omitempty" yaml:"machine,omitempty" toml:"machine,omitempty"`
-	Srvs         []*Service
-	Operations   *Operation
+	Hooks        *Hooks        `json:"hooks,omitempty" yaml:"hooks,omitempty" toml:"hooks,omitempty"`
+	Srvs         []*Service    `json:"-" yaml:"-" toml:"-"`
+	Operations   *Operation    `json:"-" yaml:"-" toml:"-"`
 }
 
 type Dependencies struct {
@@ -29,6 +41,7 @@ func BlankServiceDefinition() *ServiceDefinition {
 		Maintainer: BlankMaintainer(),
 		Location:   BlankLocation(),
 		Machine:    BlankMachine(),
+		Hooks:      BlankHooks(),
 		Operations: BlankOperation(),
 	}
 }
@@ -0,0 +1,123 @@
+package hooks
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// PerformAction performs the eris action (e.g. "dns register") of an action
+// hook, substituting $chain in its steps with chain and exporting env
+// to them. It is set by the actions package, which depends on services
+// and chains and thus cannot be imported here.
+var PerformAction func(action, chain string, env []string) error
+
+// Run runs the hooks of the lifecycle event of a service or a chain in
+// order. A failing hook stops the rest and its error is returned, unless
+// the hook's failure policy is "warn".
+//
+//  event - lifecycle event (def.HookPreStart, def.HookPostStart, etc.)
+//  name  - service or chain name
+//  hooks - hooks section of the definition file (can be nil)
+//
+//  srv, ops - service (or chain) and its container, used by hooks
+//             run in a throwaway container
+//
+func Run(event, name string, hooks *def.Hooks, srv *def.Service, ops *def.Operation) error {
+	for _, hook := range util.HooksFor(hooks, event) {
+		log.WithFields(log.Fields{
+			"=>":      name,
+			"event":   event,
+			"action":  hook.Action,
+			"command": hook.Command,
+		}).Info("Running hook")
+
+		err := run(event, name, hook, srv, ops)
+		if err == nil {
+			continue
+		}
+
+		err = fmt.Errorf("The %s hook of %s failed: %v", event, name, err)
+		if hook.OnFailure == def.HookWarn {
+			log.Warn(err)
+			continue
+		}
+		return err
+	}
+	return nil
+}
+
+// Env returns the environment variables hooks are given.
+func Env(event, name string, ops *def.Operation) []string {
+	return []string{
+		"ERIS_HOOK=" + event,
+		"ERIS_NAME=" + name,
+		"ERIS_TYPE=" + ops.ContainerType,
+		"ERIS_CONTAINER=" + ops.SrvContainerName,
+	}
+}
+
+func run(event, name string, hook *def.Hook, srv *def.Service, ops *def.Operation) error {
+	env := Env(event, name, ops)
+
+	switch {
+	case hook.Action != "":
+		if PerformAction == nil {
+			return fmt.Errorf("cannot perform the %q action: actions are not available", hook.Action)
+		}
+		var chain string
+		if ops.ContainerType == def.TypeChain {
+			chain = name
+		}
+		return PerformAction(hook.Action, chain, env)
+	case hook.Container:
+		return runInContainer(hook.Command, env, srv, ops)
+	default:
+		return runOnHost(hook.Command, env)
+	}
+}
+
+func runOnHost(command string, env []string) error {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+
+	out, err := cmd.CombinedOutput()
+	if output := strings.TrimSpace(string(out)); output != "" {
+		log.Warn(output)
+	}
+	if err != nil {
+		return fmt.Errorf("error running command (%v)", err)
+	}
+	return nil
+}
+
+// runInContainer runs the command in a throwaway container of the service
+// image with the service settings and its data container mounted.
+func runInContainer(command string, env []string, srv *def.Service, ops *def.Operation) error {
+	srvCopy := *srv
+	srvCopy.Environment = append(append([]string{}, srv.Environment...), env...)
+	srvCopy.Restart = ""
+
+	opsCopy := *ops
+	opsCopy.Args = []string{"sh", "-c", command}
+	opsCopy.Interactive = true
+
+	buf, err := perform.DockerExecService(&srvCopy, &opsCopy)
+	if buf != nil {
+		if output := strings.TrimSpace(buf.String()); output != "" {
+			log.Warn(output)
+		}
+	}
+	return err
+}
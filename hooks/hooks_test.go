@@ -0,0 +1,129 @@
+package hooks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/tests"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	logger "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFormatter(logger.ErisFormatter{})
+
+	log.SetLevel(log.ErrorLevel)
+	// log.SetLevel(log.InfoLevel)
+	// log.SetLevel(log.DebugLevel)
+
+	tests.IfExit(tests.TestsInit("hooks"))
+
+	exitCode := m.Run()
+	tests.IfExit(tests.TestsTearDown())
+	os.Exit(exitCode)
+}
+
+func TestRunOnHost(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatalf("expected a temp dir, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	hooks := &def.Hooks{
+		PreStart: []*def.Hook{
+			{Command: "echo $ERIS_HOOK $ERIS_NAME $ERIS_TYPE $ERIS_CONTAINER >> " + out},
+		},
+	}
+	ops := &def.Operation{ContainerType: def.TypeService, SrvContainerName: "eris_service_web_1"}
+	if err := Run(def.HookPreStart, "web", hooks, def.BlankService(), ops); err != nil {
+		t.Fatalf("expected hook to succeed, got %v", err)
+	}
+
+	contents, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("expected hook to write the file, got %v", err)
+	}
+	if expected := "pre_start web service eris_service_web_1"; strings.TrimSpace(string(contents)) != expected {
+		t.Fatalf("expected %q, got %q", expected, contents)
+	}
+
+	// Hooks of other events are not run.
+	if err := Run(def.HookPostStop, "web", hooks, def.BlankService(), ops); err != nil {
+		t.Fatalf("expected no hooks to run, got %v", err)
+	}
+}
+
+func TestRunFailurePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatalf("expected a temp dir, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	ops := &def.Operation{ContainerType: def.TypeService}
+
+	warn := &def.Hooks{
+		PreStop: []*def.Hook{
+			{Command: "exit 1", OnFailure: def.HookWarn},
+			{Command: "touch " + out},
+		},
+	}
+	if err := Run(def.HookPreStop, "web", warn, def.BlankService(), ops); err != nil {
+		t.Fatalf("expected warn policy to carry on, got %v", err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Fatalf("expected the second hook to run, got %v", err)
+	}
+	os.Remove(out)
+
+	abort := &def.Hooks{
+		PreStop: []*def.Hook{
+			{Command: "exit 1"},
+			{Command: "touch " + out},
+		},
+	}
+	if err := Run(def.HookPreStop, "web", abort, def.BlankService(), ops); err == nil {
+		t.Fatalf("expected abort policy to return an error, got nil")
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Fatalf("expected the second hook not to run")
+	}
+}
+
+func TestRunAction(t *testing.T) {
+	defer func(f func(string, string, []string) error) { PerformAction = f }(PerformAction)
+
+	var performed []string
+	PerformAction = func(action, chain string, env []string) error {
+		performed = append(performed, fmt.Sprintf("%s/%s/%s", action, chain, env[0]))
+		return nil
+	}
+
+	hooks := &def.Hooks{
+		PostStart: []*def.Hook{{Action: "dns register"}},
+	}
+	if err := Run(def.HookPostStart, "test", hooks, def.BlankService(), &def.Operation{ContainerType: def.TypeChain}); err != nil {
+		t.Fatalf("expected action hook to succeed, got %v", err)
+	}
+	if err := Run(def.HookPostStart, "web", hooks, def.BlankService(), &def.Operation{ContainerType: def.TypeService}); err != nil {
+		t.Fatalf("expected action hook to succeed, got %v", err)
+	}
+
+	if expected := "dns register/test/ERIS_HOOK=post_start,dns register//ERIS_HOOK=post_start"; strings.Join(performed, ",") != expected {
+		t.Fatalf("expected %q, got %q", expected, strings.Join(performed, ","))
+	}
+
+	PerformAction = nil
+	if err := Run(def.HookPostStart, "web", hooks, def.BlankService(), &def.Operation{}); err == nil {
+		t.Fatalf("expected an error without an action runner, got nil")
+	}
+}
@@ -96,7 +96,6 @@ func pullDefaultImages() error {
 		ver.ERIS_IMG_IPFS,
 		ver.ERIS_IMG_DB,
 		ver.ERIS_IMG_PM,
-		ver.ERIS_IMG_CM,
 	}
 
 	// Spacer.
@@ -0,0 +1,355 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/services"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// Triggers of job runs.
+const (
+	TriggerManual   = "manual"
+	TriggerSchedule = "schedule"
+)
+
+// Run is a record of a job run kept in the jobs history file.
+type Run struct {
+	Name     string    `json:"name"`
+	Trigger  string    `json:"trigger"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// exit code of the job container (-1 if it didn't run)
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	// path of the captured job output
+	Log string `json:"log"`
+}
+
+// Result returns "ok", "exit <code>", or "error".
+func (r *Run) Result() string {
+	switch {
+	case r.Error != "":
+		return "error"
+	case r.ExitCode != 0:
+		return "exit " + strconv.Itoa(r.ExitCode)
+	default:
+		return "ok"
+	}
+}
+
+// historyLock serializes history writes of concurrently scheduled jobs.
+var historyLock sync.Mutex
+
+// JobsPath returns the directory job logs and the history file are kept in.
+func JobsPath() string {
+	return filepath.Join(ErisRoot, "jobs")
+}
+
+// HistoryFile returns the path of the jobs history file.
+func HistoryFile() string {
+	return filepath.Join(JobsPath(), "history.json")
+}
+
+// RunJob starts the dependencies of a job service, runs the job container
+// to completion, and records the run in the jobs history. The job output
+// is written to the global writer and captured in the eris root.
+// RunJob returns the exit code of the job container.
+//
+//  do.Name      - name of the job service
+//  do.ChainName - chain the job connects to (overrides the definition file)
+//  do.Env       - additional environment variables for the job
+//  do.Links     - additional links for the job
+//
+func RunJob(do *definitions.Do) (int, error) {
+	run, err := runJob(do, TriggerManual, config.GlobalConfig.Writer)
+	if err != nil {
+		return -1, err
+	}
+	if run.Error != "" {
+		return run.ExitCode, errors.New(run.Error)
+	}
+	return run.ExitCode, nil
+}
+
+func runJob(do *definitions.Do, trigger string, out io.Writer) (*Run, error) {
+	group, err := services.BuildServicesGroup(do.Name)
+	if err != nil {
+		return nil, err
+	}
+	job := group[len(group)-1]
+	if job.Type != definitions.ServiceTypeJob {
+		return nil, fmt.Errorf("Service %s is not a job. Start it with [eris services start %s]", do.Name, do.Name)
+	}
+	job.Service.Environment = append(job.Service.Environment, do.Env...)
+	job.Service.Links = append(job.Service.Links, do.Links...)
+
+	group, err = services.BuildChainGroup(do.ChainName, group)
+	if err != nil {
+		return nil, err
+	}
+	if err := services.CheckMachineRequirements(group); err != nil {
+		return nil, err
+	}
+	if err := services.StartGroup(group[:len(group)-1]); err != nil {
+		return nil, err
+	}
+
+	run := &Run{
+		Name:     do.Name,
+		Trigger:  trigger,
+		Started:  time.Now(),
+		ExitCode: -1,
+	}
+	logFile, err := createLog(run)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+	if out != nil {
+		out = io.MultiWriter(out, logFile)
+	} else {
+		out = logFile
+	}
+
+	run.ExitCode, err = perform.DockerRunJob(job.Service, job.Operations, out)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	run.Finished = time.Now()
+
+	log.WithFields(log.Fields{
+		"=>":        run.Name,
+		"exit code": run.ExitCode,
+		"log":       run.Log,
+	}).Info("Recording job run")
+	return run, appendHistory(run)
+}
+
+func createLog(run *Run) (*os.File, error) {
+	dir := filepath.Join(JobsPath(), run.Name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	run.Log = filepath.Join(dir, run.Started.UTC().Format("20060102T150405Z")+".log")
+	return os.Create(run.Log)
+}
+
+func appendHistory(run *Run) error {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+
+	if err := os.MkdirAll(JobsPath(), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(HistoryFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(run)
+}
+
+// ReadHistory returns the recorded runs of the job, or of all jobs if
+// name is empty, oldest first. No runs are returned if there is no
+// history file.
+func ReadHistory(name string) ([]*Run, error) {
+	file, err := os.Open(HistoryFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var runs []*Run
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		run := &Run{}
+		if err := json.Unmarshal(scanner.Bytes(), run); err != nil {
+			return nil, fmt.Errorf("Cannot read the jobs history file %s: %v", HistoryFile(), err)
+		}
+		if name == "" || run.Name == name {
+			runs = append(runs, run)
+		}
+	}
+	return runs, scanner.Err()
+}
+
+// ListJobs displays the known job services, their schedules, when they
+// run next, and the result of their last run.
+func ListJobs(do *definitions.Do) error {
+	jobs := LoadJobs()
+	history, err := ReadHistory("")
+	if err != nil {
+		return err
+	}
+	last := make(map[string]*Run)
+	for _, run := range history {
+		last[run.Name] = run
+	}
+
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetBorder(false)
+	table.SetColumnSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"NAME", "SCHEDULE", "NEXT RUN", "LAST RUN", "RESULT"})
+
+	now := time.Now()
+	for _, job := range jobs {
+		schedule, next := "-", "-"
+		if job.Schedule != "" {
+			schedule = job.Schedule
+			if cron, err := util.ParseCron(job.Schedule); err == nil {
+				if t := cron.Next(now); !t.IsZero() {
+					next = t.Format(time.RFC822)
+				}
+			}
+		}
+		lastRun, result := "-", "-"
+		if run, ok := last[job.Name]; ok {
+			lastRun, result = run.Started.Format(time.RFC822), run.Result()
+		}
+		table.Append([]string{job.Name, schedule, next, lastRun, result})
+	}
+	table.Render()
+	return nil
+}
+
+// JobsHistory displays the recorded runs of a job, or of all jobs.
+//
+//  do.Name - name of the job service (all jobs if empty)
+//
+func JobsHistory(do *definitions.Do) error {
+	runs, err := ReadHistory(do.Name)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetBorder(false)
+	table.SetColumnSeparator(" ")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"NAME", "TRIGGER", "STARTED", "DURATION", "RESULT", "LOG"})
+	for _, run := range runs {
+		duration := run.Finished.Sub(run.Started) / time.Second * time.Second
+		table.Append([]string{run.Name, run.Trigger, run.Started.Format(time.RFC822), duration.String(), run.Result(), run.Log})
+	}
+	table.Render()
+	return nil
+}
+
+// LoadJobs returns the definitions of known job services sorted by name.
+// Definition files which fail to load are skipped.
+func LoadJobs() []*definitions.ServiceDefinition {
+	var jobs []*definitions.ServiceDefinition
+
+	names := util.GetGlobalLevelConfigFilesByType("services", false)
+	sort.Strings(names)
+	for _, name := range names {
+		srv, err := loaders.LoadServiceDefinition(name, false)
+		if err != nil {
+			log.WithField("=>", name).Warnf("Skipping service: %v", err)
+			continue
+		}
+		if srv.Type == definitions.ServiceTypeJob {
+			jobs = append(jobs, srv)
+		}
+	}
+	return jobs
+}
+
+// DueJobs returns the names of scheduled jobs which fire at the minute of t.
+func DueJobs(jobs []*definitions.ServiceDefinition, t time.Time) []string {
+	var due []string
+	for _, job := range jobs {
+		if job.Schedule == "" {
+			continue
+		}
+		cron, err := util.ParseCron(job.Schedule)
+		if err != nil {
+			log.WithField("=>", job.Name).Warn(err)
+			continue
+		}
+		if cron.Matches(t) {
+			due = append(due, job.Name)
+		}
+	}
+	return due
+}
+
+// Scheduler runs scheduled jobs when their schedules fire until the
+// process is stopped. Job definition files are reloaded every minute,
+// so changes to schedules don't require a restart. A job which is still
+// running when it is due again is skipped.
+//
+//  do.ChainName - chain the jobs connect to (overrides the definition files)
+//
+func Scheduler(do *definitions.Do) error {
+	log.Warn("Scheduler started. Press Ctrl+C to stop")
+
+	var (
+		lock    sync.Mutex
+		running = make(map[string]bool)
+	)
+	for {
+		now := time.Now()
+		tick := now.Truncate(time.Minute).Add(time.Minute)
+		time.Sleep(tick.Sub(now))
+
+		for _, name := range DueJobs(LoadJobs(), tick) {
+			lock.Lock()
+			if running[name] {
+				lock.Unlock()
+				log.WithField("=>", name).Warn("Job is still running, skipping")
+				continue
+			}
+			running[name] = true
+			lock.Unlock()
+
+			go func(name string) {
+				defer func() {
+					lock.Lock()
+					delete(running, name)
+					lock.Unlock()
+				}()
+
+				log.WithField("=>", name).Warn("Running scheduled job")
+				jobDo := definitions.NowDo()
+				jobDo.Name = name
+				jobDo.ChainName = do.ChainName
+				run, err := runJob(jobDo, TriggerSchedule, nil)
+				if err != nil {
+					log.WithField("=>", name).Errorf("Error running job: %v", err)
+					return
+				}
+				log.WithFields(log.Fields{
+					"=>":     name,
+					"result": run.Result(),
+				}).Warn("Scheduled job finished")
+			}(name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/tests"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	logger "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFormatter(logger.ErisFormatter{})
+
+	log.SetLevel(log.ErrorLevel)
+	// log.SetLevel(log.InfoLevel)
+	// log.SetLevel(log.DebugLevel)
+
+	tests.IfExit(tests.TestsInit("jobs"))
+
+	exitCode := m.Run()
+	tests.IfExit(tests.TestsTearDown())
+	os.Exit(exitCode)
+}
+
+func TestDueJobs(t *testing.T) {
+	jobs := []*def.ServiceDefinition{
+		{Name: "nightly", Schedule: "0 3 * * *"},
+		{Name: "quarterly", Schedule: "*/15 * * * *"},
+		{Name: "manual"},
+		{Name: "broken", Schedule: "every day"},
+	}
+
+	for at, due := range map[time.Time][]string{
+		time.Date(2016, time.March, 14, 3, 0, 0, 0, time.UTC):  {"nightly", "quarterly"},
+		time.Date(2016, time.March, 14, 3, 45, 0, 0, time.UTC): {"quarterly"},
+		time.Date(2016, time.March, 14, 3, 46, 0, 0, time.UTC): nil,
+	} {
+		if returned := DueJobs(jobs, at); !reflect.DeepEqual(returned, due) {
+			t.Fatalf("%v: expected %v, got %v", at, due, returned)
+		}
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	for _, test := range []struct {
+		run    *Run
+		result string
+	}{
+		{&Run{ExitCode: 0}, "ok"},
+		{&Run{ExitCode: 3}, "exit 3"},
+		{&Run{ExitCode: -1, Error: "no such image"}, "error"},
+	} {
+		if returned := test.run.Result(); returned != test.result {
+			t.Fatalf("%+v: expected %v, got %v", test.run, test.result, returned)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	defer os.RemoveAll(JobsPath())
+
+	if runs, err := ReadHistory(""); runs != nil || err != nil {
+		t.Fatalf("expected (nil, nil) without a history file, got (%v, %v)", runs, err)
+	}
+
+	started := time.Date(2016, time.March, 14, 3, 0, 0, 0, time.UTC)
+	for _, run := range []*Run{
+		{Name: "backup", Trigger: TriggerSchedule, Started: started, Finished: started.Add(time.Minute)},
+		{Name: "report", Trigger: TriggerManual, Started: started, Finished: started, ExitCode: 2},
+		{Name: "backup", Trigger: TriggerManual, Started: started.Add(time.Hour), Finished: started.Add(time.Hour), ExitCode: 1},
+	} {
+		if err := appendHistory(run); err != nil {
+			t.Fatalf("expected run to be recorded, got %v", err)
+		}
+	}
+
+	runs, err := ReadHistory("")
+	if err != nil {
+		t.Fatalf("expected history to be read, got %v", err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 runs, got %v", len(runs))
+	}
+
+	runs, err = ReadHistory("backup")
+	if err != nil {
+		t.Fatalf("expected history to be read, got %v", err)
+	}
+	if len(runs) != 2 || runs[0].Trigger != TriggerSchedule || runs[1].ExitCode != 1 || !runs[0].Started.Equal(started) {
+		t.Fatalf("expected 2 backup runs in order, got %+v", runs)
+	}
+}
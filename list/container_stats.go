@@ -99,22 +99,35 @@ func AssembleTable(typ string) ([]Parts, error) {
 	}
 
 	var myTable []Parts
-	addedAlready := make(map[string]bool)
+	rows := make(map[string]int)       // short name -> table row
+	containers := make(map[string]int) // short name -> number of containers
+	seen := make(map[string]bool)
+
+	// Running containers come first. Chain networks have several
+	// containers (one for each node), but are listed once.
+	for i, name := range append(contsR, contsE...) {
+		if seen[name.FullName] {
+			continue
+		}
+		seen[name.FullName] = true
+		containers[name.ShortName]++
+		if _, ok := rows[name.ShortName]; ok {
+			continue
+		}
 
-	for _, name := range contsR {
 		part, _ := makePartFromContainer(name.FullName)
-		addedAlready[part.ShortName] = true //has to come after because short name needed
-		part.Running = true
+		part.Running = i < len(contsR)
+		rows[name.ShortName] = len(myTable)
 		myTable = append(myTable, part)
 	}
 
-	for _, name := range contsE {
-		part, _ := makePartFromContainer(name.FullName)
-		if addedAlready[part.ShortName] == true {
-			continue
-		} else {
-			part.Running = false
-			myTable = append(myTable, part)
+	unit := "containers"
+	if typ == "chain" {
+		unit = "nodes"
+	}
+	for name, row := range rows {
+		if containers[name] > 1 {
+			myTable[row].FullName = fmt.Sprintf("%s (%d %s)", myTable[row].FullName, containers[name], unit)
 		}
 	}
 	return myTable, nil
@@ -48,6 +48,18 @@ func LoadChainDefinition(chainName string, newCont bool) (*definitions.Chain, er
 		return nil, err
 	}
 
+	if err = util.CheckSecurityOptions(chain.Service); err != nil {
+		return nil, err
+	}
+
+	if err = util.CheckDockerOptions(chain.Service); err != nil {
+		return nil, err
+	}
+
+	if err = util.CheckHooks(chain.Hooks); err != nil {
+		return nil, err
+	}
+
 	// Docker 1.6 (which eris doesn't support) had different linking mechanism.
 	if util.IsMinimalDockerClientVersion() {
 		if chain.Dependencies != nil {
@@ -65,6 +77,33 @@ func LoadChainDefinition(chainName string, newCont bool) (*definitions.Chain, er
 	return chain, nil
 }
 
+// ReadChainDefinition reads the chainName chain definition file as is,
+// without merging in the default chain settings. It is meant for displaying
+// or rewriting definition files rather than running them.
+func ReadChainDefinition(chainName string) (*definitions.Chain, error) {
+	chainConf, err := config.LoadViperConfig(filepath.Join(ChainsPath), chainName, "chain")
+	if err != nil {
+		return nil, err
+	}
+
+	chain := definitions.BlankChain()
+	if err := chainConf.Unmarshal(chain); err != nil {
+		return nil, fmt.Errorf("The marmots coult not marshal from viper to chain def: %v", err)
+	}
+
+	// toml bools don't really marshal well
+	for _, s := range []string{"", "service."} {
+		if chainConf.GetBool(s + "data_container") {
+			chain.Service.AutoData = true
+		}
+	}
+
+	if chain.Name == "" {
+		chain.Name = chainName
+	}
+	return chain, nil
+}
+
 // Convert the chain def to a service def but keep the "eris_chains" containers prefix and set the chain id
 func ChainsAsAService(chainName string, newCont bool) (*definitions.ServiceDefinition, error) {
 	chain, err := LoadChainDefinition(chainName, newCont)
@@ -99,6 +138,7 @@ func ServiceDefFromChain(chain *definitions.Chain, cmd string) *definitions.Serv
 		Maintainer:   chain.Maintainer,
 		Location:     chain.Location,
 		Machine:      chain.Machine,
+		Hooks:        chain.Hooks,
 	}
 	ServiceFinalizeLoad(srv) // these are mostly operational considerations that we want to ensure are met
 
@@ -135,6 +175,18 @@ func MarshalChainDefinition(chainConf *viper.Viper, chain *definitions.Chain) er
 
 	util.Merge(chain.Service, chnTemp.Service)
 	chain.ChainID = chnTemp.ChainID
+	chain.ChainType = chnTemp.ChainType
+	chain.PreviousImage = chnTemp.PreviousImage
+	chain.PreviousImageDigest = chnTemp.PreviousImageDigest
+	if chain.ChainType == definitions.ChainTypeThrowaway {
+		chain.Operations.Labels = util.SetLabel(chain.Operations.Labels, definitions.LabelThrowaway, "true")
+	}
+	if chnTemp.Machine != nil && len(chnTemp.Machine.Requires) != 0 {
+		chain.Machine = chnTemp.Machine
+	}
+	if chnTemp.Hooks != nil {
+		chain.Hooks = chnTemp.Hooks
+	}
 
 	// toml bools don't really marshal well
 	// data_container can be in the chain or
@@ -146,6 +198,12 @@ func MarshalChainDefinition(chainConf *viper.Viper, chain *definitions.Chain) er
 			log.WithField("autodata", chain.Service.AutoData).Debug()
 		}
 	}
+	if chainConf.GetBool("service.read_only") {
+		chain.Service.ReadOnly = true
+	}
+	if chainConf.GetBool("service.no_new_privileges") {
+		chain.Service.NoNewPrivileges = true
+	}
 
 	return nil
 }
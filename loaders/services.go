@@ -39,6 +39,22 @@ func LoadServiceDefinition(servName string, newCont bool) (*definitions.ServiceD
 		return nil, err
 	}
 
+	if err = util.CheckSecurityOptions(srv.Service); err != nil {
+		return nil, err
+	}
+
+	if err = util.CheckDockerOptions(srv.Service); err != nil {
+		return nil, err
+	}
+
+	if err = util.CheckHooks(srv.Hooks); err != nil {
+		return nil, err
+	}
+
+	if err = checkJob(srv); err != nil {
+		return nil, err
+	}
+
 	// Docker 1.6 (which eris doesn't support) had different linking mechanism.
 	if util.IsMinimalDockerClientVersion() {
 		addDependencyVolumesAndLinks(srv.Dependencies, srv.Service, srv.Operations)
@@ -48,6 +64,37 @@ func LoadServiceDefinition(servName string, newCont bool) (*definitions.ServiceD
 	return srv, nil
 }
 
+// ReadServiceDefinition reads the servName service definition file as is,
+// without linking dependencies or looking up containers. It is meant for
+// displaying or rewriting definition files rather than running them.
+func ReadServiceDefinition(servName string) (*definitions.ServiceDefinition, error) {
+	srv := definitions.BlankServiceDefinition()
+	serviceConf, err := loadServiceDefinition(servName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = MarshalServiceDefinition(serviceConf, srv); err != nil {
+		return nil, err
+	}
+
+	if srv.Service == nil {
+		return nil, fmt.Errorf("No service given.")
+	}
+
+	// harmonize names
+	if srv.Name == "" {
+		srv.Name = srv.Service.Name
+	}
+	if srv.Name == "" {
+		srv.Name = servName
+	}
+	if srv.Service.Name == "" {
+		srv.Service.Name = srv.Name
+	}
+	return srv, nil
+}
+
 func MockServiceDefinition(servName string, newCont bool) *definitions.ServiceDefinition {
 	srv := definitions.BlankServiceDefinition()
 	srv.Name = servName
@@ -72,6 +119,12 @@ func MarshalServiceDefinition(serviceConf *viper.Viper, srv *definitions.Service
 	if serviceConf.GetBool("service.data_container") {
 		srv.Service.AutoData = true
 	}
+	if serviceConf.GetBool("service.read_only") {
+		srv.Service.ReadOnly = true
+	}
+	if serviceConf.GetBool("service.no_new_privileges") {
+		srv.Service.NoNewPrivileges = true
+	}
 
 	return nil
 }
@@ -163,6 +216,24 @@ func checkImage(srv *definitions.Service) error {
 	return nil
 }
 
+// Only jobs can be scheduled, and the schedule has to be a valid cron expression.
+func checkJob(srv *definitions.ServiceDefinition) error {
+	switch srv.Type {
+	case "", definitions.TypeService, definitions.ServiceTypeJob:
+	default:
+		return fmt.Errorf("Unknown service type %q. It can be either %q or %q", srv.Type, definitions.TypeService, definitions.ServiceTypeJob)
+	}
+
+	if srv.Schedule == "" {
+		return nil
+	}
+	if srv.Type != definitions.ServiceTypeJob {
+		return fmt.Errorf("Only jobs can have a schedule. Please add type = %q to the service definition file", definitions.ServiceTypeJob)
+	}
+	_, err := util.ParseCron(srv.Schedule)
+	return err
+}
+
 func addDependencyVolumesAndLinks(deps *definitions.Dependencies, srv *definitions.Service, ops *definitions.Operation) {
 	if deps != nil {
 		for i, dep := range deps.Services {
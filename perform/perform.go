@@ -1,6 +1,7 @@
 package perform
 
 import (
+	"archive/tar"
 	"bytes"
 	"errors"
 	"fmt"
@@ -10,11 +11,14 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/eris-ltd/eris-cli/config"
 	def "github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/secrets"
 	"github.com/eris-ltd/eris-cli/util"
 	ver "github.com/eris-ltd/eris-cli/version"
 
@@ -27,6 +31,13 @@ import (
 
 var (
 	ErrContainerExists = errors.New("container exists")
+
+	// ReadyTimeout is how long DockerRestart waits for a container to become ready.
+	ReadyTimeout = 30 * time.Second
+
+	// A container is considered ready if it's been up for that long.
+	readySettle = 2 * time.Second
+	readyPoll   = 500 * time.Millisecond
 )
 
 // DockerCreateData creates a blank data container. It returns ErrContainerExists
@@ -177,6 +188,9 @@ func DockerExecData(ops *def.Operation, service *def.Service) (buf *bytes.Buffer
 //  srv.AutoData          - if true, create or use existing data container
 //  srv.Restart           - container restart policy ("always", "max:<#attempts>"
 //                          or never if unspecified)
+//  srv.Secrets           - secrets to resolve from the secrets store and
+//                          export as environment variables or write to
+//                          the data container
 //
 //  ops.SrvContainerName  - service or a chain container name
 //  ops.DataContainerName - dependent data container name
@@ -202,8 +216,12 @@ func DockerRunService(srv *def.Service, ops *def.Operation) error {
 
 	optsServ := configureServiceContainer(srv, ops)
 
+	secretFiles, err := configureSecrets(srv, &optsServ)
+	if err != nil {
+		return err
+	}
+
 	// Fix volume paths.
-	var err error
 	srv.Volumes, err = util.FixDirs(srv.Volumes)
 	if err != nil {
 		return err
@@ -228,6 +246,10 @@ func DockerRunService(srv *def.Service, ops *def.Operation) error {
 		}
 	}
 
+	if err := uploadSecrets(srv, ops, secretFiles); err != nil {
+		return err
+	}
+
 	// Check existence || create the container.
 	if _, exists := ContainerExists(ops); exists {
 		log.Debug("Container already exists. Not creating")
@@ -247,7 +269,7 @@ func DockerRunService(srv *def.Service, ops *def.Operation) error {
 		"entrypoint":      optsServ.Config.Entrypoint,
 		"cmd":             optsServ.Config.Cmd,
 		"published ports": optsServ.HostConfig.PublishAllPorts,
-		"environment":     optsServ.Config.Env,
+		"environment":     secrets.Redact(optsServ.Config.Env, secrets.EnvNames(srv.Secrets)),
 		"image":           optsServ.Config.Image,
 	}).Info("Starting container")
 	if err := startContainer(optsServ); err != nil {
@@ -266,6 +288,67 @@ func DockerRunService(srv *def.Service, ops *def.Operation) error {
 	return nil
 }
 
+// DockerRunJob runs the container of a one-shot job to completion, writing
+// its output to out, and removes it afterwards (the data container is
+// kept). A container left over from an interrupted run is replaced.
+// DockerRunJob returns the exit code of the container.
+//
+// See parameter description for DockerRunService.
+func DockerRunJob(srv *def.Service, ops *def.Operation, out io.Writer) (int, error) {
+	log.WithField("=>", ops.SrvContainerName).Info("Running job")
+
+	if _, running := ContainerRunning(ops); running {
+		return -1, fmt.Errorf("Job %s is already running", srv.Name)
+	}
+	if _, exists := ContainerExists(ops); exists {
+		log.WithField("=>", ops.SrvContainerName).Info("Removing container of the previous run")
+		if err := removeContainer(ops.SrvContainerName, false, true); err != nil {
+			return -1, err
+		}
+	}
+
+	// Jobs are never restarted.
+	job := *srv
+	job.Restart = ""
+	jobOps := *ops
+	jobOps.Remove = false
+	if err := DockerRunService(&job, &jobOps); err != nil {
+		return -1, err
+	}
+
+	defer func() {
+		log.WithField("=>", ops.SrvContainerName).Info("Removing job container")
+		if err := removeContainer(ops.SrvContainerName, false, true); err != nil {
+			log.WithField("=>", ops.SrvContainerName).Errorf("Error removing job container: %v", err)
+		}
+	}()
+
+	// Following the logs returns when the container exits.
+	if err := util.DockerClient.Logs(docker.LogsOptions{
+		Container:    ops.SrvContainerName,
+		OutputStream: out,
+		ErrorStream:  out,
+		Follow:       true,
+		Stdout:       true,
+		Stderr:       true,
+		RawTerminal:  true,
+	}); err != nil {
+		return -1, err
+	}
+
+	log.WithField("=>", ops.SrvContainerName).Info("Waiting for job to exit")
+	exitCode, err := util.DockerClient.WaitContainer(ops.SrvContainerName)
+	if err != nil {
+		return -1, err
+	}
+
+	log.WithFields(log.Fields{
+		"=>":        ops.SrvContainerName,
+		"exit code": exitCode,
+	}).Info("Job finished")
+	return exitCode, nil
+}
+
 // DockerExecService creates and runs a chain or a service container interactively.
 //
 //  ops.Args         - command line parameters
@@ -278,6 +361,11 @@ func DockerExecService(srv *def.Service, ops *def.Operation) (buf *bytes.Buffer,
 
 	optsServ := configureInteractiveContainer(srv, ops)
 
+	secretFiles, err := configureSecrets(srv, &optsServ)
+	if err != nil {
+		return nil, err
+	}
+
 	// Fix volume paths.
 	srv.Volumes, err = util.FixDirs(srv.Volumes)
 	if err != nil {
@@ -305,6 +393,10 @@ func DockerExecService(srv *def.Service, ops *def.Operation) (buf *bytes.Buffer,
 		}
 	}
 
+	if err := uploadSecrets(srv, ops, secretFiles); err != nil {
+		return nil, err
+	}
+
 	log.WithField("image", srv.Image).Debug("Container does not exist. Creating")
 	_, err = createContainer(optsServ)
 	if err != nil {
@@ -338,7 +430,7 @@ func DockerExecService(srv *def.Service, ops *def.Operation) (buf *bytes.Buffer,
 		"workdir":         optsServ.Config.WorkingDir,
 		"cmd":             optsServ.Config.Cmd,
 		"ports published": optsServ.HostConfig.PublishAllPorts,
-		"environment":     optsServ.Config.Env,
+		"environment":     secrets.Redact(optsServ.Config.Env, secrets.EnvNames(srv.Secrets)),
 		"image":           optsServ.Config.Image,
 		"user":            optsServ.Config.User,
 		"vols":            optsServ.HostConfig.Binds,
@@ -394,11 +486,20 @@ func DockerRebuild(srv *def.Service, ops *def.Operation, pullImage bool, timeout
 	}
 
 	opts := configureServiceContainer(srv, ops)
-	var err error
+	secretFiles, err := configureSecrets(srv, &opts)
+	if err != nil {
+		return err
+	}
 	srv.Volumes, err = util.FixDirs(srv.Volumes)
 	if err != nil {
 		return err
 	}
+	if srv.AutoData {
+		opts.HostConfig.VolumesFrom = append(opts.HostConfig.VolumesFrom, ops.DataContainerName)
+	}
+	if err := uploadSecrets(srv, ops, secretFiles); err != nil {
+		return err
+	}
 
 	log.WithField("=>", ops.SrvContainerName).Info("Recreating container")
 	_, err = createContainer(opts)
@@ -419,6 +520,141 @@ func DockerRebuild(srv *def.Service, ops *def.Operation, pullImage bool, timeout
 	return nil
 }
 
+// Drift is a difference between the container configuration a service
+// or a chain definition produces and the configuration of the live container.
+type Drift struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+// DockerDiff compares the container configuration srv and ops produce
+// with the configuration of the existing ops.SrvContainerName container,
+// field by field. It returns nil if the container hasn't drifted.
+// Environment variables coming from secrets are redacted in the result.
+//
+// See parameter description for DockerRunService.
+func DockerDiff(srv *def.Service, ops *def.Operation) ([]Drift, error) {
+	log.WithField("=>", ops.SrvContainerName).Info("Comparing container with its definition")
+
+	container, err := util.DockerClient.InspectContainer(ops.SrvContainerName)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot inspect %s: %v", ops.SrvContainerName, err)
+	}
+	if container.Config == nil {
+		container.Config = &docker.Config{}
+	}
+	if container.HostConfig == nil {
+		container.HostConfig = &docker.HostConfig{}
+	}
+
+	opts := configureServiceContainer(srv, ops)
+	if _, err := configureSecrets(srv, &opts); err != nil {
+		return nil, err
+	}
+	binds, err := util.FixDirs(append([]string{}, opts.HostConfig.Binds...))
+	if err != nil {
+		return nil, err
+	}
+	volumesFrom := opts.HostConfig.VolumesFrom
+	if srv.AutoData {
+		volumesFrom = append(volumesFrom, ops.DataContainerName)
+	}
+
+	// Variables set by the image itself are not drift.
+	var imageEnv []string
+	if image, err := util.DockerClient.InspectImage(container.Image); err == nil && image.Config != nil {
+		imageEnv = image.Config.Env
+	}
+
+	var drift []Drift
+	compare := func(field string, expected, actual interface{}) {
+		e, a := fmt.Sprint(expected), fmt.Sprint(actual)
+		if e != a {
+			drift = append(drift, Drift{Field: field, Expected: e, Actual: a})
+		}
+	}
+
+	compare("image", opts.Config.Image, container.Config.Image)
+	if opts.Config.Entrypoint != nil {
+		compare("entrypoint", opts.Config.Entrypoint, container.Config.Entrypoint)
+	}
+	if opts.Config.Cmd != nil {
+		compare("command", opts.Config.Cmd, container.Config.Cmd)
+	}
+	if opts.Config.WorkingDir != "" {
+		compare("workdir", opts.Config.WorkingDir, container.Config.WorkingDir)
+	}
+	if opts.Config.User != "" {
+		compare("user", opts.Config.User, container.Config.User)
+	}
+	if opts.Config.Hostname != "" {
+		compare("hostname", opts.Config.Hostname, container.Config.Hostname)
+	}
+
+	names := secrets.EnvNames(srv.Secrets)
+	compare("environment",
+		secrets.Redact(sorted(opts.Config.Env), names),
+		secrets.Redact(sorted(subtract(container.Config.Env, subtract(imageEnv, opts.Config.Env))), names))
+
+	compare("ports", portBindings(opts.HostConfig.PortBindings), portBindings(container.HostConfig.PortBindings))
+	compare("publish_all", opts.HostConfig.PublishAllPorts, container.HostConfig.PublishAllPorts)
+	compare("volumes", sorted(binds), sorted(container.HostConfig.Binds))
+	compare("volumes_from", sorted(volumesFrom), sorted(container.HostConfig.VolumesFrom))
+	compare("links", sorted(opts.HostConfig.Links), sorted(normalizeLinks(container.HostConfig.Links)))
+	compare("dns", sorted(opts.HostConfig.DNS), sorted(container.HostConfig.DNS))
+	compare("dns_search", sorted(opts.HostConfig.DNSSearch), sorted(container.HostConfig.DNSSearch))
+	compare("privileged", opts.HostConfig.Privileged, container.HostConfig.Privileged)
+	compare("cap_add", sorted(opts.HostConfig.CapAdd), sorted(container.HostConfig.CapAdd))
+	compare("cap_drop", sorted(opts.HostConfig.CapDrop), sorted(container.HostConfig.CapDrop))
+	compare("restart", restartPolicy(opts.HostConfig.RestartPolicy), restartPolicy(container.HostConfig.RestartPolicy))
+	compare("read_only", opts.HostConfig.ReadonlyRootfs, container.HostConfig.ReadonlyRootfs)
+	compare("extra_hosts", sorted(opts.HostConfig.ExtraHosts), sorted(container.HostConfig.ExtraHosts))
+	compare("sysctls", opts.HostConfig.Sysctls, container.HostConfig.Sysctls)
+	compare("devices", opts.HostConfig.Devices, container.HostConfig.Devices)
+	if opts.HostConfig.ShmSize != 0 {
+		compare("shm_size", opts.HostConfig.ShmSize, container.HostConfig.ShmSize)
+	}
+	if opts.Config.StopSignal != "" {
+		compare("stop_signal", opts.Config.StopSignal, container.Config.StopSignal)
+	}
+	if opts.HostConfig.LogConfig.Type != "" {
+		compare("log_driver", opts.HostConfig.LogConfig.Type, container.HostConfig.LogConfig.Type)
+	}
+	if opts.HostConfig.LogConfig.Config != nil {
+		compare("log_opts", opts.HostConfig.LogConfig.Config, container.HostConfig.LogConfig.Config)
+	}
+	var labels []string
+	for label := range opts.Config.Labels {
+		if !strings.HasPrefix(label, def.Namespace+":") {
+			labels = append(labels, label)
+		}
+	}
+	for _, label := range sorted(labels) {
+		compare("label "+label, opts.Config.Labels[label], container.Config.Labels[label])
+	}
+	compare("security_opt", sorted(opts.HostConfig.SecurityOpt), sorted(container.HostConfig.SecurityOpt))
+	compare("tmpfs", opts.HostConfig.Tmpfs, container.HostConfig.Tmpfs)
+	compare("user_ns", opts.HostConfig.UsernsMode, container.HostConfig.UsernsMode)
+	if opts.HostConfig.PidsLimit != 0 {
+		compare("pids_limit", opts.HostConfig.PidsLimit, container.HostConfig.PidsLimit)
+	}
+	if opts.HostConfig.MemorySwap != 0 {
+		compare("memory_swap", opts.HostConfig.MemorySwap, container.HostConfig.MemorySwap)
+	}
+	if opts.HostConfig.CPUSetCPUs != "" {
+		compare("cpuset", opts.HostConfig.CPUSetCPUs, container.HostConfig.CPUSetCPUs)
+	}
+	if opts.Config.Memory != 0 {
+		compare("memory", opts.Config.Memory, container.Config.Memory+container.HostConfig.Memory)
+	}
+	if opts.Config.CPUShares != 0 {
+		compare("cpu_shares", opts.Config.CPUShares, container.Config.CPUShares+container.HostConfig.CPUShares)
+	}
+
+	return drift, nil
+}
+
 // DockerPull pulls the image for the container specified in srv.Image.
 // DockerPull returns Docker errors on exit if not successful.
 //
@@ -466,6 +702,25 @@ func DockerPull(srv *def.Service, ops *def.Operation) error {
 	return nil
 }
 
+// DockerInspectImage returns the metadata of the image, pulling the
+// image first if it cannot be found locally.
+func DockerInspectImage(name string) (*docker.Image, error) {
+	image, err := util.DockerClient.InspectImage(name)
+	if err != docker.ErrNoSuchImage {
+		return image, err
+	}
+
+	log.WithField("image", name).Warn("The Docker image is not found locally. Pulling")
+	writer := ioutil.Discard
+	if log.GetLevel() > 0 {
+		writer = os.Stdout
+	}
+	if err := pullImage(name, writer); err != nil {
+		return nil, err
+	}
+	return util.DockerClient.InspectImage(name)
+}
+
 // DockerLogs displays tail number of lines of container ops.SrvContainerName
 // output. If follow is true, it behaves like `tail -f`. It returns Docker
 // errors on exit if not successful.
@@ -515,6 +770,7 @@ func DockerStop(srv *def.Service, ops *def.Operation, timeout uint) error {
 		log.WithField("=>", srv.Name).Warn("Stopping (may take a few seconds)")
 	}
 
+	timeout = stopTimeout(srv, timeout)
 	log.WithFields(log.Fields{
 		"=>":      ops.SrvContainerName,
 		"timeout": timeout,
@@ -537,15 +793,97 @@ func DockerStop(srv *def.Service, ops *def.Operation, timeout uint) error {
 	return nil
 }
 
+// DockerWaitReady waits for the container to be up and stay up for
+// a couple of seconds, which catches containers crashing at boot.
+// It returns an error if the container exits or if timeout expires.
+//
+//  ops.SrvContainerName  - service or a chain container name to wait for
+//
+func DockerWaitReady(ops *def.Operation, timeout time.Duration) error {
+	log.WithField("=>", ops.SrvContainerName).Info("Waiting for container to become ready")
+
+	var upSince time.Time
+	for deadline := time.Now().Add(timeout); ; time.Sleep(readyPoll) {
+		cont, err := util.DockerClient.InspectContainer(ops.SrvContainerName)
+		if err != nil {
+			return err
+		}
+
+		state := cont.State
+		switch {
+		case state.Running && !state.Restarting:
+			// Measure uptime with the local clock; Docker host clock may differ.
+			if upSince.IsZero() {
+				upSince = time.Now()
+			}
+			if time.Since(upSince) >= readySettle {
+				log.WithField("=>", ops.SrvContainerName).Info("Container ready")
+				return nil
+			}
+		case !state.Running && !state.Restarting && !state.FinishedAt.Before(state.StartedAt):
+			return fmt.Errorf("Container %s exited with code %d", ops.SrvContainerName, state.ExitCode)
+		default:
+			upSince = time.Time{}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Container %s is not ready after %v", ops.SrvContainerName, timeout)
+		}
+	}
+}
+
+// DockerCheckReady runs the readiness check command of a service (with
+// sh -c) in its running container. It returns an error with the output
+// of the command if the command exits with a non-zero code.
+//
+//  ops.SrvContainerName  - service or a chain container name to check
+//
+func DockerCheckReady(ops *def.Operation, command string) error {
+	log.WithFields(log.Fields{
+		"=>":      ops.SrvContainerName,
+		"command": command,
+	}).Debug("Running readiness check")
+
+	exec, err := util.DockerClient.CreateExec(docker.CreateExecOptions{
+		Container:    ops.SrvContainerName,
+		Cmd:          []string{"sh", "-c", command},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	output := new(bytes.Buffer)
+	if err := util.DockerClient.StartExec(exec.ID, docker.StartExecOptions{
+		OutputStream: output,
+		ErrorStream:  output,
+	}); err != nil {
+		return err
+	}
+
+	inspect, err := util.DockerClient.InspectExec(exec.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.ExitCode != 0 {
+		if out := strings.TrimSpace(output.String()); out != "" {
+			return fmt.Errorf("readiness check exited with code %d: %s", inspect.ExitCode, out)
+		}
+		return fmt.Errorf("readiness check exited with code %d", inspect.ExitCode)
+	}
+	return nil
+}
+
 // DockerRename renames the container by removing and recreating it. The container
 // is also restarted if it was running before rename. The container ops.SrvContainerName
 // is renamed to a new name, constructed using a short given newName.
 // DockerRename returns Docker errors on exit or ErrContainerExists
-// if the container with the new (long) name exists.
+// if the container with the new (long) name exists. The labels of the
+// container are kept, except for the name label which is changed.
 //
 //  ops.SrvContainerName  - container name
 //  ops.ContainerType     - container type
-//  ops.Labels            - container creation time labels
 //
 func DockerRename(ops *def.Operation, newName string) error {
 	longNewName := util.ContainersName(ops.ContainerType, newName)
@@ -609,7 +947,7 @@ func DockerRename(ops *def.Operation, newName string) error {
 	createOpts.HostConfig.VolumesFrom = newVolumesFrom
 
 	// Rename labels.
-	createOpts.Config.Labels = util.Labels(newName, ops)
+	createOpts.Config.Labels = util.SetLabel(container.Config.Labels, def.LabelShortName, newName)
 
 	newContainer, err := util.DockerClient.CreateContainer(createOpts)
 	if err != nil {
@@ -679,26 +1017,34 @@ func DataContainerExists(ops *def.Operation) (docker.APIContainers, bool) {
 // ---------------------    Images Core    ------------------------------------
 // ----------------------------------------------------------------------------
 func pullImage(name string, writer io.Writer) error {
-	var tag string = "latest"
 	var reg string = ""
 
-	nameSplit := strings.Split(name, ":")
-	if len(nameSplit) == 2 {
-		tag = nameSplit[1]
-	}
-	if len(nameSplit) == 3 {
-		tag = nameSplit[2]
-	}
-	name = nameSplit[0]
-
-	repoSplit := strings.Split(nameSplit[0], "/")
+	repo, tag := util.SplitImage(name)
+	repoSplit := strings.Split(repo, "/")
 	if len(repoSplit) > 2 {
 		reg = repoSplit[0]
 	}
 
+	// Images in the lock file are pulled by their digest
+	// and tagged afterwards, so the tag cannot drift.
+	var locked *util.LockedImage
+	if !util.IsPinnedImage(name) && !util.DriftAllowed() {
+		lock, err := util.ReadImageLock()
+		if err != nil {
+			return err
+		}
+		if locked = lock.Locked(name); locked != nil && locked.Digest != "" {
+			log.WithFields(log.Fields{
+				"image":  name,
+				"digest": locked.Digest,
+			}).Info("Pulling locked image")
+			_, tag = util.SplitImage(locked.Digest)
+		}
+	}
+
 	r, w := io.Pipe()
 	opts := docker.PullImageOptions{
-		Repository:    name,
+		Repository:    repo,
 		Registry:      reg,
 		Tag:           tag,
 		OutputStream:  w,
@@ -725,13 +1071,23 @@ func pullImage(name string, writer io.Writer) error {
 		return err
 	}
 
-	return nil
+	if locked != nil && locked.Digest != "" {
+		_, nameTag := util.SplitImage(name)
+		if err := util.DockerClient.TagImage(repo+"@"+tag, docker.TagImageOptions{Repo: repo, Tag: nameTag, Force: true}); err != nil {
+			return err
+		}
+	}
+	return util.CheckImageLock(name)
 }
 
 // ----------------------------------------------------------------------------
 // ---------------------    Container Core ------------------------------------
 // ----------------------------------------------------------------------------
 func createContainer(opts docker.CreateContainerOptions) (*docker.Container, error) {
+	if err := util.CheckImageLock(opts.Config.Image); err != nil {
+		return nil, err
+	}
+
 	dockerContainer, err := util.DockerClient.CreateContainer(opts)
 	if err != nil {
 		if err == docker.ErrNoSuchImage {
@@ -898,6 +1254,15 @@ func inspectContainer(id, field string) error {
 	return nil
 }
 
+// stopTimeout extends the timeout to the stop_grace_period of the service
+// definition. A zero timeout (--force) is kept as is.
+func stopTimeout(srv *def.Service, timeout uint) uint {
+	if grace := util.StopGracePeriod(srv); timeout != 0 && grace > timeout {
+		return grace
+	}
+	return timeout
+}
+
 func stopContainer(id string, timeout uint) error {
 	err := util.DockerClient.StopContainer(id, timeout)
 	if err != nil {
@@ -970,6 +1335,66 @@ func configureInteractiveContainer(srv *def.Service, ops *def.Operation) docker.
 	return opts
 }
 
+// configureDockerOptions maps the custom labels, extra hosts, sysctls,
+// devices, stop, and logging options of the service definition (validated
+// by the loaders) onto the container options. Custom labels never
+// override eris labels.
+func configureDockerOptions(srv *def.Service, opts docker.CreateContainerOptions) {
+	if labels, _ := util.ParseKeyValues("label", srv.Labels); len(labels) != 0 {
+		for label, value := range opts.Config.Labels {
+			labels[label] = value
+		}
+		opts.Config.Labels = labels
+	}
+
+	opts.HostConfig.ExtraHosts = srv.ExtraHosts
+	opts.HostConfig.Sysctls, _ = util.ParseKeyValues("sysctl", srv.Sysctls)
+	for _, d := range srv.Devices {
+		if device, err := util.ParseDevice(d); err == nil {
+			opts.HostConfig.Devices = append(opts.HostConfig.Devices, device)
+		}
+	}
+	opts.HostConfig.ShmSize = srv.ShmSize
+
+	opts.Config.StopSignal = srv.StopSignal
+
+	if srv.LogDriver != "" || len(srv.LogOpts) != 0 {
+		opts.HostConfig.LogConfig.Type = srv.LogDriver
+		opts.HostConfig.LogConfig.Config, _ = util.ParseKeyValues("log_opts entry", srv.LogOpts)
+	}
+}
+
+// configureSecurityOptions maps the security options of the service
+// definition (validated by the loaders) onto the host config. With the
+// "hardened" SecurityProfile in eris.toml all capabilities are dropped
+// except those added explicitly.
+func configureSecurityOptions(srv *def.Service, hostConfig *docker.HostConfig) {
+	hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, srv.SecurityOpt...)
+	if srv.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, util.NoNewPrivileges)
+	}
+
+	for _, u := range srv.Ulimits {
+		if ulimit, err := util.ParseUlimit(u); err == nil {
+			hostConfig.Ulimits = append(hostConfig.Ulimits, ulimit)
+		}
+	}
+
+	if len(srv.Tmpfs) != 0 {
+		hostConfig.Tmpfs = make(map[string]string)
+		for _, t := range srv.Tmpfs {
+			if mount, options, err := util.ParseTmpfs(t); err == nil {
+				hostConfig.Tmpfs[mount] = options
+			}
+		}
+	}
+
+	if util.IsHardened() {
+		log.WithField("cap add", hostConfig.CapAdd).Debug("Dropping all capabilities (hardened profile)")
+		hostConfig.CapDrop = []string{"ALL"}
+	}
+}
+
 func configureServiceContainer(srv *def.Service, ops *def.Operation) docker.CreateContainerOptions {
 
 	opts := docker.CreateContainerOptions{
@@ -995,7 +1420,11 @@ func configureServiceContainer(srv *def.Service, ops *def.Operation) docker.Crea
 			Links:           srv.Links,
 			PublishAllPorts: ops.PublishAllPorts,
 			Privileged:      ops.Privileged,
-			ReadonlyRootfs:  false,
+			ReadonlyRootfs:  srv.ReadOnly,
+			MemorySwap:      srv.MemorySwap,
+			CPUSetCPUs:      srv.CPUSet,
+			PidsLimit:       srv.PidsLimit,
+			UsernsMode:      srv.UserNS,
 			DNS:             srv.DNS,
 			DNSSearch:       srv.DNSSearch,
 			VolumesFrom:     srv.VolumesFrom,
@@ -1028,6 +1457,9 @@ func configureServiceContainer(srv *def.Service, ops *def.Operation) docker.Crea
 		opts.HostConfig.RestartPolicy = docker.RestartOnFailure(times)
 	}
 
+	configureSecurityOptions(srv, opts.HostConfig)
+	configureDockerOptions(srv, opts)
+
 	opts.Config.ExposedPorts = make(map[docker.Port]struct{})
 	opts.HostConfig.PortBindings = make(map[docker.Port][]docker.PortBinding)
 	opts.Config.Volumes = make(map[string]struct{})
@@ -1069,6 +1501,169 @@ func configureServiceContainer(srv *def.Service, ops *def.Operation) docker.Crea
 	return opts
 }
 
+// sorted returns a sorted copy of list; nil and empty lists are the same.
+func sorted(list []string) []string {
+	c := append([]string{}, list...)
+	sort.Strings(c)
+	return c
+}
+
+// subtract returns the elements of a which are not in b.
+func subtract(a, b []string) []string {
+	var c []string
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c = append(c, x)
+		}
+	}
+	return c
+}
+
+// normalizeLinks converts links reported by Docker
+// (/eris_service_keys_1:/eris_service_foo_1/keys) back to the
+// way they are given in the definition files (eris_service_keys_1:keys).
+func normalizeLinks(links []string) []string {
+	var normalized []string
+	for _, link := range links {
+		parts := strings.SplitN(strings.TrimPrefix(link, "/"), ":", 2)
+		if len(parts) != 2 {
+			normalized = append(normalized, link)
+			continue
+		}
+		normalized = append(normalized, parts[0]+":"+path.Base(parts[1]))
+	}
+	return normalized
+}
+
+func portBindings(bindings map[docker.Port][]docker.PortBinding) []string {
+	var ports []string
+	for port, binds := range bindings {
+		for _, b := range binds {
+			host := b.HostPort
+			if b.HostIP != "" {
+				host = b.HostIP + ":" + host
+			}
+			ports = append(ports, host+"->"+string(port))
+		}
+	}
+	return sorted(ports)
+}
+
+func restartPolicy(policy docker.RestartPolicy) string {
+	switch policy.Name {
+	case "", "no":
+		return "no"
+	case "on-failure":
+		return fmt.Sprintf("max:%d", policy.MaximumRetryCount)
+	}
+	return policy.Name
+}
+
+// configureSecrets resolves srv.Secrets from the secrets store and adds
+// the resulting environment variables to opts. The names of those variables
+// are recorded in a container label, so that container inspection can
+// redact them. Secrets destined for files are returned to the caller.
+func configureSecrets(srv *def.Service, opts *docker.CreateContainerOptions) ([]secrets.File, error) {
+	if len(srv.Secrets) == 0 {
+		return nil, nil
+	}
+
+	env, files, err := secrets.Resolve(srv.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 0 && !srv.AutoData {
+		return nil, fmt.Errorf("Secret files can only be written with data_container = true")
+	}
+
+	// Copy the environment, so srv.Environment stays free of secret values.
+	opts.Config.Env = append(append([]string{}, opts.Config.Env...), env...)
+
+	// Manipulate labels locally.
+	labels := make(map[string]string)
+	for k, v := range opts.Config.Labels {
+		labels[k] = v
+	}
+	opts.Config.Labels = util.SetLabel(labels, def.LabelSecrets, strings.Join(secrets.EnvNames(srv.Secrets), ","))
+
+	return files, nil
+}
+
+// uploadSecrets writes secret files into the ops.DataContainerName
+// data container and changes their owner to the eris user.
+func uploadSecrets(srv *def.Service, ops *def.Operation, files []secrets.File) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	dirsSeen := make(map[string]bool)
+	var paths, owned []string
+	for _, file := range files {
+		rel, err := filepath.Rel(dirs.ErisContainerRoot, file.Path)
+		if err != nil {
+			return err
+		}
+
+		// Parent directories might not exist in the data container yet.
+		var parents []string
+		for dir := filepath.Dir(rel); dir != "." && !dirsSeen[dir]; dir = filepath.Dir(dir) {
+			dirsSeen[dir] = true
+			parents = append([]string{dir}, parents...)
+		}
+		for _, dir := range parents {
+			owned = append(owned, filepath.Join(dirs.ErisContainerRoot, dir))
+			if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Mode: 0700, Typeflag: tar.TypeDir}); err != nil {
+				return err
+			}
+		}
+
+		if err := tw.WriteHeader(&tar.Header{Name: rel, Mode: 0600, Size: int64(len(file.Value))}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.Value); err != nil {
+			return err
+		}
+		paths = append(paths, file.Path)
+		owned = append(owned, file.Path)
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"=>":    ops.DataContainerName,
+		"files": paths,
+	}).Info("Writing secrets into data container")
+	opts := docker.UploadToContainerOptions{
+		InputStream: buf,
+		Path:        dirs.ErisContainerRoot,
+	}
+	if err := util.DockerClient.UploadToContainer(ops.DataContainerName, opts); err != nil {
+		return err
+	}
+
+	// Required b/c `docker cp` (UploadToContainer) goes in as root.
+	chown := &def.Operation{
+		DataContainerName: ops.DataContainerName,
+		ContainerType:     def.TypeData,
+		Labels:            ops.Labels,
+		Args:              append([]string{"chown", "eris"}, owned...),
+	}
+	if _, err := DockerRunData(chown, nil); err != nil {
+		return fmt.Errorf("Error changing owner of secret files: %v", err)
+	}
+	return nil
+}
+
 func configureVolumesFromContainer(ops *def.Operation, service *def.Service) docker.CreateContainerOptions {
 	// Set the defaults.
 	opts := docker.CreateContainerOptions{
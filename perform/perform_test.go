@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"os"
 	"path"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/eris-ltd/eris-cli/config"
 	def "github.com/eris-ltd/eris-cli/definitions"
 	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/secrets"
 	"github.com/eris-ltd/eris-cli/tests"
 	"github.com/eris-ltd/eris-cli/util"
 	ver "github.com/eris-ltd/eris-cli/version"
@@ -244,6 +246,68 @@ func TestRunServiceNoDataContainer(t *testing.T) {
 	}
 }
 
+func TestRunServiceSecrets(t *testing.T) {
+	const (
+		name = "ipfs"
+	)
+
+	defer tests.RemoveAllContainers()
+
+	if err := secrets.Save(map[string]string{"DB_PASSWORD": "hunter2"}); err != nil {
+		t.Fatalf("expected secrets saved, got %v", err)
+	}
+	defer secrets.Save(map[string]string{})
+
+	srv, err := loaders.LoadServiceDefinition(name, true)
+	if err != nil {
+		t.Fatalf("could not load service definition %v", err)
+	}
+
+	srv.Service.Secrets = []string{"DB_PASSWORD:PGPASSWORD"}
+	if err := DockerRunService(srv.Service, srv.Operations); err != nil {
+		t.Fatalf("expected service container created, got %v", err)
+	}
+
+	for _, e := range srv.Service.Environment {
+		if strings.Contains(e, "hunter2") {
+			t.Fatalf("expected service definition environment without secrets, got %v", e)
+		}
+	}
+
+	cont, err := util.DockerClient.InspectContainer(srv.Operations.SrvContainerName)
+	if err != nil {
+		t.Fatalf("expected container inspected, got %v", err)
+	}
+	if !strings.Contains(strings.Join(cont.Config.Env, " "), "PGPASSWORD=hunter2") {
+		t.Fatalf("expected secret in container environment, got %v", cont.Config.Env)
+	}
+	if label := cont.Config.Labels[def.Namespace+":"+def.LabelSecrets]; label != "PGPASSWORD" {
+		t.Fatalf("expected secrets label %q, got %q", "PGPASSWORD", label)
+	}
+}
+
+func TestRunServiceSecretsMissing(t *testing.T) {
+	const (
+		name = "ipfs"
+	)
+
+	defer tests.RemoveAllContainers()
+
+	srv, err := loaders.LoadServiceDefinition(name, true)
+	if err != nil {
+		t.Fatalf("could not load service definition %v", err)
+	}
+
+	srv.Service.Secrets = []string{"NONEXISTENT"}
+	if err := DockerRunService(srv.Service, srv.Operations); err == nil {
+		t.Fatalf("expected missing secret error, got nil")
+	}
+
+	if n := util.HowManyContainersExisting(name, def.TypeService); n != 0 {
+		t.Fatalf("expecting 0 containers, got %v", n)
+	}
+}
+
 func TestExecServiceSimple(t *testing.T) {
 	const (
 		name = "ipfs"
@@ -1455,6 +1519,86 @@ func TestRebuildPullRepeat(t *testing.T) {
 	}
 }
 
+func TestDiffSimple(t *testing.T) {
+	const name = "ipfs"
+
+	defer tests.RemoveAllContainers()
+
+	srv, err := loaders.LoadServiceDefinition(name, true)
+	if err != nil {
+		t.Fatalf("could not load service definition %v", err)
+	}
+
+	if err := DockerRunService(srv.Service, srv.Operations); err != nil {
+		t.Fatalf("expected service container created, got %v", err)
+	}
+
+	drift, err := DockerDiff(srv.Service, srv.Operations)
+	if err != nil {
+		t.Fatalf("expected container compared, got %v", err)
+	}
+	if len(drift) != 0 {
+		t.Fatalf("expected no drift, got %v", drift)
+	}
+}
+
+func TestDiffDrift(t *testing.T) {
+	const name = "ipfs"
+
+	defer tests.RemoveAllContainers()
+
+	srv, err := loaders.LoadServiceDefinition(name, true)
+	if err != nil {
+		t.Fatalf("could not load service definition %v", err)
+	}
+
+	if err := DockerRunService(srv.Service, srv.Operations); err != nil {
+		t.Fatalf("expected service container created, got %v", err)
+	}
+
+	srv.Service.Environment = append(srv.Service.Environment, "MARMOTS=drifted")
+	srv.Service.Ports = append(srv.Service.Ports, "12345:12345")
+
+	drift, err := DockerDiff(srv.Service, srv.Operations)
+	if err != nil {
+		t.Fatalf("expected container compared, got %v", err)
+	}
+	if len(drift) != 2 || drift[0].Field != "environment" || drift[1].Field != "ports" {
+		t.Fatalf("expected environment and ports drift, got %v", drift)
+	}
+
+	if err := DockerRebuild(srv.Service, srv.Operations, false, 5); err != nil {
+		t.Fatalf("expected container rebuilt, got %v", err)
+	}
+
+	drift, err = DockerDiff(srv.Service, srv.Operations)
+	if err != nil {
+		t.Fatalf("expected container compared, got %v", err)
+	}
+	if len(drift) != 0 {
+		t.Fatalf("expected no drift after rebuild, got %v", drift)
+	}
+}
+
+func TestDiffBadName(t *testing.T) {
+	srv := loaders.MockServiceDefinition("not-exist", true)
+
+	if _, err := DockerDiff(srv.Service, srv.Operations); err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+}
+
+func TestNormalizeLinks(t *testing.T) {
+	links := normalizeLinks([]string{
+		"/eris_service_keys_1:/eris_service_foo_1/keys",
+		"/eris_chain_simple_1:/eris_service_foo_1/chain",
+	})
+
+	if expected := []string{"eris_service_keys_1:keys", "eris_chain_simple_1:chain"}; !reflect.DeepEqual(links, expected) {
+		t.Fatalf("expected %v, got %v", expected, links)
+	}
+}
+
 func TestPullSimple(t *testing.T) {
 	const (
 		name = "keys"
@@ -1931,6 +2075,40 @@ func TestRenameService(t *testing.T) {
 	}
 }
 
+func TestRenameServiceLabels(t *testing.T) {
+	const (
+		name    = "ipfs"
+		newName = "newname"
+	)
+
+	defer tests.RemoveAllContainers()
+
+	srv, err := loaders.LoadServiceDefinition(name, true)
+	if err != nil {
+		t.Fatalf("could not load service definition %v", err)
+	}
+
+	srv.Operations.Labels["com.example.custom"] = "kept"
+	if err := DockerRunService(srv.Service, srv.Operations); err != nil {
+		t.Fatalf("expected service container created, got %v", err)
+	}
+
+	if err := DockerRename(srv.Operations, newName); err != nil {
+		t.Fatalf("expected container renamed, got %v", err)
+	}
+
+	cont, err := util.DockerClient.InspectContainer(util.ContainersName(def.TypeService, newName))
+	if err != nil {
+		t.Fatalf("expected container inspected, got %v", err)
+	}
+	if label := cont.Config.Labels["com.example.custom"]; label != "kept" {
+		t.Fatalf("expected custom label %q, got %q", "kept", label)
+	}
+	if label := cont.Config.Labels[def.Namespace+":"+def.LabelShortName]; label != newName {
+		t.Fatalf("expected name label %q, got %q", newName, label)
+	}
+}
+
 func TestRenameEmptyName(t *testing.T) {
 	const (
 		name    = "ipfs"
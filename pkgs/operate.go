@@ -235,8 +235,23 @@ func bootChain(name string, do *definitions.Do) error {
 	startChain.Name = name
 	startChain.Operations = do.Operations
 	f, err := os.Stat(filepath.Join(common.ChainsPath, startChain.Name))
+	if do.Tag != "" && !util.IsChainContainer(name, true) {
+		return fmt.Errorf("Cannot reset %s to snapshot %s. Only existing chains can be reset", name, do.Tag)
+	}
 	switch {
 	case util.IsChainContainer(name, true):
+		if do.Tag != "" {
+			log.WithFields(log.Fields{
+				"=>":  name,
+				"tag": do.Tag,
+			}).Info("Resetting Chain")
+			doReset := definitions.NowDo()
+			doReset.Name = name
+			doReset.Tag = do.Tag
+			if err := chains.ResetChain(doReset); err != nil {
+				return err
+			}
+		}
 		log.WithField("name", startChain.Name).Info("Starting Chain")
 		if err := chains.StartChain(startChain); err != nil {
 			return err
@@ -272,6 +287,9 @@ func bootChain(name string, do *definitions.Do) error {
 
 func bootThrowAwayChain(name string, do *definitions.Do) error {
 	do.Chain.ChainType = "throwaway"
+	if do.Tag != "" {
+		return fmt.Errorf("Cannot reset a throwaway chain to snapshot %s. Give the chain with --chain", do.Tag)
+	}
 
 	tmp := do.Name
 	do.Name = name
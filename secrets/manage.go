@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// SetSecret adds a secret to the encrypted store or
+// overwrites the value of an existing one.
+//
+//  do.Name            - name of the secret (required)
+//  do.Operations.Args - secret value (required)
+//
+func SetSecret(do *definitions.Do) error {
+	if err := ValidName(do.Name); err != nil {
+		return err
+	}
+	if len(do.Operations.Args) == 0 {
+		return fmt.Errorf("Please provide a value for the %s secret", do.Name)
+	}
+
+	secrets, err := Load()
+	if err != nil {
+		return err
+	}
+
+	secrets[do.Name] = strings.Join(do.Operations.Args, " ")
+	if err := Save(secrets); err != nil {
+		return err
+	}
+
+	log.WithField("=>", do.Name).Info("Secret set")
+	do.Result = "success"
+	return nil
+}
+
+// GetSecret prints the secret value to the global writer.
+//
+//  do.Name - name of the secret (required)
+//
+func GetSecret(do *definitions.Do) error {
+	secrets, err := Load()
+	if err != nil {
+		return err
+	}
+
+	value, ok := secrets[do.Name]
+	if !ok {
+		return ErrSecretNotFound
+	}
+
+	fmt.Fprintln(config.GlobalConfig.Writer, value)
+	do.Result = value
+	return nil
+}
+
+// ListSecrets displays the names (never the values) of the stored secrets.
+// The names are also returned as a comma separated list in do.Result.
+func ListSecrets(do *definitions.Do) error {
+	secrets, err := Load()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	do.Result = strings.Join(names, ",")
+
+	if len(names) == 0 {
+		log.Warn("No secrets found.")
+		return nil
+	}
+	for _, name := range names {
+		log.Warn(name)
+	}
+	return nil
+}
+
+// RmSecret removes secrets from the store.
+//
+//  do.Operations.Args - names of the secrets to remove (required)
+//
+func RmSecret(do *definitions.Do) error {
+	secrets, err := Load()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range do.Operations.Args {
+		if _, ok := secrets[name]; !ok {
+			return fmt.Errorf("%v: %s", ErrSecretNotFound, name)
+		}
+		delete(secrets, name)
+		log.WithField("=>", name).Info("Removing secret")
+	}
+
+	if err := Save(secrets); err != nil {
+		return err
+	}
+	do.Result = "success"
+	return nil
+}
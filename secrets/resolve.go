@@ -0,0 +1,122 @@
+package secrets
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// Redacted replaces secret values in logged container configurations.
+const Redacted = "[REDACTED]"
+
+// File is a secret which is to be written into a data container.
+type File struct {
+	// Path is an absolute path inside the container.
+	Path  string
+	Value []byte
+}
+
+// ParseReference splits a secret reference from a definition file's
+// secrets field into the secret name and its target. References
+// can take the following forms:
+//
+//  NAME            - exported to the container as $NAME
+//  NAME:VAR        - exported to the container as $VAR
+//  NAME:some/path  - written to a file in the data container; relative
+//                    paths are rooted at ErisContainerRoot
+//
+func ParseReference(ref string) (name, target string, file bool) {
+	parts := strings.SplitN(ref, ":", 2)
+	name = strings.TrimSpace(parts[0])
+	if len(parts) == 1 || strings.TrimSpace(parts[1]) == "" {
+		return name, name, false
+	}
+
+	target = strings.TrimSpace(parts[1])
+	if strings.Contains(target, "/") {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(ErisContainerRoot, target)
+		}
+		return name, filepath.Clean(target), true
+	}
+	return name, target, false
+}
+
+// EnvNames returns the names of environment variables populated
+// by the refs secret references.
+func EnvNames(refs []string) []string {
+	var names []string
+	for _, ref := range refs {
+		if _, target, file := ParseReference(ref); !file {
+			names = append(names, target)
+		}
+	}
+	return names
+}
+
+// Resolve looks up the refs secret references in the store and returns
+// environment variables (in the KEY=VALUE form) and files to be
+// supplied to a container. All missing secrets are reported at once.
+func Resolve(refs []string) (env []string, files []File, err error) {
+	if len(refs) == 0 {
+		return nil, nil, nil
+	}
+
+	secrets, err := Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var missing []string
+	for _, ref := range refs {
+		name, target, file := ParseReference(ref)
+		if err := ValidName(name); err != nil {
+			return nil, nil, fmt.Errorf("Bad secret reference %q: %v", ref, err)
+		}
+
+		value, ok := secrets[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+
+		if file {
+			if !strings.HasPrefix(target, ErisContainerRoot+"/") {
+				return nil, nil, fmt.Errorf("Secret file %s must be located under %s", target, ErisContainerRoot)
+			}
+			files = append(files, File{Path: target, Value: []byte(value)})
+		} else {
+			env = append(env, target+"="+value)
+		}
+	}
+
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return nil, nil, fmt.Errorf("The marmots could not find the following secrets: %s\nSet them with [eris secrets set NAME]", strings.Join(missing, ", "))
+	}
+
+	return env, files, nil
+}
+
+// Redact returns a copy of env with values of the names
+// environment variables replaced with Redacted.
+func Redact(env []string, names []string) []string {
+	if len(names) == 0 {
+		return env
+	}
+
+	redacted := make([]string, len(env))
+	for i, e := range env {
+		redacted[i] = e
+		for _, name := range names {
+			if strings.HasPrefix(e, name+"=") {
+				redacted[i] = name + "=" + Redacted
+				break
+			}
+		}
+	}
+	return redacted
+}
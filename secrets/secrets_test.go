@@ -0,0 +1,223 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	logger "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
+)
+
+var erisDir = filepath.Join(os.TempDir(), "eris-secrets")
+
+func TestMain(m *testing.M) {
+	log.SetFormatter(logger.ErisFormatter{})
+
+	log.SetLevel(log.ErrorLevel)
+	// log.SetLevel(log.InfoLevel)
+	// log.SetLevel(log.DebugLevel)
+
+	root := ErisRoot
+	ErisRoot = erisDir
+	config.GlobalConfig = &config.ErisCli{Writer: new(bytes.Buffer), ErrorWriter: new(bytes.Buffer)}
+
+	exitCode := m.Run()
+
+	ErisRoot = root
+	os.RemoveAll(erisDir)
+	os.Exit(exitCode)
+}
+
+func TestSetGetSecret(t *testing.T) {
+	defer os.RemoveAll(erisDir)
+
+	do := definitions.NowDo()
+	do.Name = "DB_PASSWORD"
+	do.Operations.Args = []string{"hunter2"}
+	if err := SetSecret(do); err != nil {
+		t.Fatalf("expected secret set, got %v", err)
+	}
+
+	do = definitions.NowDo()
+	do.Name = "DB_PASSWORD"
+	if err := GetSecret(do); err != nil {
+		t.Fatalf("expected secret returned, got %v", err)
+	}
+	if do.Result != "hunter2" {
+		t.Fatalf("expected %q, got %q", "hunter2", do.Result)
+	}
+
+	// The store should not contain the plain text value.
+	data, err := ioutil.ReadFile(storeFile())
+	if err != nil {
+		t.Fatalf("expected store file to exist, got %v", err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatalf("expected store encrypted, got plain text value")
+	}
+}
+
+func TestGetSecretMissing(t *testing.T) {
+	defer os.RemoveAll(erisDir)
+
+	do := definitions.NowDo()
+	do.Name = "NOPE"
+	if err := GetSecret(do); err != ErrSecretNotFound {
+		t.Fatalf("expected %v, got %v", ErrSecretNotFound, err)
+	}
+}
+
+func TestSetSecretBadName(t *testing.T) {
+	defer os.RemoveAll(erisDir)
+
+	do := definitions.NowDo()
+	do.Name = "BAD:NAME"
+	do.Operations.Args = []string{"value"}
+	if err := SetSecret(do); err != ErrBadSecretName {
+		t.Fatalf("expected %v, got %v", ErrBadSecretName, err)
+	}
+}
+
+func TestListAndRmSecrets(t *testing.T) {
+	defer os.RemoveAll(erisDir)
+
+	if err := Save(map[string]string{"B": "2", "A": "1"}); err != nil {
+		t.Fatalf("expected store saved, got %v", err)
+	}
+
+	do := definitions.NowDo()
+	if err := ListSecrets(do); err != nil {
+		t.Fatalf("expected secrets listed, got %v", err)
+	}
+	if do.Result != "A,B" {
+		t.Fatalf("expected %q, got %q", "A,B", do.Result)
+	}
+
+	do = definitions.NowDo()
+	do.Operations.Args = []string{"A"}
+	if err := RmSecret(do); err != nil {
+		t.Fatalf("expected secret removed, got %v", err)
+	}
+
+	secrets, err := Load()
+	if err != nil {
+		t.Fatalf("expected store loaded, got %v", err)
+	}
+	if !reflect.DeepEqual(secrets, map[string]string{"B": "2"}) {
+		t.Fatalf("expected only B left, got %v", secrets)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	defer os.RemoveAll(erisDir)
+	defer os.Setenv(PassphraseEnv, os.Getenv(PassphraseEnv))
+
+	os.Setenv(PassphraseEnv, "correct horse")
+	if err := Save(map[string]string{"A": "1"}); err != nil {
+		t.Fatalf("expected store saved, got %v", err)
+	}
+	if _, err := os.Stat(keyFile()); !os.IsNotExist(err) {
+		t.Fatalf("expected no key file with a passphrase, got %v", err)
+	}
+	if _, err := Load(); err != nil {
+		t.Fatalf("expected store loaded with the same passphrase, got %v", err)
+	}
+
+	// A new salt gives a different key for the same passphrase.
+	if err := os.Remove(saltFile()); err != nil {
+		t.Fatalf("expected a salt file with a passphrase, got %v", err)
+	}
+	if _, err := Load(); err == nil {
+		t.Fatalf("expected missing salt error, got nil")
+	}
+	first, _ := passphraseKey("correct horse", true)
+	os.Remove(saltFile())
+	second, _ := passphraseKey("correct horse", true)
+	if bytes.Equal(first, second) {
+		t.Fatalf("expected different keys for different salts")
+	}
+
+	os.Setenv(PassphraseEnv, "battery staple")
+	if _, err := Load(); err == nil {
+		t.Fatalf("expected wrong passphrase error, got nil")
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	for iterations, expected := range map[int]string{
+		1:    "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b",
+		2:    "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43",
+		4096: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a",
+	} {
+		if key := hex.EncodeToString(pbkdf2([]byte("password"), []byte("salt"), iterations, 32)); key != expected {
+			t.Fatalf("%d iterations: expected %s, got %s", iterations, expected, key)
+		}
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	for _, test := range []struct {
+		ref, name, target string
+		file              bool
+	}{
+		{"DB_PASSWORD", "DB_PASSWORD", "DB_PASSWORD", false},
+		{"DB_PASSWORD:", "DB_PASSWORD", "DB_PASSWORD", false},
+		{"DB_PASSWORD:PGPASSWORD", "DB_PASSWORD", "PGPASSWORD", false},
+		{"TLS_KEY:tls/key.pem", "TLS_KEY", filepath.Join(ErisContainerRoot, "tls", "key.pem"), true},
+		{"TLS_KEY:/etc/key.pem", "TLS_KEY", "/etc/key.pem", true},
+	} {
+		name, target, file := ParseReference(test.ref)
+		if name != test.name || target != test.target || file != test.file {
+			t.Fatalf("%q: expected (%v, %v, %v), got (%v, %v, %v)", test.ref,
+				test.name, test.target, test.file, name, target, file)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	defer os.RemoveAll(erisDir)
+
+	if err := Save(map[string]string{"A": "1", "B": "2", "C": "3"}); err != nil {
+		t.Fatalf("expected store saved, got %v", err)
+	}
+
+	env, files, err := Resolve([]string{"A", "B:BEE", "C:c/file"})
+	if err != nil {
+		t.Fatalf("expected secrets resolved, got %v", err)
+	}
+	if !reflect.DeepEqual(env, []string{"A=1", "BEE=2"}) {
+		t.Fatalf("expected environment [A=1 BEE=2], got %v", env)
+	}
+	if len(files) != 1 || files[0].Path != filepath.Join(ErisContainerRoot, "c", "file") || string(files[0].Value) != "3" {
+		t.Fatalf("expected one secret file, got %v", files)
+	}
+
+	if _, _, err := Resolve([]string{"A", "X", "Y"}); err == nil {
+		t.Fatalf("expected missing secrets error, got nil")
+	}
+
+	if _, _, err := Resolve([]string{"A:/etc/passwd"}); err == nil {
+		t.Fatalf("expected file outside of %s error, got nil", ErisContainerRoot)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	env := []string{"A=1", "AB=2", "C=3"}
+	redacted := Redact(env, []string{"A", "C"})
+
+	if !reflect.DeepEqual(redacted, []string{"A=" + Redacted, "AB=2", "C=" + Redacted}) {
+		t.Fatalf("expected A and C redacted, got %v", redacted)
+	}
+	if env[0] != "A=1" {
+		t.Fatalf("expected original environment intact, got %v", env)
+	}
+}
@@ -0,0 +1,249 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// PassphraseEnv is the environment variable which, if set, is used to derive
+// the store key instead of the key file kept next to the store.
+const PassphraseEnv = "ERIS_SECRETS_PASSPHRASE"
+
+const (
+	// number of PBKDF2 iterations used to derive the store key
+	// from a passphrase
+	pbkdf2Iterations = 100000
+	saltSize         = 16
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrBadSecretName  = errors.New("secret names can only contain letters, digits, dots, dashes and underscores")
+
+	nameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// SecretsPath returns the directory holding the encrypted store and its key.
+// It is computed on every call so that [eris --dir] changes are honored.
+func SecretsPath() string {
+	return filepath.Join(ErisRoot, "secrets")
+}
+
+func storeFile() string {
+	return filepath.Join(SecretsPath(), "secrets.enc")
+}
+
+// keyFile is where the generated store key is kept. Anyone who can
+// read both the key file and the store can decrypt the secrets, so
+// the secrets directory must be protected as a whole (or a passphrase
+// used instead).
+func keyFile() string {
+	return filepath.Join(SecretsPath(), "key")
+}
+
+func saltFile() string {
+	return filepath.Join(SecretsPath(), "salt")
+}
+
+// ValidName returns ErrBadSecretName if name cannot be used as a secret name.
+func ValidName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return ErrBadSecretName
+	}
+	return nil
+}
+
+// Load decrypts the secrets store and returns its contents. An empty map
+// is returned if nothing has been stored yet.
+func Load() (map[string]string, error) {
+	secrets := make(map[string]string)
+
+	data, err := ioutil.ReadFile(storeFile())
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := masterKey(false)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := decrypt(key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("The marmots could not read the secrets store: %v", err)
+	}
+	return secrets, nil
+}
+
+// Save encrypts secrets and overwrites the store with them.
+func Save(secrets map[string]string) error {
+	if err := os.MkdirAll(SecretsPath(), 0700); err != nil {
+		return err
+	}
+
+	key, err := masterKey(true)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	data, err := encrypt(key, plain)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a failure
+	// halfway through doesn't leave a corrupted store behind.
+	tmp := storeFile() + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, storeFile())
+}
+
+// masterKey returns a 256 bit key used to encrypt the store. The key is
+// either derived from the PassphraseEnv variable or read from the key file.
+// If create is true and there's no key file, a random key is generated.
+func masterKey(create bool) ([]byte, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return passphraseKey(passphrase, create)
+	}
+
+	key, err := ioutil.ReadFile(keyFile())
+	if err == nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("The secrets key file %s is corrupted", keyFile())
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("The marmots could not read the secrets key: %v", err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(keyFile(), key, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// passphraseKey derives the store key from passphrase with PBKDF2-HMAC-SHA256
+// and the random salt kept next to the store. If create is true and there's
+// no salt file, a new salt is generated.
+func passphraseKey(passphrase string, create bool) ([]byte, error) {
+	salt, err := ioutil.ReadFile(saltFile())
+	switch {
+	case err == nil:
+		if len(salt) != saltSize {
+			return nil, fmt.Errorf("The secrets salt file %s is corrupted", saltFile())
+		}
+	case os.IsNotExist(err) && create:
+		salt = make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(saltFile(), salt, 0600); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("The marmots could not read the secrets salt: %v", err)
+	}
+
+	return pbkdf2([]byte(passphrase), salt, pbkdf2Iterations, 32), nil
+}
+
+// pbkdf2 derives a key of keyLen bytes from password and salt
+// with PBKDF2 (RFC 2898) using HMAC-SHA256.
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	key := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		key = prf.Sum(key)
+
+		t := key[len(key)-hashLen:]
+		copy(u, t)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return key[:keyLen]
+}
+
+// encrypt seals plain with AES-GCM and prepends the random nonce.
+func encrypt(key, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("The secrets store %s is corrupted", storeFile())
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("The marmots could not decrypt the secrets store. Wrong key or passphrase?")
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/data"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// BackupService writes a compressed archive with the contents of the data
+// container of the service, its definition file, the image digest, and
+// a manifest with checksums of all files. The archive name is returned
+// in do.Result.
+//
+//  do.Name        - name of the service (required)
+//  do.Destination - archive to write (defaults to NAME_DATE.tar.gz)
+//
+func BackupService(do *definitions.Do) error {
+	srv, err := loaders.LoadServiceDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+
+	manifest := &data.BackupManifest{
+		Name:  do.Name,
+		Type:  definitions.TypeService,
+		Image: srv.Service.Image,
+	}
+	var containerID string
+	if c := util.FindServiceContainer(do.Name, true); c != nil {
+		containerID = c.ContainerID
+	}
+	manifest.ImageID, manifest.ImageDigests = data.ImageDigests(srv.Service.Image, containerID)
+
+	archive := BackupFileName(do.Name, do.Destination)
+	if err := data.WriteBackup(manifest, archive, FindServiceDefinitionFile(do.Name)); err != nil {
+		return err
+	}
+
+	log.WithField("=>", archive).Warn("Service backed up")
+	do.Result = archive
+	return nil
+}
+
+// RestoreService verifies the backup archive and recreates the service
+// definition file and the data container from it.
+//
+//  do.Source  - backup archive written by BackupService (required)
+//  do.NewName - name to restore the service under (optional)
+//  do.Force   - replace the existing definition file and data container
+//
+func RestoreService(do *definitions.Do) error {
+	b, err := data.ReadBackup(do.Source)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	if b.Manifest.Type != definitions.TypeService {
+		return fmt.Errorf("%s is a backup of the %s %s, not a service", do.Source, b.Manifest.Type, b.Manifest.Name)
+	}
+
+	name := b.Manifest.Name
+	if do.NewName != "" {
+		name = do.NewName
+	}
+
+	if old := FindServiceDefinitionFile(name); old != "" {
+		if !do.Force {
+			return fmt.Errorf("Service %s already exists. Use --force to replace it", name)
+		}
+		os.Remove(old)
+	}
+
+	fileName := filepath.Join(ServicesPath, name+path.Ext(b.Manifest.Definition))
+	if err := ioutil.WriteFile(fileName, b.Definition, 0644); err != nil {
+		return err
+	}
+	if name != b.Manifest.Name {
+		// Only the name changes; the rest of the definition file
+		// is restored as it was.
+		content, err := util.SetDefinitionValues(b.Definition, fileName, map[string]string{
+			"name":         name,
+			"service.name": name,
+		})
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fileName, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := b.Restore(name, do.Force, nil); err != nil {
+		return err
+	}
+	b.Manifest.WarnImageDrift()
+
+	log.WithField("=>", name).Warn("Service restored")
+	do.Result = name
+	return nil
+}
+
+// BackupFileName returns the archive name to back up to.
+func BackupFileName(name, archive string) string {
+	if archive != "" {
+		return archive
+	}
+	return name + "_" + time.Now().Format("2006-01-02_15-04-05") + ".tar.gz"
+}
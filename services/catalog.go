@@ -0,0 +1,374 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// DescriptionPlaceholder is written to new service definition files
+// in place of the description.
+const DescriptionPlaceholder = "# describe your service"
+
+// Name of the index file if the index location is a directory.
+const indexFile = "index.json"
+
+var indexClient = &http.Client{Timeout: 30 * time.Second}
+
+// CatalogEntry describes a service in the local catalog (the installed
+// service definition files) or in a remote index.
+//
+// A remote index is a JSON file of the following format:
+//
+//  {
+//    "services": [
+//      {
+//        "name": "ipfs",
+//        "description": "The InterPlanetary File System",
+//        "version": "0.4.0",
+//        "tags": [ "storage", "p2p" ],
+//        "image": "eris/ipfs",
+//        "file": "ipfs.toml"
+//      }
+//    ]
+//  }
+//
+// The file field is the location of the service definition file, either
+// a URL or a path relative to the index.
+type CatalogEntry struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	File        string   `json:"file,omitempty"`
+
+	Installed bool `json:"-"`
+}
+
+type catalogIndex struct {
+	Services []*CatalogEntry `json:"services"`
+}
+
+// SearchServices displays the services from the local catalog and the
+// remote index which match the query. The query is matched (ignoring case)
+// against names, descriptions, tags, and images; all words of the query
+// have to match. The names of the matching services are returned as a
+// comma separated list in do.Result.
+//
+//  do.Operations.Args - query words (all services are displayed if empty)
+//  do.Index           - remote index URL, file, or directory (optional)
+//
+func SearchServices(do *definitions.Do) error {
+	entries, err := LocalCatalog()
+	if err != nil {
+		return err
+	}
+
+	if index := servicesIndex(do); index != "" {
+		remote, err := RemoteCatalog(index)
+		if err != nil {
+			return err
+		}
+		entries = MergeCatalogs(entries, remote)
+	}
+
+	matches := FilterCatalog(entries, strings.Join(do.Operations.Args, " "))
+
+	var names []string
+	for _, entry := range matches {
+		names = append(names, entry.Name)
+	}
+	do.Result = strings.Join(names, ",")
+
+	if len(matches) == 0 {
+		log.Warn("No services found.")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetHeader([]string{"NAME", "VERSION", "TAGS", "INSTALLED", "DESCRIPTION"})
+	for _, entry := range matches {
+		installed := ""
+		if entry.Installed {
+			installed = "yes"
+		}
+		table.Append([]string{entry.Name, entry.Version, strings.Join(entry.Tags, ", "), installed, summary(entry.Description)})
+	}
+	table.SetBorder(false)
+	table.SetCenterSeparator(" ")
+	table.SetColumnSeparator(" ")
+	table.SetRowSeparator("-")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+	return nil
+}
+
+// InstallService installs a service definition file from the remote index.
+//
+//  do.Name  - name of the service in the index (required)
+//  do.Index - remote index URL, file, or directory (required unless
+//             set in the ServicesIndex field of eris.toml)
+//  do.Force - overwrite the installed service definition file
+//
+func InstallService(do *definitions.Do) error {
+	index := servicesIndex(do)
+	if index == "" {
+		return fmt.Errorf("Please provide the services index with the --index flag")
+	}
+
+	if FindServiceDefinitionFile(do.Name) != "" && !do.Force {
+		return fmt.Errorf("Service %s is already installed. Use --force to overwrite it", do.Name)
+	}
+
+	entries, err := RemoteCatalog(index)
+	if err != nil {
+		return err
+	}
+
+	var entry *CatalogEntry
+	for _, e := range entries {
+		if e.Name == do.Name {
+			entry = e
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("Service %s cannot be found in the index %s", do.Name, index)
+	}
+	if entry.File == "" {
+		return fmt.Errorf("Service %s has no definition file in the index", do.Name)
+	}
+
+	location := resolveIndexLocation(index, entry.File)
+	log.WithFields(log.Fields{
+		"=>":   do.Name,
+		"from": location,
+	}).Info("Installing service definition file")
+	contents, err := readIndexLocation(location)
+	if err != nil {
+		return err
+	}
+
+	ext := path.Ext(entry.File)
+	if ext == "" || strings.Contains(ext, "?") {
+		ext = ".toml"
+	}
+	fileName := filepath.Join(ServicesPath, do.Name+ext)
+
+	// The installed file is put back if the new one is broken.
+	old := FindServiceDefinitionFile(do.Name)
+	var oldContents []byte
+	if old != "" {
+		if oldContents, err = ioutil.ReadFile(old); err != nil {
+			return err
+		}
+		os.Remove(old)
+	}
+	if err := ioutil.WriteFile(fileName, contents, 0644); err != nil {
+		return err
+	}
+
+	if _, err := loaders.LoadServiceDefinition(do.Name, false); err != nil {
+		os.Remove(fileName)
+		if old != "" {
+			ioutil.WriteFile(old, oldContents, 0644)
+		}
+		return fmt.Errorf("The service definition file for %s looks improperly formatted and will not marshal: %v", do.Name, err)
+	}
+
+	log.WithField("=>", do.Name).Warn("Service installed")
+	do.Result = "success"
+	return nil
+}
+
+// LocalCatalog returns the catalog entries of the installed services.
+func LocalCatalog() ([]*CatalogEntry, error) {
+	var entries []*CatalogEntry
+	for _, name := range util.GetGlobalLevelConfigFilesByType("services", false) {
+		srv, err := loaders.ReadServiceDefinition(name)
+		if err != nil {
+			log.WithField("=>", name).Debugf("Skipping service definition: %v", err)
+			entries = append(entries, &CatalogEntry{Name: name, Installed: true})
+			continue
+		}
+
+		description := strings.TrimSpace(srv.Description)
+		if description == DescriptionPlaceholder {
+			description = ""
+		}
+		entries = append(entries, &CatalogEntry{
+			Name:        name,
+			Description: description,
+			Version:     srv.Version,
+			Tags:        srv.Tags,
+			Image:       srv.Service.Image,
+			Installed:   true,
+		})
+	}
+	return entries, nil
+}
+
+// RemoteCatalog reads the catalog entries from the index at the given
+// location: an HTTP(S) URL, a JSON file, or a directory containing
+// an index.json file. Installed services are marked as such.
+func RemoteCatalog(index string) ([]*CatalogEntry, error) {
+	log.WithField("index", index).Debug("Reading services index")
+	contents, err := readIndexLocation(index)
+	if err != nil {
+		return nil, err
+	}
+
+	var catalog catalogIndex
+	if err := json.Unmarshal(contents, &catalog); err != nil {
+		return nil, fmt.Errorf("Cannot read the services index %s: %v", index, err)
+	}
+
+	for _, entry := range catalog.Services {
+		entry.Installed = FindServiceDefinitionFile(entry.Name) != ""
+	}
+	return catalog.Services, nil
+}
+
+// MergeCatalogs adds remote entries to the local ones. Metadata of the
+// installed services takes precedence; missing fields are taken from
+// the remote entry. The result is sorted by name.
+func MergeCatalogs(local, remote []*CatalogEntry) []*CatalogEntry {
+	byName := make(map[string]*CatalogEntry)
+	for _, entry := range local {
+		byName[entry.Name] = entry
+	}
+
+	merged := append([]*CatalogEntry{}, local...)
+	for _, entry := range remote {
+		l, ok := byName[entry.Name]
+		if !ok {
+			merged = append(merged, entry)
+			continue
+		}
+		if l.Description == "" {
+			l.Description = entry.Description
+		}
+		if l.Version == "" {
+			l.Version = entry.Version
+		}
+		if len(l.Tags) == 0 {
+			l.Tags = entry.Tags
+		}
+		if l.Image == "" {
+			l.Image = entry.Image
+		}
+		l.File = entry.File
+	}
+
+	sort.Sort(byEntryName(merged))
+	return merged
+}
+
+// FilterCatalog returns the entries matching all words of the query.
+func FilterCatalog(entries []*CatalogEntry, query string) []*CatalogEntry {
+	words := strings.Fields(strings.ToLower(query))
+
+	var matches []*CatalogEntry
+	for _, entry := range entries {
+		haystack := strings.ToLower(strings.Join([]string{
+			entry.Name, entry.Description, entry.Image, strings.Join(entry.Tags, " "),
+		}, " "))
+
+		match := true
+		for _, word := range words {
+			match = match && strings.Contains(haystack, word)
+		}
+		if match {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+type byEntryName []*CatalogEntry
+
+func (e byEntryName) Len() int           { return len(e) }
+func (e byEntryName) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byEntryName) Less(i, j int) bool { return e[i].Name < e[j].Name }
+
+func servicesIndex(do *definitions.Do) string {
+	if do.Index != "" {
+		return do.Index
+	}
+	return config.GetConfigValue("ServicesIndex")
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// readIndexLocation reads an HTTP(S) URL, a file, or the index.json
+// file of a directory.
+func readIndexLocation(location string) ([]byte, error) {
+	if isURL(location) {
+		resp, err := indexClient.Get(location)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("Cannot get %s: %s", location, resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		location = filepath.Join(location, indexFile)
+	}
+	return ioutil.ReadFile(location)
+}
+
+// resolveIndexLocation resolves the location of a file relative to the index.
+func resolveIndexLocation(index, file string) string {
+	if isURL(file) || filepath.IsAbs(file) {
+		return file
+	}
+
+	if isURL(index) {
+		base, err := url.Parse(index)
+		if err != nil {
+			return file
+		}
+		ref, err := url.Parse(file)
+		if err != nil {
+			return file
+		}
+		return base.ResolveReference(ref).String()
+	}
+
+	if info, err := os.Stat(index); err == nil && info.IsDir() {
+		return filepath.Join(index, file)
+	}
+	return filepath.Join(filepath.Dir(index), file)
+}
+
+// summary returns the first line of the description, shortened.
+func summary(description string) string {
+	line := strings.SplitN(strings.TrimSpace(description), "\n", 2)[0]
+	if len(line) > 60 {
+		line = line[:57] + "..."
+	}
+	return line
+}
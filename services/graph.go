@@ -0,0 +1,357 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+)
+
+// GraphService displays the dependency graph of services: the services
+// and chains [eris services start] would bring up.
+//
+//  do.Operations.Args - names of the services (all known services if empty)
+//  do.ChainName       - chain to use instead of the ones in the definitions
+//  do.GraphFormat     - text (default) or dot
+//
+func GraphService(do *definitions.Do) error {
+	names := do.Operations.Args
+	if len(names) == 0 {
+		names = util.GetGlobalLevelConfigFilesByType("services", false)
+	}
+
+	g := NewGraph(do.ChainName)
+	for _, name := range names {
+		g.AddService(name)
+	}
+	return WriteGraph(g, do)
+}
+
+// WriteGraph writes the graph to the global writer in do.GraphFormat
+// and saves it in do.Result.
+func WriteGraph(g *Graph, do *definitions.Do) error {
+	buf := new(bytes.Buffer)
+	if err := g.Write(buf, do.GraphFormat); err != nil {
+		return err
+	}
+	do.Result = buf.String()
+	config.GlobalConfig.Writer.Write(buf.Bytes())
+	return nil
+}
+
+// Node states in a dependency graph.
+const (
+	NodeRunning = "running"
+	NodeStopped = "stopped"
+	NodeMissing = "missing"
+)
+
+// GraphNode is a service, chain, or action in a dependency graph.
+type GraphNode struct {
+	Name  string
+	Type  string
+	State string
+	Edges []*GraphEdge
+}
+
+// GraphEdge is a dependency of a node. Link and Mount are set
+// according to the util.ParseDependency flags of the dependency.
+type GraphEdge struct {
+	To           *GraphNode
+	InternalName string
+	Link         bool
+	Mount        bool
+}
+
+// Graph is a dependency graph of services and chains resolved the same
+// way BuildServicesGroup and BuildChainGroup do. Nodes are shared, so
+// dependency cycles don't send the marmots into an endless loop.
+type Graph struct {
+	Roots []*GraphNode
+
+	chainName string
+	nodes     map[string]*GraphNode
+	running   map[string]bool
+}
+
+// NewGraph returns an empty dependency graph. If chainName is not empty,
+// it overwrites the chains specified in service definitions, similarly
+// to the --chain flag of [eris services start].
+func NewGraph(chainName string) *Graph {
+	g := &Graph{
+		chainName: chainName,
+		nodes:     make(map[string]*GraphNode),
+		running:   make(map[string]bool),
+	}
+
+	for _, typ := range []string{definitions.TypeService, definitions.TypeChain} {
+		for _, c := range util.ErisContainersByType(typ, false) {
+			g.running[typ+":"+c.ShortName] = true
+		}
+	}
+	return g
+}
+
+// AddService adds a service and its dependencies to the graph roots.
+func (g *Graph) AddService(name string) *GraphNode {
+	node := g.service(name)
+	g.Roots = append(g.Roots, node)
+	return node
+}
+
+// AddChain adds a chain and its dependencies to the graph roots.
+func (g *Graph) AddChain(name string) *GraphNode {
+	node := g.chain(name)
+	g.Roots = append(g.Roots, node)
+	return node
+}
+
+// AddAction adds an action and the services and chain it starts
+// to the graph roots.
+func (g *Graph) AddAction(action *definitions.Action) *GraphNode {
+	node := &GraphNode{Name: action.Name, Type: "action", State: NodeStopped}
+	g.nodes["action:"+action.Name] = node
+
+	if action.Dependencies != nil {
+		for _, dep := range action.Dependencies.Services {
+			g.connect(node, definitions.TypeService, dep)
+		}
+	}
+	if action.Chain != "" {
+		g.connectChain(node, action.Chain)
+	}
+
+	g.Roots = append(g.Roots, node)
+	return node
+}
+
+func (g *Graph) service(name string) *GraphNode {
+	if node, ok := g.nodes[definitions.TypeService+":"+name]; ok {
+		return node
+	}
+	node := g.node(definitions.TypeService, name)
+
+	srv, err := loaders.ReadServiceDefinition(name)
+	if err != nil {
+		log.WithField("=>", name).Debugf("Cannot read service definition: %v", err)
+		node.State = NodeMissing
+		return node
+	}
+
+	if srv.Dependencies != nil {
+		for _, dep := range srv.Dependencies.Services {
+			g.connect(node, definitions.TypeService, dep)
+		}
+		for _, dep := range srv.Dependencies.Chains {
+			g.connect(node, definitions.TypeChain, dep)
+		}
+	}
+	if srv.Chain != "" {
+		g.connectChain(node, srv.Chain)
+	}
+	return node
+}
+
+func (g *Graph) chain(name string) *GraphNode {
+	if node, ok := g.nodes[definitions.TypeChain+":"+name]; ok {
+		return node
+	}
+	node := g.node(definitions.TypeChain, name)
+
+	chain, err := loaders.ReadChainDefinition(name)
+	if err != nil {
+		log.WithField("=>", name).Debugf("Cannot read chain definition: %v", err)
+		node.State = NodeMissing
+		return node
+	}
+
+	if chain.Dependencies != nil {
+		for _, dep := range chain.Dependencies.Services {
+			g.connect(node, definitions.TypeService, dep)
+		}
+		for _, dep := range chain.Dependencies.Chains {
+			g.connect(node, definitions.TypeChain, dep)
+		}
+	}
+	return node
+}
+
+// node registers a new node before its dependencies are resolved.
+func (g *Graph) node(typ, name string) *GraphNode {
+	node := &GraphNode{Name: name, Type: typ, State: NodeStopped}
+	if g.running[typ+":"+name] {
+		node.State = NodeRunning
+	}
+	g.nodes[typ+":"+name] = node
+	return node
+}
+
+func (g *Graph) connect(from *GraphNode, typ, nameAndOpts string) {
+	name, internalName, link, mount := util.ParseDependency(nameAndOpts)
+
+	var to *GraphNode
+	if typ == definitions.TypeChain {
+		to = g.chain(name)
+	} else {
+		to = g.service(name)
+	}
+	from.Edges = append(from.Edges, &GraphEdge{To: to, InternalName: internalName, Link: link, Mount: mount})
+}
+
+// connectChain resolves the chain a service or an action specifies
+// the same way ConnectChainToService does.
+func (g *Graph) connectChain(from *GraphNode, chainNameAndOpts string) {
+	name, internalName, link, mount := util.ParseDependency(chainNameAndOpts)
+	if g.chainName != "" {
+		name = g.chainName
+	} else if strings.HasPrefix(name, "$chain") {
+		if head, err := util.GetHead(); err == nil && head != "" {
+			name = head
+		}
+	}
+
+	var to *GraphNode
+	if strings.HasPrefix(name, "$chain") {
+		// No chain given and none checked out.
+		if to = g.nodes[definitions.TypeChain+":"+name]; to == nil {
+			to = g.node(definitions.TypeChain, name)
+			to.State = NodeMissing
+		}
+	} else {
+		to = g.chain(name)
+	}
+	from.Edges = append(from.Edges, &GraphEdge{To: to, InternalName: internalName, Link: link, Mount: mount})
+}
+
+// WriteTree writes the graph as a text tree. Dependencies shared by
+// several nodes are displayed under each of them; cycles are cut short.
+func (g *Graph) WriteTree(w io.Writer) error {
+	for _, root := range g.Roots {
+		if _, err := fmt.Fprintln(w, nodeLabel(root)); err != nil {
+			return err
+		}
+		if err := writeSubtree(w, root, "", map[*GraphNode]bool{root: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSubtree(w io.Writer, node *GraphNode, indent string, ancestors map[*GraphNode]bool) error {
+	for i, edge := range node.Edges {
+		branch, next := "|-- ", "|   "
+		if i == len(node.Edges)-1 {
+			branch, next = "`-- ", "    "
+		}
+
+		line := indent + branch + nodeLabel(edge.To) + " (" + edgeLabel(edge) + ")"
+		if ancestors[edge.To] {
+			line += " (cycle)"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		if ancestors[edge.To] {
+			continue
+		}
+
+		ancestors[edge.To] = true
+		if err := writeSubtree(w, edge.To, indent+next, ancestors); err != nil {
+			return err
+		}
+		delete(ancestors, edge.To)
+	}
+	return nil
+}
+
+// WriteDot writes the graph in the Graphviz DOT format. Running nodes
+// are green, stopped grey, and missing red; link edges are solid,
+// volumes-from edges dashed.
+func (g *Graph) WriteDot(w io.Writer) error {
+	colors := map[string]string{
+		NodeRunning: "palegreen",
+		NodeStopped: "lightgrey",
+		NodeMissing: "salmon",
+	}
+	shapes := map[string]string{
+		definitions.TypeService: "box",
+		definitions.TypeChain:   "hexagon",
+		"action":                "ellipse",
+	}
+
+	lines := []string{"digraph eris {"}
+	seen := make(map[*GraphNode]bool)
+	var walk func(*GraphNode)
+	walk = func(node *GraphNode) {
+		if seen[node] {
+			return
+		}
+		seen[node] = true
+
+		lines = append(lines, fmt.Sprintf("  %q [label=%q shape=%s style=filled fillcolor=%s];",
+			nodeID(node), node.Name+"\n"+node.State, shapes[node.Type], colors[node.State]))
+		for _, edge := range node.Edges {
+			style := "solid"
+			switch {
+			case !edge.Link && edge.Mount:
+				style = "dashed"
+			case !edge.Link && !edge.Mount:
+				style = "dotted"
+			}
+			lines = append(lines, fmt.Sprintf("  %q -> %q [label=%q style=%s];",
+				nodeID(node), nodeID(edge.To), edgeLabel(edge), style))
+		}
+		for _, edge := range node.Edges {
+			walk(edge.To)
+		}
+	}
+	for _, root := range g.Roots {
+		walk(root)
+	}
+	lines = append(lines, "}")
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// Write writes the graph either as a text tree (format is "" or "text")
+// or in the DOT format (format is "dot").
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "", "text", "tree":
+		return g.WriteTree(w)
+	case "dot":
+		return g.WriteDot(w)
+	default:
+		return fmt.Errorf("Unknown graph format %q (use text or dot)", format)
+	}
+}
+
+func nodeID(node *GraphNode) string {
+	return node.Type + ":" + node.Name
+}
+
+func nodeLabel(node *GraphNode) string {
+	return fmt.Sprintf("%s [%s, %s]", node.Name, node.Type, node.State)
+}
+
+func edgeLabel(edge *GraphEdge) string {
+	var kinds []string
+	if edge.Link {
+		kinds = append(kinds, "link as "+edge.InternalName)
+	}
+	if edge.Mount {
+		kinds = append(kinds, "volumes")
+	}
+	if len(kinds) == 0 {
+		return "start only"
+	}
+	return strings.Join(kinds, ", ")
+}
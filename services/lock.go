@@ -0,0 +1,82 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// LockImages writes the images lock file: it resolves the image of every
+// known service and chain definition to the digest of the local image
+// (pulling images which are not found locally). Containers are then only
+// created from images matching the lock file (see util.CheckImageLock).
+// Images referenced by digests (image@sha256:...) are not locked.
+// The lock file name is returned in do.Result.
+func LockImages(do *definitions.Do) error {
+	images := make(map[string]bool)
+	for _, name := range util.GetGlobalLevelConfigFilesByType("services", false) {
+		srv, err := loaders.LoadServiceDefinition(name, false)
+		if err != nil {
+			log.WithField("=>", name).Warnf("Skipping service: %v", err)
+			continue
+		}
+		images[srv.Service.Image] = true
+	}
+	for _, name := range util.GetGlobalLevelConfigFilesByType("chains", false) {
+		chain, err := loaders.LoadChainDefinition(name, false)
+		if err != nil {
+			log.WithField("=>", name).Warnf("Skipping chain: %v", err)
+			continue
+		}
+		images[chain.Service.Image] = true
+	}
+
+	var names []string
+	for image := range images {
+		if image != "" && !util.IsPinnedImage(image) {
+			names = append(names, image)
+		}
+	}
+	sort.Strings(names)
+
+	lock := &util.ImageLock{Images: make(map[string]*util.LockedImage)}
+	for _, image := range names {
+		metadata, err := perform.DockerInspectImage(image)
+		if err != nil {
+			return fmt.Errorf("Cannot resolve the image %s: %v", image, err)
+		}
+		lock.Images[util.NormalizeImage(image)] = util.LockImage(image, metadata)
+	}
+
+	if err := util.WriteImageLock(lock); err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+	table.SetHeader([]string{"IMAGE", "DIGEST"})
+	for _, image := range lock.LockedImages() {
+		digest := lock.Images[image].Digest
+		if digest == "" {
+			digest = lock.Images[image].ID + " (local)"
+		}
+		table.Append([]string{image, digest})
+	}
+	table.SetBorder(false)
+	table.SetCenterSeparator(" ")
+	table.SetColumnSeparator(" ")
+	table.SetRowSeparator("-")
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.Render()
+
+	log.WithField("=>", util.ImageLockFile()).Warn("Images locked")
+	do.Result = util.ImageLockFile()
+	return nil
+}
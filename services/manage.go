@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -48,6 +49,14 @@ func ImportService(do *definitions.Do) error {
 	return nil
 }
 
+// NewService writes a new service definition file. The image is inspected
+// (and pulled if it cannot be found locally) to prefill the ports, working
+// directory, user, entrypoint, command, and environment of the service.
+//
+//  do.Name                   - name of the service (required)
+//  do.Operations.Args        - image of the service (required)
+//  do.Operations.Interactive - ask the user to confirm each prefilled value
+//
 func NewService(do *definitions.Do) error {
 	srv := definitions.BlankServiceDefinition()
 	srv.Name = do.Name
@@ -62,14 +71,30 @@ func NewService(do *definitions.Do) error {
 		log.Debug(err.Error())
 	}
 
+	var volumes []string
+	if image, err := perform.DockerInspectImage(srv.Service.Image); err != nil {
+		log.WithField("image", srv.Service.Image).Warnf("Cannot inspect the image (%v). Not prefilling the definition", err)
+	} else {
+		volumes = scaffoldService(srv, image)
+	}
+
+	if do.Operations.Interactive {
+		if err := confirmScaffold(srv, volumes, wizardInput, config.GlobalConfig.Writer); err != nil {
+			return err
+		}
+	}
+
 	log.WithFields(log.Fields{
 		"service": srv.Service.Name,
 		"image":   srv.Service.Image,
 	}).Debug("Creating a new service definition file")
-	err = WriteServiceDefinitionFile(srv, filepath.Join(ServicesPath, do.Name+".toml"))
+	file, err := os.Create(filepath.Join(ServicesPath, do.Name+".toml"))
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+	writeServiceTOML(file, srv, scaffoldPlaceholders(srv, volumes))
+
 	do.Result = "success"
 	return nil
 }
@@ -207,6 +232,71 @@ To find known services use [eris services ls --known]`)
 	return nil
 }
 
+// ErrDrift is returned by DiffService and DiffChain if the container
+// doesn't match its definition file.
+var ErrDrift = errors.New("container has drifted from its definition file")
+
+// DiffService compares the service container with the container
+// the service definition file describes, field by field.
+// It returns ErrDrift if they differ (unless do.Fix is set).
+//
+//  do.Name      - name of the service (required)
+//  do.ChainName - chain to use if the service definition specifies $chain
+//  do.Fix       - recreate the container from the definition file on drift
+//  do.Timeout   - number of seconds to wait for the container to stop
+//
+func DiffService(do *definitions.Do) error {
+	service, err := loaders.LoadServiceDefinition(do.Name, false)
+	if err != nil {
+		return err
+	}
+	if util.FindServiceContainer(do.Name, true) == nil {
+		return fmt.Errorf("Service %s has no container. Start it with [eris services start %s]", do.Name, do.Name)
+	}
+
+	if service.Chain != "" {
+		if _, err := ConnectChainToService(do.ChainName, service.Chain, service); err != nil {
+			return err
+		}
+	}
+
+	drift, err := perform.DockerDiff(service.Service, service.Operations)
+	if err != nil {
+		return err
+	}
+	return ReportDrift(service, drift, do)
+}
+
+// ReportDrift displays the drift of a service or a chain container.
+// If do.Fix is set, the container is recreated; otherwise ErrDrift is
+// returned if there is any drift.
+func ReportDrift(srv *definitions.ServiceDefinition, drift []perform.Drift, do *definitions.Do) error {
+	if len(drift) == 0 {
+		log.WithField("=>", srv.Name).Warn("No drift detected")
+		do.Result = "no drift"
+		return nil
+	}
+
+	log.Warnf("%s has drifted from its definition file:", srv.Name)
+	for _, d := range drift {
+		log.Warnf("  %s", d.Field)
+		log.Warnf("    definition: %s", d.Expected)
+		log.Warnf("    container:  %s", d.Actual)
+	}
+
+	if !do.Fix {
+		do.Result = "drift"
+		return ErrDrift
+	}
+
+	log.WithField("=>", srv.Operations.SrvContainerName).Warn("Recreating container")
+	if err := RebuildContainer(srv, false, do.Timeout); err != nil {
+		return err
+	}
+	do.Result = "fixed"
+	return nil
+}
+
 func UpdateService(do *definitions.Do) error {
 	service, err := loaders.LoadServiceDefinition(do.Name, false)
 	if err != nil {
@@ -214,7 +304,7 @@ func UpdateService(do *definitions.Do) error {
 	}
 	service.Service.Environment = append(service.Service.Environment, do.Env...)
 	service.Service.Links = append(service.Service.Links, do.Links...)
-	err = perform.DockerRebuild(service.Service, service.Operations, do.Pull, do.Timeout)
+	err = RebuildContainer(service, do.Pull, do.Timeout)
 	if err != nil {
 		return err
 	}
@@ -252,6 +342,20 @@ func RmService(do *definitions.Do) error {
 }
 
 func CatService(do *definitions.Do) error {
+	if do.Format != "" {
+		serviceDef, err := loaders.ReadServiceDefinition(do.Name)
+		if err != nil {
+			return err
+		}
+		buf := new(bytes.Buffer)
+		if err := EncodeServiceDefinition(buf, serviceDef, do.Format); err != nil {
+			return err
+		}
+		do.Result = buf.String()
+		config.GlobalConfig.Writer.Write(buf.Bytes())
+		return nil
+	}
+
 	configs := util.GetGlobalLevelConfigFilesByType("services", true)
 	for _, c := range configs {
 		cName := strings.Split(filepath.Base(c), ".")[0]
@@ -268,6 +372,50 @@ func CatService(do *definitions.Do) error {
 	return fmt.Errorf("Unknown service %s or invalid file extension", do.Name)
 }
 
+func ConvertService(do *definitions.Do) error {
+	oldFile := FindServiceDefinitionFile(do.Name)
+	if oldFile == "" {
+		return fmt.Errorf("Unknown service %s or invalid file extension", do.Name)
+	}
+
+	var ext string
+	switch do.ConvertTo {
+	case "toml", "json", "yaml":
+		ext = "." + do.ConvertTo
+	case "yml":
+		ext = ".yaml"
+	case "":
+		ext = ".toml"
+	default:
+		return fmt.Errorf("Unknown format %q. Use toml, json, or yaml", do.ConvertTo)
+	}
+
+	if FormatFromExt(oldFile) == FormatFromExt(ext) {
+		log.WithField("=>", oldFile).Warn("Service definition file is already in that format")
+		return nil
+	}
+
+	serviceDef, err := loaders.ReadServiceDefinition(do.Name)
+	if err != nil {
+		return err
+	}
+
+	newFile := strings.TrimSuffix(oldFile, filepath.Ext(oldFile)) + ext
+	log.WithFields(log.Fields{
+		"from": oldFile,
+		"to":   newFile,
+	}).Info("Converting service definition file")
+	if err := WriteServiceDefinitionFile(serviceDef, newFile); err != nil {
+		return err
+	}
+
+	if err := os.Remove(oldFile); err != nil {
+		return err
+	}
+	do.Result = newFile
+	return nil
+}
+
 func InspectServiceByService(srv *definitions.Service, ops *definitions.Operation, field string) error {
 	err := perform.DockerInspect(srv, ops, field)
 	if err != nil {
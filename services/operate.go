@@ -3,9 +3,11 @@ package services
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/hooks"
 	"github.com/eris-ltd/eris-cli/loaders"
 	"github.com/eris-ltd/eris-cli/perform"
 	"github.com/eris-ltd/eris-cli/util"
@@ -23,6 +25,9 @@ func StartService(do *definitions.Do) (err error) {
 		if e != nil {
 			return e
 		}
+		if s[len(s)-1].Type == definitions.ServiceTypeJob {
+			return fmt.Errorf("Service %s is a job. Run it with [eris services run %s]", srv, srv)
+		}
 		services = append(services, s...)
 	}
 
@@ -66,9 +71,36 @@ func StartService(do *definitions.Do) (err error) {
 	topService.Service.Links = append(topService.Service.Links, do.Links...)
 	services[len(services)-1] = topService
 
+	if err := CheckMachineRequirements(services); err != nil {
+		return err
+	}
+
 	return StartGroup(services)
 }
 
+// CheckMachineRequirements evaluates the machine.requires preflight checks
+// of services which aren't running yet and returns a combined report of
+// the unmet ones as an error.
+func CheckMachineRequirements(services []*definitions.ServiceDefinition) error {
+	unmet := make(map[string][]string)
+	for _, s := range services {
+		if s.Machine == nil || len(s.Machine.Requires) == 0 {
+			continue
+		}
+		if s.Operations.ContainerType == definitions.TypeChain {
+			if util.FindChainContainer(s.Name, false) != nil {
+				continue
+			}
+		} else if IsServiceRunning(s.Service, s.Operations) {
+			continue
+		}
+		if u := util.UnmetRequirements(s.Machine.Requires, s.Operations); len(u) != 0 {
+			unmet[s.Name] = u
+		}
+	}
+	return util.RequirementsReport(unmet)
+}
+
 func KillService(do *definitions.Do) (err error) {
 	var services []*definitions.ServiceDefinition
 
@@ -88,11 +120,18 @@ func KillService(do *definitions.Do) (err error) {
 
 	for _, service := range services {
 		if IsServiceRunning(service.Service, service.Operations) {
+			if err := hooks.Run(definitions.HookPreStop, service.Name, service.Hooks, service.Service, service.Operations); err != nil {
+				return err
+			}
+
 			log.WithField("=>", service.Service.Name).Debug("Stopping service")
 			if err := perform.DockerStop(service.Service, service.Operations, do.Timeout); err != nil {
 				return err
 			}
 
+			if err := hooks.Run(definitions.HookPostStop, service.Name, service.Hooks, service.Service, service.Operations); err != nil {
+				return err
+			}
 		} else {
 			log.WithField("=>", service.Service.Name).Info("Service not currently running. Skipping")
 		}
@@ -107,6 +146,227 @@ func KillService(do *definitions.Do) (err error) {
 	return nil
 }
 
+// RestartService restarts services one after another in the order of
+// their dependency graph, waiting for each to become ready before moving on.
+// If the services are recreated, running services depending on them are
+// restarted as well, so that their links point to the new containers.
+//
+//  do.Operations.Args - names of the services to restart (required)
+//  do.All             - restart the dependencies of the services as well
+//  do.Recreate        - recreate containers instead of restarting them
+//  do.Pull            - pull an updated image before recreating (implies do.Recreate)
+//  do.Env             - environment variables to add to the services (implies do.Recreate)
+//  do.Links           - links to add to the services (implies do.Recreate)
+//  do.Timeout         - number of seconds to wait for a service to stop
+//
+func RestartService(do *definitions.Do) error {
+	var services []*definitions.ServiceDefinition
+
+	log.WithField("args", do.Operations.Args).Info("Building services group")
+	for _, servName := range do.Operations.Args {
+		group, err := BuildServicesGroup(servName)
+		if err != nil {
+			return err
+		}
+
+		// NOTE: the top level service is at the end of the list.
+		s := group[len(group)-1]
+		s.Service.Environment = append(s.Service.Environment, do.Env...)
+		s.Service.Links = append(s.Service.Links, do.Links...)
+
+		if do.All {
+			services = append(services, group...)
+		} else {
+			services = append(services, s)
+		}
+	}
+
+	if len(do.Env) != 0 || len(do.Links) != 0 {
+		do.Recreate = true
+	}
+
+	if err := CheckMachineRequirements(services); err != nil {
+		return err
+	}
+
+	recreated, err := RestartGroup(services, do.Recreate, do.Pull, do.Timeout)
+	if err != nil {
+		return err
+	}
+
+	if err := RestartDependents(recreated, do.Timeout); err != nil {
+		return err
+	}
+
+	do.Result = "success"
+	return nil
+}
+
+// RestartGroup restarts a group of services or chains in order. Every
+// instance of a service is restarted separately, one at a time, and each
+// restart waits for the container to become ready. Non-existent containers
+// are created and started. Restarts run the stop and start hooks.
+// RestartGroup returns the names of services or chains whose containers
+// were recreated.
+func RestartGroup(group []*definitions.ServiceDefinition, recreate, pull bool, timeout uint) ([]string, error) {
+	var recreated []string
+	seen := make(map[string]bool)
+
+	for _, srv := range group {
+		// Dependencies shared by several services are listed several times.
+		if seen[srv.Operations.ContainerType+srv.Name] {
+			continue
+		}
+		seen[srv.Operations.ContainerType+srv.Name] = true
+
+		names := instances(srv)
+		if len(names) == 0 {
+			log.WithField("=>", srv.Name).Warn("Container does not exist. Starting")
+			if err := startWithHooks(srv, srv.Operations); err != nil {
+				return nil, err
+			}
+			recreated = append(recreated, srv.Name)
+			continue
+		}
+
+		for _, name := range names {
+			ops := *srv.Operations
+			ops.SrvContainerName = name
+			ops.SrvContainerID = ""
+
+			log.WithField("=>", name).Warn("Restarting")
+			if err := stopWithHooks(srv, &ops, timeout); err != nil {
+				return nil, err
+			}
+			if recreate || pull {
+				if err := perform.DockerRebuild(srv.Service, &ops, pull, timeout); err != nil {
+					return nil, fmt.Errorf("Error recreating %s: %v", name, err)
+				}
+			}
+			if err := startWithHooks(srv, &ops); err != nil {
+				return nil, err
+			}
+		}
+
+		if recreate || pull {
+			recreated = append(recreated, srv.Name)
+		}
+	}
+
+	return recreated, nil
+}
+
+// RebuildContainer recreates the container of a service or a chain
+// (see perform.DockerRebuild). A running container is stopped and started
+// again with the stop and start hooks.
+func RebuildContainer(srv *definitions.ServiceDefinition, pull bool, timeout uint) error {
+	_, running := perform.ContainerRunning(srv.Operations)
+	if err := stopWithHooks(srv, srv.Operations, timeout); err != nil {
+		return err
+	}
+	if err := perform.DockerRebuild(srv.Service, srv.Operations, pull, timeout); err != nil {
+		return err
+	}
+	if !running {
+		return nil
+	}
+	return startWithHooks(srv, srv.Operations)
+}
+
+// stopWithHooks stops the ops.SrvContainerName container of the service
+// or chain if it's running, with the pre_stop and post_stop hooks.
+func stopWithHooks(srv *definitions.ServiceDefinition, ops *definitions.Operation, timeout uint) error {
+	if _, running := perform.ContainerRunning(ops); !running {
+		return nil
+	}
+	if err := hooks.Run(definitions.HookPreStop, srv.Name, srv.Hooks, srv.Service, ops); err != nil {
+		return err
+	}
+	if err := perform.DockerStop(srv.Service, ops, timeout); err != nil {
+		return fmt.Errorf("Error stopping %s: %v", ops.SrvContainerName, err)
+	}
+	return hooks.Run(definitions.HookPostStop, srv.Name, srv.Hooks, srv.Service, ops)
+}
+
+// startWithHooks starts the ops.SrvContainerName container of the service
+// or chain (creating it if needed) and waits for it to become ready, with
+// the pre_start and post_start hooks.
+func startWithHooks(srv *definitions.ServiceDefinition, ops *definitions.Operation) error {
+	if err := hooks.Run(definitions.HookPreStart, srv.Name, srv.Hooks, srv.Service, ops); err != nil {
+		return err
+	}
+	if err := perform.DockerRunService(srv.Service, ops); err != nil {
+		return fmt.Errorf("Error starting %s: %v", ops.SrvContainerName, err)
+	}
+	if err := perform.DockerWaitReady(ops, perform.ReadyTimeout); err != nil {
+		return err
+	}
+	return hooks.Run(definitions.HookPostStart, srv.Name, srv.Hooks, srv.Service, ops)
+}
+
+// RestartDependents restarts running services which depend on (or link to)
+// the recreated services or chains, so that their links get refreshed.
+func RestartDependents(recreated []string, timeout uint) error {
+	if len(recreated) == 0 {
+		return nil
+	}
+
+	changed := make(map[string]bool)
+	for _, name := range recreated {
+		changed[name] = true
+	}
+
+	var dependents []*definitions.ServiceDefinition
+	for _, name := range util.GetGlobalLevelConfigFilesByType("services", false) {
+		if changed[name] || util.FindServiceContainer(name, false) == nil {
+			continue
+		}
+
+		group, err := BuildServicesGroup(name)
+		if err != nil {
+			log.WithField("=>", name).Debugf("Skipping dependent: %v", err)
+			continue
+		}
+
+		srv := group[len(group)-1]
+		depends := false
+		for _, s := range group[:len(group)-1] {
+			depends = depends || changed[s.Name]
+		}
+		if srv.Chain != "" {
+			chainName, _, _, _ := util.ParseDependency(srv.Chain)
+			if strings.HasPrefix(chainName, "$chain") {
+				chainName, _ = util.GetHead()
+			}
+			depends = depends || changed[chainName]
+		}
+
+		if depends {
+			dependents = append(dependents, srv)
+		}
+	}
+
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	log.WithField("services", len(dependents)).Info("Restarting dependent services")
+	_, err := RestartGroup(dependents, false, false, timeout)
+	return err
+}
+
+// instances returns the container names of each instance of a service or chain.
+func instances(srv *definitions.ServiceDefinition) []string {
+	var names []string
+	for _, c := range util.ErisContainersByType(srv.Operations.ContainerType, true) {
+		if c.ShortName == srv.Name {
+			names = append(names, c.FullName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 func ExecService(do *definitions.Do) (buf *bytes.Buffer, err error) {
 	service, err := loaders.LoadServiceDefinition(do.Name, false)
 	if err != nil {
@@ -177,10 +437,24 @@ func BuildServicesGroup(srvName string, services ...*definitions.ServiceDefiniti
 func StartGroup(group []*definitions.ServiceDefinition) error {
 	log.WithField("services#", len(group)).Debug("Starting services group")
 	for _, srv := range group {
+		// Hooks only run if the container is actually started.
+		_, running := perform.ContainerRunning(srv.Operations)
+		if !running {
+			if err := hooks.Run(definitions.HookPreStart, srv.Name, srv.Hooks, srv.Service, srv.Operations); err != nil {
+				return err
+			}
+		}
+
 		log.WithField("=>", srv.Name).Debug("Performing container start")
 		if err := perform.DockerRunService(srv.Service, srv.Operations); err != nil {
 			return fmt.Errorf("Error starting service %s: %v", srv.Name, err)
 		}
+
+		if !running {
+			if err := hooks.Run(definitions.HookPostStart, srv.Name, srv.Hooks, srv.Service, srv.Operations); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
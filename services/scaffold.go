@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/definitions"
+
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// Where the --interactive wizard of [eris services new] reads answers from.
+var wizardInput io.Reader = os.Stdin
+
+// scaffoldService prefills the service definition from the image metadata:
+// ports from exposed ports, the working directory, user, entrypoint,
+// command, and environment defaults. It returns the volumes the image
+// declares (they are kept in the data container by default).
+func scaffoldService(srv *definitions.ServiceDefinition, image *docker.Image) (volumes []string) {
+	if image == nil || image.Config == nil {
+		return nil
+	}
+	config := image.Config
+
+	for port := range config.ExposedPorts {
+		p := port.Port() + ":" + port.Port()
+		if port.Proto() != "" && port.Proto() != "tcp" {
+			p += "/" + port.Proto()
+		}
+		srv.Service.Ports = append(srv.Service.Ports, p)
+	}
+	sort.Strings(srv.Service.Ports)
+
+	for volume := range config.Volumes {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+
+	for _, env := range config.Env {
+		// PATH is always set by the image.
+		if !strings.HasPrefix(env, "PATH=") {
+			srv.Service.Environment = append(srv.Service.Environment, env)
+		}
+	}
+
+	srv.Service.WorkDir = config.WorkingDir
+	srv.Service.User = config.User
+
+	// Entrypoint and command are split on white space when the container
+	// is created, so arguments containing spaces can't be written down.
+	if entrypoint, ok := joinArgs(config.Entrypoint); ok {
+		srv.Service.EntryPoint = entrypoint
+	}
+	if command, ok := joinArgs(config.Cmd); ok {
+		srv.Service.Command = command
+	}
+
+	return volumes
+}
+
+// scaffoldPlaceholders returns commented TOML placeholders for the
+// [service] section, including binds for the declared volumes which
+// are not bound to host directories yet.
+func scaffoldPlaceholders(srv *definitions.ServiceDefinition, declared []string) []string {
+	var placeholders, volumes []string
+
+	for _, volume := range declared {
+		bound := false
+		for _, bind := range srv.Service.Volumes {
+			if strings.HasSuffix(bind, ":"+volume) {
+				bound = true
+			}
+		}
+		if !bound {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	if len(volumes) != 0 {
+		placeholders = append(placeholders,
+			"# The image declares the "+strings.Join(volumes, ", ")+" volume(s),",
+			"# which are kept in the data container. To bind them to host",
+			"# directories instead, uncomment and edit:",
+		)
+		var binds []string
+		for _, volume := range volumes {
+			binds = append(binds, fmt.Sprintf("%q", "$eris/data/"+srv.Name+volume+":"+volume))
+		}
+		placeholders = append(placeholders, "# volumes = [ "+strings.Join(binds, ", ")+" ]")
+	}
+	if len(srv.Service.Environment) == 0 {
+		placeholders = append(placeholders, `# environment = [ "KEY=value" ]`)
+	}
+	placeholders = append(placeholders,
+		`# links = [ "eris_service_keys_1:keys" ]`,
+		`# secrets = [ "SECRET_NAME" ]`,
+	)
+	return placeholders
+}
+
+func joinArgs(args []string) (string, bool) {
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t\n") {
+			return "", false
+		}
+	}
+	return strings.Join(args, " "), true
+}
+
+// confirmScaffold asks the user to confirm or change each prefilled value
+// of the service definition. An empty answer keeps the value; a dash
+// ("-") clears it. Lists are given as space separated values.
+func confirmScaffold(srv *definitions.ServiceDefinition, volumes []string, in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	ask := func(question, value string) (string, error) {
+		fmt.Fprintf(out, "%s [%s]: ", question, value)
+		// Values are kept if the input ends early.
+		answer, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("Error reading from stdin: %v", err)
+		}
+
+		switch answer = strings.TrimSpace(answer); answer {
+		case "":
+			return value, nil
+		case "-":
+			return "", nil
+		}
+		return answer, nil
+	}
+	askList := func(question string, values []string) ([]string, error) {
+		answer, err := ask(question, strings.Join(values, " "))
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(answer), nil
+	}
+
+	var err error
+	if srv.Service.Image, err = ask("Image", srv.Service.Image); err != nil {
+		return err
+	}
+	if srv.Service.Image == "" {
+		return fmt.Errorf("An image is required for the service definition file")
+	}
+	if srv.Service.Ports, err = askList("Ports (host:container)", srv.Service.Ports); err != nil {
+		return err
+	}
+
+	for _, volume := range volumes {
+		host, err := ask("Host directory to bind "+volume+" to (empty keeps it in the data container)", "")
+		if err != nil {
+			return err
+		}
+		if host != "" {
+			srv.Service.Volumes = append(srv.Service.Volumes, host+":"+volume)
+		}
+	}
+
+	var environment []string
+	for _, env := range srv.Service.Environment {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value, err := ask(parts[0], parts[1])
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			environment = append(environment, parts[0]+"="+value)
+		}
+	}
+	srv.Service.Environment = environment
+
+	for _, field := range []struct {
+		question string
+		value    *string
+	}{
+		{"Working directory", &srv.Service.WorkDir},
+		{"User", &srv.Service.User},
+		{"Entrypoint", &srv.Service.EntryPoint},
+		{"Command", &srv.Service.Command},
+	} {
+		if *field.value, err = ask(field.question, *field.value); err != nil {
+			return err
+		}
+	}
+
+	data := "y"
+	if !srv.Service.AutoData {
+		data = "n"
+	}
+	if data, err = ask("Use a data container (y/n)", data); err != nil {
+		return err
+	}
+	srv.Service.AutoData = strings.HasPrefix(strings.ToLower(data), "y")
+
+	return nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
@@ -20,7 +21,9 @@ import (
 	ver "github.com/eris-ltd/eris-cli/version"
 
 	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
 	logger "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
 )
 
 const servName = "ipfs"
@@ -212,6 +215,259 @@ func TestUpdateService(t *testing.T) {
 	}
 }
 
+func TestRestartService(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	start(t, servName, false)
+	name := util.ContainersName(def.TypeService, servName)
+	before, err := util.DockerClient.InspectContainer(name)
+	if err != nil {
+		t.Fatalf("expected service container, got %v", err)
+	}
+
+	do := def.NowDo()
+	do.Operations.Args = []string{servName}
+	do.Timeout = 1
+	if err := RestartService(do); err != nil {
+		t.Fatalf("expected service to be restarted, got %v", err)
+	}
+
+	after, err := util.DockerClient.InspectContainer(name)
+	if err != nil {
+		t.Fatalf("expected service container, got %v", err)
+	}
+	if after.ID != before.ID {
+		t.Fatalf("expected the same container restarted, got a new one")
+	}
+	if !after.State.Running || !after.State.StartedAt.After(before.State.StartedAt) {
+		t.Fatalf("expected service container restarted, got %v", after.State)
+	}
+
+	do = def.NowDo()
+	do.Operations.Args = []string{servName}
+	do.Env = []string{"MARMOTS=restarted"}
+	do.Timeout = 1
+	if err := RestartService(do); err != nil {
+		t.Fatalf("expected service to be recreated, got %v", err)
+	}
+
+	recreated, err := util.DockerClient.InspectContainer(name)
+	if err != nil {
+		t.Fatalf("expected service container, got %v", err)
+	}
+	if recreated.ID == after.ID {
+		t.Fatalf("expected a new container, got the same one")
+	}
+	if n := util.HowManyContainersRunning(servName, def.TypeService); n != 1 {
+		t.Fatalf("expecting 1 running service container, got %v", n)
+	}
+}
+
+func TestRestartServiceUnknown(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	do := def.NowDo()
+	do.Operations.Args = []string{"no-such-service-for-the-marmots"}
+	if err := RestartService(do); err == nil {
+		t.Fatalf("expected restart of an unknown service to fail, got nil")
+	}
+}
+
+func TestDiffService(t *testing.T) {
+	const name = "drift"
+
+	defer tests.RemoveAllContainers()
+
+	srv := def.BlankServiceDefinition()
+	srv.Name = name
+	srv.Service.Name = name
+	srv.Service.Image = path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_IPFS)
+	srv.Service.Environment = []string{"MARMOTS=happy"}
+	if err := WriteServiceDefinitionFile(srv, ""); err != nil {
+		t.Fatalf("expected service definition file written, got %v", err)
+	}
+	defer os.Remove(filepath.Join(config.GlobalConfig.ErisDir, "services", name+".toml"))
+
+	start(t, name, false)
+
+	do := def.NowDo()
+	do.Name = name
+	if err := DiffService(do); err != nil {
+		t.Fatalf("expected no drift, got %v", err)
+	}
+
+	srv.Service.Environment = []string{"MARMOTS=grumpy"}
+	if err := WriteServiceDefinitionFile(srv, ""); err != nil {
+		t.Fatalf("expected service definition file written, got %v", err)
+	}
+
+	do = def.NowDo()
+	do.Name = name
+	if err := DiffService(do); err != ErrDrift {
+		t.Fatalf("expected %v, got %v", ErrDrift, err)
+	}
+
+	do = def.NowDo()
+	do.Name = name
+	do.Fix = true
+	do.Timeout = 1
+	if err := DiffService(do); err != nil || do.Result != "fixed" {
+		t.Fatalf("expected drift fixed, got %v (%v)", do.Result, err)
+	}
+
+	do = def.NowDo()
+	do.Name = name
+	if err := DiffService(do); err != nil {
+		t.Fatalf("expected no drift after fix, got %v", err)
+	}
+}
+
+func TestRestartServiceHooks(t *testing.T) {
+	const name = "hooked"
+
+	defer tests.RemoveAllContainers()
+
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatalf("expected a temp dir, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	srv := def.BlankServiceDefinition()
+	srv.Name = name
+	srv.Service.Name = name
+	srv.Service.Image = path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_IPFS)
+	srv.Service.Environment = []string{"MARMOTS=happy"}
+	for _, event := range []*[]*def.Hook{&srv.Hooks.PreStop, &srv.Hooks.PostStop, &srv.Hooks.PreStart, &srv.Hooks.PostStart} {
+		*event = []*def.Hook{{Command: "echo $ERIS_HOOK >> " + out}}
+	}
+	if err := WriteServiceDefinitionFile(srv, ""); err != nil {
+		t.Fatalf("expected service definition file written, got %v", err)
+	}
+	defer os.Remove(filepath.Join(config.GlobalConfig.ErisDir, "services", name+".toml"))
+
+	start(t, name, false)
+	os.Remove(out)
+
+	expected := "pre_stop\npost_stop\npre_start\npost_start\n"
+	do := def.NowDo()
+	do.Operations.Args = []string{name}
+	do.Timeout = 1
+	if err := RestartService(do); err != nil {
+		t.Fatalf("expected service to be restarted, got %v", err)
+	}
+	if contents, _ := ioutil.ReadFile(out); string(contents) != expected {
+		t.Fatalf("expected restart hooks %q, got %q", expected, contents)
+	}
+	os.Remove(out)
+
+	srv.Service.Environment = []string{"MARMOTS=grumpy"}
+	if err := WriteServiceDefinitionFile(srv, ""); err != nil {
+		t.Fatalf("expected service definition file written, got %v", err)
+	}
+	do = def.NowDo()
+	do.Name = name
+	do.Fix = true
+	do.Timeout = 1
+	if err := DiffService(do); err != nil || do.Result != "fixed" {
+		t.Fatalf("expected drift fixed, got %v (%v)", do.Result, err)
+	}
+	if contents, _ := ioutil.ReadFile(out); string(contents) != expected {
+		t.Fatalf("expected rebuild hooks %q, got %q", expected, contents)
+	}
+}
+
+func TestGraphService(t *testing.T) {
+	defs := map[string]string{
+		"graph_a": `chain = "$chain:chain:l"
+[service]
+image = "busybox"
+[dependencies]
+services = [ "graph_b:bee:m", "graph_c" ]
+`,
+		"graph_b": `[service]
+image = "busybox"
+[dependencies]
+services = [ "graph_a:a:_" ]
+`,
+	}
+	for name, definition := range defs {
+		file := filepath.Join(config.GlobalConfig.ErisDir, "services", name+".toml")
+		if err := ioutil.WriteFile(file, []byte(definition), 0644); err != nil {
+			t.Fatalf("expected service definition file written, got %v", err)
+		}
+		defer os.Remove(file)
+	}
+
+	do := def.NowDo()
+	do.Operations.Args = []string{"graph_a"}
+	do.ChainName = "graph_chain"
+	if err := GraphService(do); err != nil {
+		t.Fatalf("expected service graph, got %v", err)
+	}
+
+	expected := []string{
+		"graph_a [service, stopped]",
+		"|-- graph_b [service, stopped] (volumes)",
+		"|   `-- graph_a [service, stopped] (start only) (cycle)",
+		"|-- graph_c [service, missing] (link as graph_c, volumes)",
+		"`-- graph_chain [chain, missing] (link as chain)",
+	}
+	if strings.TrimSpace(do.Result) != strings.Join(expected, "\n") {
+		t.Fatalf("expected graph\n%s\ngot\n%s", strings.Join(expected, "\n"), do.Result)
+	}
+
+	do.GraphFormat = "dot"
+	if err := GraphService(do); err != nil {
+		t.Fatalf("expected service graph, got %v", err)
+	}
+	for _, line := range []string{
+		`"service:graph_a" -> "service:graph_b" [label="volumes" style=dashed];`,
+		`"service:graph_b" -> "service:graph_a" [label="start only" style=dotted];`,
+		`"service:graph_a" -> "chain:graph_chain" [label="link as chain" style=solid];`,
+	} {
+		if !strings.Contains(do.Result, line) {
+			t.Fatalf("expected %s in the DOT graph, got %s", line, do.Result)
+		}
+	}
+
+	do.GraphFormat = "png"
+	if err := GraphService(do); err == nil {
+		t.Fatalf("expected unknown format error, got nil")
+	}
+}
+
+func TestStartServiceMachineRequirements(t *testing.T) {
+	const name = "requirements"
+
+	defer tests.RemoveAllContainers()
+
+	srv := def.BlankServiceDefinition()
+	srv.Name = name
+	srv.Service.Name = name
+	srv.Service.Image = path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_IPFS)
+	srv.Machine.Requires = []string{"binary:no-such-binary-for-the-marmots", "flux capacitor"}
+	if err := WriteServiceDefinitionFile(srv, ""); err != nil {
+		t.Fatalf("expected service definition file written, got %v", err)
+	}
+	defer os.Remove(filepath.Join(config.GlobalConfig.ErisDir, "services", name+".toml"))
+
+	do := def.NowDo()
+	do.Operations.Args = []string{name}
+	err := StartService(do)
+	if err == nil {
+		t.Fatalf("expected service start to fail, got nil")
+	}
+	if !strings.Contains(err.Error(), "no-such-binary") || !strings.Contains(err.Error(), "flux capacitor") {
+		t.Fatalf("expected both unmet requirements reported, got %v", err)
+	}
+
+	if n := util.HowManyContainersExisting(name, def.TypeService); n != 0 {
+		t.Fatalf("expecting 0 service containers, got %v", n)
+	}
+}
+
 func TestKillService(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -383,6 +639,103 @@ func TestNewService(t *testing.T) {
 
 }
 
+func TestNewServiceScaffold(t *testing.T) {
+	const name = "scaffold"
+
+	do := def.NowDo()
+	do.Name = name
+	do.Operations.Args = []string{path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_IPFS)}
+	if err := NewService(do); err != nil {
+		t.Fatalf("expected a new service to be created, got %v", err)
+	}
+	file := filepath.Join(config.GlobalConfig.ErisDir, "services", name+".toml")
+	defer os.Remove(file)
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("expected service definition file, got %v", err)
+	}
+	if !strings.Contains(string(contents), "# links = ") {
+		t.Fatalf("expected commented placeholders, got %s", contents)
+	}
+
+	srv, err := loaders.LoadServiceDefinition(name, false)
+	if err != nil {
+		t.Fatalf("expected service definition loaded, got %v", err)
+	}
+	if len(srv.Service.Ports) == 0 {
+		t.Fatalf("expected ports prefilled from the image, got none")
+	}
+}
+
+func TestScaffoldService(t *testing.T) {
+	srv := def.BlankServiceDefinition()
+	srv.Name = "scaffold"
+
+	volumes := scaffoldService(srv, &docker.Image{Config: &docker.Config{
+		ExposedPorts: map[docker.Port]struct{}{"5001/tcp": {}, "53/udp": {}},
+		Volumes:      map[string]struct{}{"/data": {}},
+		Env:          []string{"PATH=/bin", "MARMOTS=happy"},
+		WorkingDir:   "/home",
+		User:         "eris",
+		Entrypoint:   []string{"sh", "-c", "echo hi"},
+		Cmd:          []string{"start", "--all"},
+	}})
+
+	if !reflect.DeepEqual(srv.Service.Ports, []string{"5001:5001", "53:53/udp"}) {
+		t.Fatalf("expected ports prefilled, got %v", srv.Service.Ports)
+	}
+	if !reflect.DeepEqual(volumes, []string{"/data"}) {
+		t.Fatalf("expected /data volume, got %v", volumes)
+	}
+	if !reflect.DeepEqual(srv.Service.Environment, []string{"MARMOTS=happy"}) {
+		t.Fatalf("expected environment without PATH, got %v", srv.Service.Environment)
+	}
+	if srv.Service.WorkDir != "/home" || srv.Service.User != "eris" {
+		t.Fatalf("expected work dir and user prefilled, got %q, %q", srv.Service.WorkDir, srv.Service.User)
+	}
+	if srv.Service.EntryPoint != "" || srv.Service.Command != "start --all" {
+		t.Fatalf("expected command only prefilled, got %q, %q", srv.Service.EntryPoint, srv.Service.Command)
+	}
+
+	placeholders := strings.Join(scaffoldPlaceholders(srv, volumes), "\n")
+	if !strings.Contains(placeholders, `# volumes = [ "$eris/data/scaffold/data:/data" ]`) {
+		t.Fatalf("expected volumes placeholder, got %v", placeholders)
+	}
+}
+
+func TestConfirmScaffold(t *testing.T) {
+	srv := def.BlankServiceDefinition()
+	srv.Service.Image = "eris/ipfs"
+	srv.Service.Ports = []string{"4001:4001"}
+	srv.Service.Environment = []string{"A=1", "B=2"}
+	srv.Service.User = "eris"
+	srv.Service.AutoData = true
+
+	// Image, ports, /data volume, A, B, work dir, user; the rest is kept.
+	in := strings.NewReader("\n4001:4001 8080:8080\n/srv/data\n\n-\n/home\n-\n")
+	if err := confirmScaffold(srv, []string{"/data"}, in, new(bytes.Buffer)); err != nil {
+		t.Fatalf("expected values confirmed, got %v", err)
+	}
+
+	if srv.Service.Image != "eris/ipfs" {
+		t.Fatalf("expected image kept, got %v", srv.Service.Image)
+	}
+	if !reflect.DeepEqual(srv.Service.Ports, []string{"4001:4001", "8080:8080"}) {
+		t.Fatalf("expected ports changed, got %v", srv.Service.Ports)
+	}
+	if !reflect.DeepEqual(srv.Service.Volumes, []string{"/srv/data:/data"}) {
+		t.Fatalf("expected volume bound, got %v", srv.Service.Volumes)
+	}
+	if !reflect.DeepEqual(srv.Service.Environment, []string{"A=1"}) {
+		t.Fatalf("expected B cleared, got %v", srv.Service.Environment)
+	}
+	if srv.Service.WorkDir != "/home" || srv.Service.User != "" || !srv.Service.AutoData {
+		t.Fatalf("expected work dir set, user cleared, data container kept, got %q, %q, %v",
+			srv.Service.WorkDir, srv.Service.User, srv.Service.AutoData)
+	}
+}
+
 func TestRenameService(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
@@ -447,6 +800,205 @@ func TestCatService(t *testing.T) {
 	}
 }
 
+func TestCatServiceFormat(t *testing.T) {
+	for _, format := range []string{"json", "yaml", "toml"} {
+		do := def.NowDo()
+		do.Name = servName
+		do.Format = format
+		if err := CatService(do); err != nil {
+			t.Fatalf("expected cat --format %v to succeed, got %v", format, err)
+		}
+
+		if !strings.Contains(do.Result, "image") || !strings.Contains(do.Result, servName) {
+			t.Fatalf("expected %v service definition to be returned, got %v", format, do.Result)
+		}
+	}
+
+	do := def.NowDo()
+	do.Name = servName
+	do.Format = "xml"
+	if err := CatService(do); err == nil {
+		t.Fatalf("expected cat --format xml to fail, got nil")
+	}
+}
+
+func TestConvertService(t *testing.T) {
+	const name = "converted"
+
+	do := def.NowDo()
+	do.Name = name
+	do.Operations.Args = []string{path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_IPFS)}
+	if err := NewService(do); err != nil {
+		t.Fatalf("expected a new service to be created, got %v", err)
+	}
+	defer os.Remove(filepath.Join(config.GlobalConfig.ErisDir, "services", name+".yaml"))
+
+	before, err := loaders.ReadServiceDefinition(name)
+	if err != nil {
+		t.Fatalf("expected service definition to be read, got %v", err)
+	}
+
+	do = def.NowDo()
+	do.Name = name
+	do.ConvertTo = "yaml"
+	if err := ConvertService(do); err != nil {
+		t.Fatalf("expected service definition to be converted, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(config.GlobalConfig.ErisDir, "services", name+".toml")); !os.IsNotExist(err) {
+		t.Fatalf("expected the old service definition file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.GlobalConfig.ErisDir, "services", name+".yaml")); err != nil {
+		t.Fatalf("expected the new service definition file to exist, got %v", err)
+	}
+
+	after, err := loaders.ReadServiceDefinition(name)
+	if err != nil {
+		t.Fatalf("expected converted service definition to be read, got %v", err)
+	}
+	if !reflect.DeepEqual(before.Service, after.Service) {
+		t.Fatalf("expected service definitions to match, got %v and %v", before.Service, after.Service)
+	}
+}
+
+func TestWriteServiceDefinitionTOML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_services")
+	if err != nil {
+		t.Fatalf("expected a temp dir, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(path string) { common.ServicesPath = path }(common.ServicesPath)
+	common.ServicesPath = dir
+
+	srv := def.BlankServiceDefinition()
+	srv.Name = "hooked"
+	srv.Service.Name = "hooked"
+	srv.Service.Image = "busybox"
+	srv.Type = def.ServiceTypeJob
+	srv.Schedule = "*/15 * * * *"
+	srv.Hooks.PreStart = []*def.Hook{{Command: "echo starting"}}
+	srv.Hooks.PostStop = []*def.Hook{{Action: "dns deregister", OnFailure: def.HookWarn}}
+	if err := WriteServiceDefinitionFile(srv, ""); err != nil {
+		t.Fatalf("expected service definition file written, got %v", err)
+	}
+
+	read, err := loaders.ReadServiceDefinition("hooked")
+	if err != nil {
+		t.Fatalf("expected service definition to be read, got %v", err)
+	}
+	if read.Type != def.ServiceTypeJob || read.Schedule != srv.Schedule {
+		t.Fatalf("expected a job scheduled at %q, got %q at %q", srv.Schedule, read.Type, read.Schedule)
+	}
+	if !reflect.DeepEqual(read.Hooks, srv.Hooks) {
+		t.Fatalf("expected hooks %+v, got %+v", srv.Hooks, read.Hooks)
+	}
+}
+
+func TestSearchServices(t *testing.T) {
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		t.Fatalf("expected a temporary directory, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	index := `{"services": [
+		{"name": "ipfs", "description": "Distributed file system", "tags": ["storage"]},
+		{"name": "ethereum", "description": "Blockchain node", "tags": ["chain", "p2p"], "version": "1.3"},
+		{"name": "postgres", "description": "Relational database", "tags": ["storage", "sql"]}
+	]}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.json"), []byte(index), 0644); err != nil {
+		t.Fatalf("expected index to be written, got %v", err)
+	}
+
+	for _, test := range []struct {
+		query []string
+		want  string
+	}{
+		{[]string{"STORAGE"}, "ipfs,postgres"},
+		{[]string{"storage", "sql"}, "postgres"},
+		{[]string{"p2p"}, "ethereum"},
+		{[]string{"no-such-service"}, ""},
+	} {
+		do := def.NowDo()
+		do.Index = dir
+		do.Operations.Args = test.query
+		if err := SearchServices(do); err != nil {
+			t.Fatalf("expected search to succeed, got %v", err)
+		}
+		if do.Result != test.want {
+			t.Fatalf("%v: expected %q, got %q", test.query, test.want, do.Result)
+		}
+	}
+
+	entries, err := RemoteCatalog(dir)
+	if err != nil {
+		t.Fatalf("expected index to be read, got %v", err)
+	}
+	for _, entry := range MergeCatalogs(nil, entries) {
+		if installed := entry.Name == servName; entry.Installed != installed {
+			t.Fatalf("%v: expected installed %v, got %v", entry.Name, installed, entry.Installed)
+		}
+	}
+}
+
+func TestInstallService(t *testing.T) {
+	const name = "installed"
+	defer os.Remove(filepath.Join(config.GlobalConfig.ErisDir, "services", name+".toml"))
+
+	definition := tests.NewServer()
+	definition.SetResponse(tests.ServerResponse{
+		Code: http.StatusOK,
+		Body: `name = "` + name + `"
+description = "Installed from the index"
+tags = [ "storage" ]
+
+[service]
+image = "` + path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_IPFS) + `"`,
+	})
+	defer definition.Close()
+
+	index := tests.NewServer()
+	index.SetResponse(tests.ServerResponse{
+		Code: http.StatusOK,
+		Body: `{"services": [{"name": "` + name + `", "file": "` + definition.URL() + `/` + name + `.toml"}]}`,
+	})
+	defer index.Close()
+
+	do := def.NowDo()
+	do.Name = name
+	do.Index = index.URL() + "/index.json"
+	if err := InstallService(do); err != nil {
+		t.Fatalf("expected service to be installed, got %v", err)
+	}
+	if expected := "/" + name + ".toml"; definition.Path() != expected {
+		t.Fatalf("called the wrong endpoint; expected %v, got %v", expected, definition.Path())
+	}
+
+	srv, err := loaders.ReadServiceDefinition(name)
+	if err != nil {
+		t.Fatalf("expected installed service definition to be read, got %v", err)
+	}
+	if srv.Description != "Installed from the index" || !reflect.DeepEqual(srv.Tags, []string{"storage"}) {
+		t.Fatalf("expected service metadata to be installed, got %v %v", srv.Description, srv.Tags)
+	}
+
+	if err := InstallService(do); err == nil {
+		t.Fatalf("expected second install without --force to fail, got nil")
+	}
+	do.Force = true
+	if err := InstallService(do); err != nil {
+		t.Fatalf("expected install with --force to succeed, got %v", err)
+	}
+
+	definition.SetResponse(tests.ServerResponse{Code: http.StatusOK, Body: "not = [ toml"})
+	if err := InstallService(do); err == nil {
+		t.Fatalf("expected bad service definition to fail, got nil")
+	}
+	if _, err := loaders.ReadServiceDefinition(name); err != nil {
+		t.Fatalf("expected installed service definition to be kept, got %v", err)
+	}
+}
+
 func TestStartKillServiceWithDependencies(t *testing.T) {
 	defer tests.RemoveAllContainers()
 
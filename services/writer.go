@@ -2,8 +2,11 @@ package services
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	def "github.com/eris-ltd/eris-cli/definitions"
 
@@ -29,28 +32,55 @@ func WriteServiceDefinitionFile(serviceDef *def.ServiceDefinition, fileName stri
 		return err
 	}
 
-	switch filepath.Ext(fileName) {
-	case ".json":
+	return EncodeServiceDefinition(writer, serviceDef, FormatFromExt(fileName))
+}
+
+// EncodeServiceDefinition writes the service definition to writer in
+// the given format: "json", "yaml", or "toml" (default, if empty).
+func EncodeServiceDefinition(writer io.Writer, serviceDef *def.ServiceDefinition, format string) error {
+	switch format {
+	case "json":
 		mar, err := json.MarshalIndent(serviceDef, "", "  ")
 		if err != nil {
 			return err
 		}
 		mar = append(mar, '\n')
 		writer.Write(mar)
-	case ".yaml":
+	case "yaml", "yml":
 		mar, err := yaml.Marshal(serviceDef)
 		if err != nil {
 			return err
 		}
 		mar = append(mar, '\n')
 		writer.Write(mar)
-	default:
+	case "toml", "":
 		WriteDefaultServiceTOML(writer, serviceDef)
+	default:
+		return fmt.Errorf("Unknown format %q. Use toml, json, or yaml", format)
 	}
 	return nil
 }
 
-func WriteDefaultServiceTOML(writer *os.File, serviceDef *def.ServiceDefinition) {
+// FormatFromExt returns the definition file format ("json", "yaml", or
+// "toml") from the fileName extension. TOML is assumed if unsure.
+func FormatFromExt(fileName string) string {
+	switch filepath.Ext(fileName) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "toml"
+	}
+}
+
+func WriteDefaultServiceTOML(writer io.Writer, serviceDef *def.ServiceDefinition) {
+	writeServiceTOML(writer, serviceDef, nil)
+}
+
+// writeServiceTOML writes the service definition in TOML, adding the
+// commented placeholders lines to the end of the [service] section.
+func writeServiceTOML(writer io.Writer, serviceDef *def.ServiceDefinition, placeholders []string) {
 
 	writer.Write([]byte("# This is a TOML config file.\n# For more information, see https://github.com/toml-lang/toml\n\n"))
 	enc := toml.NewEncoder(writer)
@@ -59,14 +89,40 @@ func WriteDefaultServiceTOML(writer *os.File, serviceDef *def.ServiceDefinition)
 	if serviceDef.ServiceID != "" {
 		writer.Write([]byte("service_id = \"" + serviceDef.ServiceID + "\"\n"))
 	}
+	if serviceDef.Type != "" {
+		writer.Write([]byte("type = \"" + serviceDef.Type + "\"\n"))
+	}
+	if serviceDef.Schedule != "" {
+		writer.Write([]byte("schedule = \"" + serviceDef.Schedule + "\"\n"))
+	}
 	if serviceDef.Chain != "" {
 		writer.Write([]byte("chain = \"" + serviceDef.Chain + "\"\n\n"))
 	}
 
-	writer.Write([]byte("description = \"\"\"\n" + "# describe your service" + "\n\"\"\"\n\n"))
+	if serviceDef.Description != "" {
+		writer.Write([]byte("description = \"\"\"\n" + strings.TrimSpace(serviceDef.Description) + "\n\"\"\"\n\n"))
+	} else {
+		writer.Write([]byte("description = \"\"\"\n" + DescriptionPlaceholder + "\n\"\"\"\n\n"))
+	}
+	if serviceDef.Version != "" {
+		writer.Write([]byte("version = \"" + serviceDef.Version + "\"\n"))
+	}
+	if len(serviceDef.Tags) != 0 {
+		var tags []string
+		for _, tag := range serviceDef.Tags {
+			tags = append(tags, fmt.Sprintf("%q", tag))
+		}
+		writer.Write([]byte("tags = [ " + strings.Join(tags, ", ") + " ]\n"))
+	}
+	if serviceDef.Version != "" || len(serviceDef.Tags) != 0 {
+		writer.Write([]byte("\n"))
+	}
 	writer.Write([]byte("status = \"\"" + " # alpha, beta, ready" + "\n\n"))
 	writer.Write([]byte("[service]\n"))
 	enc.Encode(serviceDef.Service)
+	for _, placeholder := range placeholders {
+		writer.Write([]byte(placeholder + "\n"))
+	}
 	writer.Write([]byte("\n"))
 	writer.Write([]byte("[dependencies]\n"))
 	if serviceDef.Dependencies != nil {
@@ -76,10 +132,30 @@ func WriteDefaultServiceTOML(writer *os.File, serviceDef *def.ServiceDefinition)
 	}
 	writer.Write([]byte("\n[maintainer]\n"))
 	enc.Encode(serviceDef.Maintainer)
+	if serviceDef.Machine != nil && len(serviceDef.Machine.Requires) != 0 {
+		writer.Write([]byte("\n[machine]\n"))
+		enc.Encode(serviceDef.Machine)
+	}
 	writer.Write([]byte("\n[location]\n"))
 	enc.Encode(serviceDef.Location)
 	writer.Write([]byte("dockerfile = \"\"\n"))
-	writer.Write([]byte("repository = \"\"\n"))
+	if serviceDef.Location == nil || serviceDef.Location.Repository == "" {
+		writer.Write([]byte("repository = \"\"\n"))
+	}
 	writer.Write([]byte("website = \"\"\n"))
+	WriteHooksTOML(writer, serviceDef.Hooks)
+}
 
+// WriteHooksTOML writes the [hooks] section of a service or a chain
+// definition in TOML, unless there are no hooks.
+func WriteHooksTOML(writer io.Writer, hooks *def.Hooks) {
+	if hooks == nil || len(hooks.PreStart)+len(hooks.PostStart)+len(hooks.PreStop)+len(hooks.PostStop) == 0 {
+		return
+	}
+	writer.Write([]byte("\n"))
+	enc := toml.NewEncoder(writer)
+	enc.Indent = ""
+	enc.Encode(struct {
+		Hooks *def.Hooks `toml:"hooks"`
+	}{hooks})
 }
@@ -0,0 +1,272 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/eris-ltd/eris-cli/config"
+	"github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/loaders"
+	"github.com/eris-ltd/eris-cli/perform"
+	"github.com/eris-ltd/eris-cli/util"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/olekukonko/tablewriter"
+)
+
+// Health levels reported by [eris status]. They follow the Nagios
+// plugin conventions and are used as the command exit codes.
+const (
+	HealthOK = iota
+	HealthWarn
+	HealthCrit
+)
+
+var healthNames = []string{"OK", "WARN", "CRIT"}
+
+// Health is the result of the health checks of a service or a chain.
+type Health struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	Status       string `json:"status"`
+	Level        int    `json:"level"`
+	Exists       bool   `json:"exists"`
+	Running      bool   `json:"running"`
+	RestartCount int    `json:"restart_count"`
+	ExitCode     int    `json:"exit_code"`
+	// "yes" or "no" if the service defines a readiness check
+	Ready   string `json:"ready,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// StatusReport summarizes the health checks of [eris status].
+type StatusReport struct {
+	Status string    `json:"status"`
+	Level  int       `json:"level"`
+	OK     int       `json:"ok"`
+	Warn   int       `json:"warn"`
+	Crit   int       `json:"crit"`
+	Checks []*Health `json:"checks"`
+}
+
+// Status checks the health of services and chains: whether their
+// containers exist and run, how many times they were restarted, their
+// last exit code, and the result of the readiness check (if the service
+// or chain definition file defines one). The report is written to the
+// global writer as a table or in JSON. Status returns the worst health
+// level found (HealthOK, HealthWarn, or HealthCrit); do.Result is set
+// to its name.
+//
+//  do.Operations.Args - "chains" or "services" optionally followed by names
+//                       (all existing containers are checked if no names given)
+//  do.JSON            - write the report in JSON
+//
+func Status(do *definitions.Do) (int, error) {
+	types, names := statusArgs(do.Operations.Args)
+
+	report := &StatusReport{}
+	if _, err := util.DockerClient.Version(); err != nil {
+		return HealthCrit, fmt.Errorf("The marmots cannot connect to Docker: %v", err)
+	}
+
+	for _, typ := range types {
+		checks := names
+		if len(checks) == 0 {
+			for _, c := range util.ErisContainersByType(typ, true) {
+				checks = append(checks, c.ShortName)
+			}
+			sort.Strings(checks)
+		}
+
+		for _, name := range checks {
+			// Names without a type are looked up both as services and chains.
+			if len(names) != 0 && len(types) > 1 && !statusKnown(typ, name) {
+				continue
+			}
+			report.Checks = append(report.Checks, CheckHealth(typ, name))
+		}
+	}
+
+	// Names which are neither services nor chains.
+	if len(types) > 1 {
+		for _, name := range names {
+			if !statusKnown(definitions.TypeService, name) && !statusKnown(definitions.TypeChain, name) {
+				report.Checks = append(report.Checks, &Health{
+					Name:    name,
+					Level:   HealthCrit,
+					Status:  healthNames[HealthCrit],
+					Message: "unknown service or chain",
+				})
+			}
+		}
+	}
+
+	for _, check := range report.Checks {
+		switch check.Level {
+		case HealthOK:
+			report.OK++
+		case HealthWarn:
+			report.Warn++
+		default:
+			report.Crit++
+		}
+		if check.Level > report.Level {
+			report.Level = check.Level
+		}
+	}
+	report.Status = healthNames[report.Level]
+	do.Result = report.Status
+
+	if do.JSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return HealthCrit, err
+		}
+		fmt.Fprintln(config.GlobalConfig.Writer, string(out))
+		return report.Level, nil
+	}
+
+	if len(report.Checks) != 0 {
+		table := tablewriter.NewWriter(config.GlobalConfig.Writer)
+		table.SetHeader([]string{"STATUS", "TYPE", "NAME", "RUNNING", "RESTARTS", "EXIT CODE", "READY", "MESSAGE"})
+		for _, check := range report.Checks {
+			running := "No"
+			if check.Running {
+				running = "Yes"
+			}
+			table.Append([]string{check.Status, check.Type, check.Name, running,
+				strconv.Itoa(check.RestartCount), strconv.Itoa(check.ExitCode), check.Ready, check.Message})
+		}
+		table.SetBorder(false)
+		table.SetCenterSeparator(" ")
+		table.SetColumnSeparator(" ")
+		table.SetRowSeparator("-")
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		table.Render()
+	}
+	fmt.Fprintf(config.GlobalConfig.Writer, "%s - %d OK, %d WARN, %d CRIT\n", report.Status, report.OK, report.Warn, report.Crit)
+
+	return report.Level, nil
+}
+
+// CheckHealth runs the health checks of a service or a chain
+// (typ is either definitions.TypeService or definitions.TypeChain).
+func CheckHealth(typ, name string) *Health {
+	health := &Health{Name: name, Type: typ}
+	defer func() {
+		health.Status = healthNames[health.Level]
+	}()
+
+	var c *util.ContainerName
+	if typ == definitions.TypeChain {
+		c = util.FindChainContainer(name, true)
+	} else {
+		c = util.FindServiceContainer(name, true)
+	}
+	if c == nil {
+		health.Level = HealthCrit
+		health.Message = "container does not exist"
+		return health
+	}
+	health.Exists = true
+
+	container, err := util.DockerClient.InspectContainer(c.ContainerID)
+	if err != nil {
+		health.Level = HealthCrit
+		health.Message = err.Error()
+		return health
+	}
+	state := container.State
+	health.Running = state.Running
+	health.RestartCount = container.RestartCount
+	health.ExitCode = state.ExitCode
+
+	switch {
+	case state.Restarting:
+		health.Level = HealthCrit
+		health.Message = fmt.Sprintf("restarting (exited with code %d)", state.ExitCode)
+		return health
+	case !state.Running && state.StartedAt.IsZero():
+		health.Level = HealthCrit
+		health.Message = "container was never started"
+		return health
+	case !state.Running:
+		health.Level = HealthCrit
+		health.Message = fmt.Sprintf("stopped (exited with code %d)", state.ExitCode)
+		if state.OOMKilled {
+			health.Message += ", out of memory"
+		}
+		return health
+	case state.Paused:
+		health.Level = HealthWarn
+		health.Message = "paused"
+		return health
+	case container.RestartCount > 0:
+		health.Level = HealthWarn
+		health.Message = fmt.Sprintf("restarted %d times", container.RestartCount)
+	}
+
+	if ready := readinessCheck(typ, name); ready != "" {
+		ops := definitions.BlankOperation()
+		ops.SrvContainerName = c.FullName
+		if err := perform.DockerCheckReady(ops, ready); err != nil {
+			health.Ready = "no"
+			health.Level = HealthCrit
+			health.Message = err.Error()
+		} else {
+			health.Ready = "yes"
+		}
+	}
+	return health
+}
+
+// readinessCheck returns the readiness check command from the definition
+// file, if any.
+func readinessCheck(typ, name string) string {
+	var service *definitions.Service
+	if typ == definitions.TypeChain {
+		chain, err := loaders.ReadChainDefinition(name)
+		if err != nil {
+			log.WithField("=>", name).Debugf("Cannot read chain definition: %v", err)
+			return ""
+		}
+		service = chain.Service
+	} else {
+		srv, err := loaders.ReadServiceDefinition(name)
+		if err != nil {
+			log.WithField("=>", name).Debugf("Cannot read service definition: %v", err)
+			return ""
+		}
+		service = srv.Service
+	}
+
+	if service == nil {
+		return ""
+	}
+	return service.Ready
+}
+
+// statusArgs splits [eris status] arguments into container types
+// and names.
+func statusArgs(args []string) (types, names []string) {
+	if len(args) != 0 {
+		switch args[0] {
+		case "services", "service":
+			return []string{definitions.TypeService}, args[1:]
+		case "chains", "chain":
+			return []string{definitions.TypeChain}, args[1:]
+		}
+	}
+	return []string{definitions.TypeChain, definitions.TypeService}, args
+}
+
+// statusKnown returns true if the service or chain has a definition file
+// or a container.
+func statusKnown(typ, name string) bool {
+	if typ == definitions.TypeChain {
+		return util.GetFileByNameAndType("chains", name) != "" || util.FindChainContainer(name, true) != nil
+	}
+	return util.GetFileByNameAndType("services", name) != "" || util.FindServiceContainer(name, true) != nil
+}
@@ -0,0 +1,143 @@
+package status
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eris-ltd/eris-cli/config"
+	def "github.com/eris-ltd/eris-cli/definitions"
+	srv "github.com/eris-ltd/eris-cli/services"
+	"github.com/eris-ltd/eris-cli/tests"
+	ver "github.com/eris-ltd/eris-cli/version"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	logger "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/log"
+)
+
+func TestMain(m *testing.M) {
+	log.SetFormatter(logger.ErisFormatter{})
+
+	log.SetLevel(log.ErrorLevel)
+	// log.SetLevel(log.InfoLevel)
+	// log.SetLevel(log.DebugLevel)
+
+	tests.IfExit(tests.TestsInit("status"))
+
+	exitCode := m.Run()
+	tests.IfExit(tests.TestsTearDown())
+	os.Exit(exitCode)
+}
+
+func TestStatusArgs(t *testing.T) {
+	for _, test := range []struct {
+		args  []string
+		types []string
+		names []string
+	}{
+		{nil, []string{def.TypeChain, def.TypeService}, nil},
+		{[]string{"services"}, []string{def.TypeService}, []string{}},
+		{[]string{"chains", "a", "b"}, []string{def.TypeChain}, []string{"a", "b"}},
+		{[]string{"ipfs"}, []string{def.TypeChain, def.TypeService}, []string{"ipfs"}},
+	} {
+		types, names := statusArgs(test.args)
+		if !reflect.DeepEqual(types, test.types) || len(names) != len(test.names) {
+			t.Fatalf("%v: expected %v %v, got %v %v", test.args, test.types, test.names, types, names)
+		}
+	}
+}
+
+func TestStatusService(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	do := def.NowDo()
+	do.Operations.Args = []string{"ipfs"}
+	if err := srv.StartService(do); err != nil {
+		t.Fatalf("expected service to start, got %v", err)
+	}
+
+	if level := testStatus(t, "services", "ipfs"); level != HealthOK {
+		t.Fatalf("expected running service to be OK, got %v", level)
+	}
+	if level := testStatus(t, "no-such-service"); level != HealthCrit {
+		t.Fatalf("expected unknown service to be CRIT, got %v", level)
+	}
+
+	do = def.NowDo()
+	do.Operations.Args = []string{"ipfs"}
+	if err := srv.KillService(do); err != nil {
+		t.Fatalf("expected service to stop, got %v", err)
+	}
+	if level := testStatus(t, "services", "ipfs"); level != HealthCrit {
+		t.Fatalf("expected stopped service to be CRIT, got %v", level)
+	}
+}
+
+func TestStatusReadinessCheck(t *testing.T) {
+	defer tests.RemoveAllContainers()
+
+	for _, test := range []struct {
+		ready string
+		level int
+	}{
+		{"true", HealthOK},
+		{"exit 3", HealthCrit},
+	} {
+		definition := filepath.Join(common.ServicesPath, "ready.toml")
+		if err := ioutil.WriteFile(definition, []byte(`name = "ready"
+
+[service]
+image = "`+path.Join(ver.ERIS_REG_DEF, ver.ERIS_IMG_KEYS)+`"
+ready = "`+test.ready+`"
+`), 0644); err != nil {
+			t.Fatalf("expected service definition to be written, got %v", err)
+		}
+		defer os.Remove(definition)
+
+		do := def.NowDo()
+		do.Operations.Args = []string{"ready"}
+		if err := srv.StartService(do); err != nil {
+			t.Fatalf("expected service to start, got %v", err)
+		}
+
+		buf := new(bytes.Buffer)
+		config.GlobalConfig.Writer = buf
+		do = def.NowDo()
+		do.Operations.Args = []string{"services", "ready"}
+		do.JSON = true
+		level, err := Status(do)
+		config.GlobalConfig.Writer = os.Stdout
+		if err != nil {
+			t.Fatalf("expected status to be checked, got %v", err)
+		}
+		if level != test.level {
+			t.Fatalf("%q: expected level %v, got %v", test.ready, test.level, level)
+		}
+
+		var report StatusReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("expected a JSON report, got %v", err)
+		}
+		if len(report.Checks) != 1 || report.Checks[0].Ready == "" {
+			t.Fatalf("expected a readiness check to be reported, got %v", buf.String())
+		}
+
+		tests.RemoveAllContainers()
+	}
+}
+
+func testStatus(t *testing.T, args ...string) int {
+	do := def.NowDo()
+	do.Operations.Args = args
+	level, err := Status(do)
+	if err != nil {
+		t.Fatalf("expected status to be checked, got %v", err)
+	}
+	return level
+}
@@ -3,7 +3,10 @@ package util
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
@@ -30,23 +33,125 @@ func NullHead() error {
 	return ChangeHead("")
 }
 
-// Get the current active chain (top of the HEAD file)
+// LocalHeadFile is the file which checks out a chain for the directory
+// it is in and the subdirectories.
+const LocalHeadFile = ".eris-chain"
+
+// HeadEntry is a checkout recorded in the HEAD file.
+type HeadEntry struct {
+	// name of the chain, blank if the chain was "uncheckedout"
+	Name string
+	// time of the checkout (from the HeadHistoryFile), zero for entries
+	// of older versions
+	Time time.Time
+}
+
+// HeadHistoryFile returns the file next to the HEAD file which records
+// the time of each checkout as NAME<tab>TIME lines, most recent first.
+// The HEAD file itself keeps one chain name per line.
+func HeadHistoryFile() string {
+	return common.HEAD + ".history"
+}
+
+// Get the current active chain: the chain checked out in the
+// working directory (see LocalHeadFile) or the top of the HEAD file.
 // Returns chain name
 func GetHead() (string, error) {
-	// TODO: only read the one line!
-	f, err := ioutil.ReadFile(common.HEAD)
+	if file := FindLocalHead(); file != "" {
+		if head, err := ReadLocalHead(file); err == nil && head != "" {
+			return head, nil
+		}
+	}
+	return GetGlobalHead()
+}
+
+// GetGlobalHead returns the chain at the top of the HEAD file,
+// ignoring the chains checked out in directories.
+func GetGlobalHead() (string, error) {
+	history, err := ReadHeadHistory()
 	if err != nil {
 		return "", err
 	}
 
-	fspl := strings.Split(string(f), "\n")
-	head := fspl[0]
-
-	if head == "" {
+	if len(history) == 0 || history[0].Name == "" {
 		return "", fmt.Errorf("There is no chain checked out")
 	}
 
-	return head, nil
+	return history[0].Name, nil
+}
+
+// PreviousHead returns the chain checked out before the current one
+// (blank if there was no chain checked out then).
+func PreviousHead() (string, error) {
+	history, err := ReadHeadHistory()
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range history {
+		if entry.Name != history[0].Name {
+			return entry.Name, nil
+		}
+	}
+	return "", fmt.Errorf("There is no previously checked out chain")
+}
+
+// ReadHeadHistory returns the checkouts recorded in the HEAD file,
+// most recent first, with their times from the HeadHistoryFile.
+func ReadHeadHistory() ([]*HeadEntry, error) {
+	f, err := ioutil.ReadFile(common.HEAD)
+	if err != nil {
+		return nil, err
+	}
+
+	names := strings.Split(string(f), "\n")
+	// The last line is blank.
+	if len(names) > 1 && strings.TrimSpace(names[len(names)-1]) == "" {
+		names = names[:len(names)-1]
+	}
+
+	// Both files are written together, so the times follow the names
+	// in the same order. Checkouts by older versions have no time.
+	times, err := readHeadTimes()
+	if err != nil {
+		return nil, err
+	}
+	var history []*HeadEntry
+	for _, name := range names {
+		entry := &HeadEntry{Name: strings.TrimSpace(name)}
+		if len(times) != 0 && times[0].Name == entry.Name {
+			entry.Time = times[0].Time
+			times = times[1:]
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// readHeadTimes reads the NAME<tab>TIME lines of the HeadHistoryFile,
+// if there is one.
+func readHeadTimes() ([]*HeadEntry, error) {
+	f, err := ioutil.ReadFile(HeadHistoryFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var times []*HeadEntry
+	for _, line := range strings.Split(string(f), "\n") {
+		fields := strings.SplitN(strings.TrimRight(line, "\r"), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(fields[1]))
+		if err != nil {
+			continue
+		}
+		times = append(times, &HeadEntry{Name: strings.TrimSpace(fields[0]), Time: t})
+	}
+	return times, nil
 }
 
 // Add a new entry (name) to the top of the HEAD file
@@ -81,6 +186,76 @@ func ChangeHead(name string) error {
 		return err
 	}
 
+	if err := recordHeadTime(name, time.Now()); err != nil {
+		return err
+	}
+
 	log.Debug("Head file saved")
 	return nil
 }
+
+// recordHeadTime adds the checkout time of name to the top of the
+// HeadHistoryFile, keeping at most MaxHead entries.
+func recordHeadTime(name string, t time.Time) error {
+	b, err := ioutil.ReadFile(HeadHistoryFile())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if len(lines) >= MaxHead {
+		lines = lines[:MaxHead-1]
+	}
+	s := name + "\t" + t.UTC().Format(time.RFC3339) + "\n" + strings.Join(lines, "")
+	return ioutil.WriteFile(HeadHistoryFile(), []byte(s), 0666)
+}
+
+// FindLocalHead returns the path of the LocalHeadFile in the working
+// directory or the closest parent directory, or "" if there is none.
+func FindLocalHead() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		file := filepath.Join(dir, LocalHeadFile)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ReadLocalHead returns the chain checked out by the LocalHeadFile file.
+func ReadLocalHead(file string) (string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0]), nil
+}
+
+// ChangeLocalHead checks out the chain for the working directory by
+// writing a LocalHeadFile to it, or removes the file if name is blank.
+func ChangeLocalHead(name string) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, LocalHeadFile)
+
+	if name == "" {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if !IsKnownChain(name) {
+		return fmt.Errorf("Chain %s is not known. Make it with [eris chains make %s] first", name, name)
+	}
+	return ioutil.WriteFile(file, []byte(name+"\n"), 0666)
+}
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+func TestHeadHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_head")
+	if err != nil {
+		t.Fatalf("expected temp dir to be created, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(head string) { common.HEAD = head }(common.HEAD)
+	common.HEAD = filepath.Join(dir, "HEAD")
+
+	// HEAD keeps one name per line; the checkout of old by an older
+	// version has no time in the history file.
+	if err := ioutil.WriteFile(common.HEAD, []byte("second\n\nfirst\nold\n"), 0666); err != nil {
+		t.Fatalf("expected HEAD file to be written, got %v", err)
+	}
+	times := "second\t2016-05-02T10:00:00Z\n\t2016-05-01T12:00:00Z\nfirst\t2016-05-01T10:00:00Z\n"
+	if err := ioutil.WriteFile(HeadHistoryFile(), []byte(times), 0666); err != nil {
+		t.Fatalf("expected HEAD history file to be written, got %v", err)
+	}
+
+	history, err := ReadHeadHistory()
+	if err != nil {
+		t.Fatalf("expected history to be read, got %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("expected 4 entries, got %v", len(history))
+	}
+	if history[0].Name != "second" || !history[0].Time.Equal(time.Date(2016, 5, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the second chain on top, got %v at %v", history[0].Name, history[0].Time)
+	}
+	if history[1].Name != "" || history[1].Time.IsZero() {
+		t.Fatalf("expected an uncheckout entry, got %v at %v", history[1].Name, history[1].Time)
+	}
+	if history[3].Name != "old" || !history[3].Time.IsZero() {
+		t.Fatalf("expected an old entry without a time, got %v at %v", history[3].Name, history[3].Time)
+	}
+
+	if head, err := GetGlobalHead(); err != nil || head != "second" {
+		t.Fatalf("expected second to be checked out, got %v, %v", head, err)
+	}
+	if previous, err := PreviousHead(); err != nil || previous != "" {
+		t.Fatalf("expected no chain checked out previously, got %v, %v", previous, err)
+	}
+
+	if err := ioutil.WriteFile(common.HEAD, []byte("first\nsecond\n"), 0666); err != nil {
+		t.Fatalf("expected HEAD file to be written, got %v", err)
+	}
+	if previous, err := PreviousHead(); err != nil || previous != "second" {
+		t.Fatalf("expected second to be the previous chain, got %v, %v", previous, err)
+	}
+
+	// A HEAD file without a history file.
+	os.Remove(HeadHistoryFile())
+	if history, err := ReadHeadHistory(); err != nil || len(history) != 2 || !history[0].Time.IsZero() {
+		t.Fatalf("expected 2 entries without a time, got %v, %v", history, err)
+	}
+
+	if err := ioutil.WriteFile(common.HEAD, nil, 0666); err != nil {
+		t.Fatalf("expected HEAD file to be written, got %v", err)
+	}
+	if _, err := GetGlobalHead(); err == nil {
+		t.Fatalf("expected no chain to be checked out, got nil")
+	}
+	if _, err := PreviousHead(); err == nil {
+		t.Fatalf("expected no previous chain, got nil")
+	}
+}
+
+func TestLocalHead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eris_local_head")
+	if err != nil {
+		t.Fatalf("expected temp dir to be created, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+
+	nested := filepath.Join(dir, "contracts", "tokens")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("expected nested dir to be created, got %v", err)
+	}
+
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(nested); err != nil {
+		t.Fatalf("expected to change directory, got %v", err)
+	}
+
+	if file := FindLocalHead(); file != "" {
+		t.Fatalf("expected no local head file, got %v", file)
+	}
+
+	file := filepath.Join(dir, LocalHeadFile)
+	if err := ioutil.WriteFile(file, []byte("project-chain\n"), 0666); err != nil {
+		t.Fatalf("expected local head file to be written, got %v", err)
+	}
+	if found := FindLocalHead(); found != file {
+		t.Fatalf("expected local head file %v, got %v", file, found)
+	}
+	if head, err := GetHead(); err != nil || head != "project-chain" {
+		t.Fatalf("expected project-chain to be checked out, got %v, %v", head, err)
+	}
+
+	if err := ChangeLocalHead(""); err != nil {
+		t.Fatalf("expected no error removing a missing local head file, got %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("expected to change directory, got %v", err)
+	}
+	if err := ChangeLocalHead(""); err != nil {
+		t.Fatalf("expected local head file to be removed, got %v", err)
+	}
+	if file := FindLocalHead(); file != "" {
+		t.Fatalf("expected no local head file, got %v", file)
+	}
+}
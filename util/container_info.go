@@ -17,7 +17,7 @@ type ContainerName struct {
 	FullName    string
 	DockersName string
 	ShortName   string
-	//	Number      int
+	Number      int
 	Type        string
 	ContainerID string
 }
@@ -26,9 +26,15 @@ func ContainersName(typ, name string) string {
 	return ContainerAssemble(typ, name).FullName
 }
 
-//func ContainersNumber(containerName string) int {
-//	return ContainerDisassemble(containerName).Number
-//}
+// NumberedContainersName returns the name of the number-th container of
+// the service or chain (e.g. a node of a chain network). Numbers start at 1.
+func NumberedContainersName(typ, name string, number int) string {
+	return fmt.Sprintf("eris_%s_%s_%d", typ, name, number)
+}
+
+func ContainersNumber(containerName string) int {
+	return ContainerDisassemble(containerName).Number
+}
 
 func ContainersType(containerName string) string {
 	return ContainerDisassemble(containerName).Type
@@ -39,14 +45,14 @@ func ContainersShortName(containerName string) string {
 }
 
 func ContainerAssemble(typ, name string) *ContainerName {
-	full := fmt.Sprintf("eris_%s_%s_1", typ, name)
+	full := NumberedContainersName(typ, name, 1)
 
 	return &ContainerName{
 		FullName:    full,
 		DockersName: "/" + full,
 		ShortName:   name,
 		Type:        typ,
-		//Number:      number,
+		Number:      1,
 	}
 }
 
@@ -65,7 +71,7 @@ func ContainerDisassemble(containerName string) *ContainerName {
 
 	typ := pop[1]
 	srt := strings.Join(pop[2:len(pop)-1], "_")
-	_, err := strconv.Atoi(pop[len(pop)-1])
+	num, err := strconv.Atoi(pop[len(pop)-1])
 	if err != nil {
 		log.WithField("=>", containerName).Debug("The marmots cannot disassemble container name")
 
@@ -79,8 +85,8 @@ func ContainerDisassemble(containerName string) *ContainerName {
 		FullName:    containerName,
 		DockersName: "/" + containerName,
 		Type:        typ,
-		//Number:      num,
-		ShortName: srt,
+		Number:      num,
+		ShortName:   srt,
 	}
 }
 
@@ -195,10 +201,17 @@ func ChainContainers(running bool) []*ContainerName {
 	return ErisContainersByType("chain", running)
 }
 
+// ChainContainerNames returns the names of chains with containers.
+// Chain networks, which have a container for each node, are listed once.
 func ChainContainerNames(running bool) []string {
 	a := ChainContainers(running)
 	b := []string{}
+	seen := make(map[string]bool)
 	for _, c := range a {
+		if seen[c.ShortName] {
+			continue
+		}
+		seen[c.ShortName] = true
 		b = append(b, c.ShortName)
 	}
 	return b
@@ -289,6 +302,17 @@ func FindDataContainer(name string) *ContainerName {
 	return nil
 }
 
+// FindNumberedContainer returns the number-th existing container
+// of the type with the short name, or nil if there isn't one.
+func FindNumberedContainer(typ, name string, number int) *ContainerName {
+	for _, c := range ErisContainersByType(typ, true) {
+		if c.ShortName == name && c.Number == number {
+			return c
+		}
+	}
+	return nil
+}
+
 func IsDataContainer(name string) bool {
 	if FindDataContainer(name) == nil {
 		return false
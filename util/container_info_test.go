@@ -54,3 +54,23 @@ func TestContainerNameBad(t *testing.T) {
 		}
 	}
 }
+
+func TestContainerNameNumbered(t *testing.T) {
+	for name, number := range map[string]int{
+		"eris_chain_mint_1":      1,
+		"eris_chain_mint_4":      4,
+		"eris_data_mint_love_12": 12,
+		"eris_chain_mint":        0,
+	} {
+		if returned := ContainersNumber(name); returned != number {
+			t.Fatalf("Wrong number from %s. Got %d, expected %d", name, returned, number)
+		}
+	}
+
+	if name := NumberedContainersName("chain", "mint", 3); name != "eris_chain_mint_3" {
+		t.Fatalf("Wrong numbered name. Got %s, expected eris_chain_mint_3", name)
+	}
+	if ContainerAssemble("chain", "mint").Number != 1 {
+		t.Fatalf("Wrong number of an assembled container. Expected 1")
+	}
+}
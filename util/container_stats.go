@@ -2,6 +2,8 @@ package util
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
@@ -9,6 +11,8 @@ import (
 	"unicode"
 
 	"github.com/eris-ltd/eris-cli/config"
+	def "github.com/eris-ltd/eris-cli/definitions"
+	"github.com/eris-ltd/eris-cli/secrets"
 
 	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
 	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
@@ -17,6 +21,13 @@ import (
 )
 
 func PrintInspectionReport(cont *docker.Container, field string) error {
+	// Never show the values of secrets exported to the container.
+	if cont.Config != nil {
+		if names, ok := cont.Config.Labels[def.Namespace+":"+def.LabelSecrets]; ok && names != "" {
+			cont.Config.Env = secrets.Redact(cont.Config.Env, strings.Split(names, ","))
+		}
+	}
+
 	switch field {
 	case "line":
 		parts, err := printLine(cont, false) //can only inspect a running container...?
@@ -96,6 +107,47 @@ func PrintPortMappings(id string, ports []string) error {
 	return nil
 }
 
+// PublishedPort returns the HOST:PORT address the port of the container
+// (e.g. "46657" or "46657/tcp") is published on. Ports published on all
+// interfaces are reached through the Docker host.
+func PublishedPort(id, port string) (string, error) {
+	cont, err := DockerClient.InspectContainer(id)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(port, "/tcp") && !strings.HasSuffix(port, "/udp") {
+		port += "/tcp"
+	}
+	var bindings []docker.PortBinding
+	if cont.NetworkSettings != nil {
+		bindings = cont.NetworkSettings.Ports[docker.Port(port)]
+	}
+	if len(bindings) == 0 {
+		return "", fmt.Errorf("Port %s of the container is not published to the host", port)
+	}
+
+	host := bindings[0].HostIP
+	if host == "" || host == "0.0.0.0" {
+		host = dockerHostIP()
+	}
+	return net.JoinHostPort(host, bindings[0].HostPort), nil
+}
+
+// dockerHostIP returns the address of the host Docker runs on: the
+// Docker Machine VM or localhost.
+func dockerHostIP() string {
+	if DockerClient != nil {
+		if u, err := url.Parse(DockerClient.Endpoint()); err == nil && u.Scheme != "unix" && u.Host != "" {
+			if host, _, err := net.SplitHostPort(u.Host); err == nil {
+				return host
+			}
+			return u.Host
+		}
+	}
+	return "127.0.0.1"
+}
+
 // this function populates the listing functions only for flags/tests
 func printLine(container *docker.Container, existing bool) ([]string, error) {
 	tmp, err := reflections.GetField(container, "Name")
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, good := range []string{"* * * * *", "*/15 0-6 1,15 * 1-5", "0 3 * * 7", "5/10 * * * *", "@daily", " @hourly "} {
+		if _, err := ParseCron(good); err != nil {
+			t.Fatalf("%q: expected schedule to parse, got %v", good, err)
+		}
+	}
+
+	for _, bad := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@often"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Fatalf("%q: expected an error, got nil", bad)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// Monday.
+	at := time.Date(2016, time.March, 14, 3, 30, 0, 0, time.UTC)
+
+	for expr, matches := range map[string]bool{
+		"* * * * *":      true,
+		"30 3 * * *":     true,
+		"*/15 * * * *":   true,
+		"*/20 * * * *":   false,
+		"30 3 * * 1-5":   true,
+		"30 3 * * 0,6":   false,
+		"30 3 1 * *":     false,
+		"30 3 1 * 1":     true, // day of month and day of week are ORed
+		"30 3 14 3 *":    true,
+		"30 3 14 4 *":    false,
+		"0-29 * * * *":   false,
+		"@hourly":        false,
+		"30 3 14 3 7":    true,
+		"30 3 1-13 * 0":  false,
+		"30 3 * 1-3/2 *": true,
+	} {
+		cron, err := ParseCron(expr)
+		if err != nil {
+			t.Fatalf("%q: expected schedule to parse, got %v", expr, err)
+		}
+		if returned := cron.Matches(at); returned != matches {
+			t.Fatalf("%q: expected %v, got %v", expr, matches, returned)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2016, time.March, 14, 3, 30, 20, 0, time.UTC)
+
+	for expr, next := range map[string]time.Time{
+		"* * * * *":     time.Date(2016, time.March, 14, 3, 31, 0, 0, time.UTC),
+		"30 3 * * *":    time.Date(2016, time.March, 15, 3, 30, 0, 0, time.UTC),
+		"0 * * * *":     time.Date(2016, time.March, 14, 4, 0, 0, 0, time.UTC),
+		"@weekly":       time.Date(2016, time.March, 20, 0, 0, 0, 0, time.UTC),
+		"@yearly":       time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":    time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 30 2 *":    time.Time{},
+		"15 12 * * 3":   time.Date(2016, time.March, 16, 12, 15, 0, 0, time.UTC),
+		"0 0 31 * *":    time.Date(2016, time.March, 31, 0, 0, 0, 0, time.UTC),
+		"*/45 23 * * *": time.Date(2016, time.March, 14, 23, 0, 0, 0, time.UTC),
+	} {
+		cron, err := ParseCron(expr)
+		if err != nil {
+			t.Fatalf("%q: expected schedule to parse, got %v", expr, err)
+		}
+		if returned := cron.Next(from); !returned.Equal(next) {
+			t.Fatalf("%q: expected %v, got %v", expr, next, returned)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/gopkg.in/yaml.v2"
+)
+
+// SetDefinitionValues returns the contents of a service or chain
+// definition file (TOML, JSON, or YAML, going by the extension of
+// fileName) with the dotted keys set to the string values. Unlike
+// loading the definition and writing it again, everything else in
+// the file is kept.
+func SetDefinitionValues(content []byte, fileName string, values map[string]string) ([]byte, error) {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch filepath.Ext(fileName) {
+	case ".json":
+		var tree map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			table := tree
+			parts := strings.Split(key, ".")
+			for _, part := range parts[:len(parts)-1] {
+				next, ok := table[part].(map[string]interface{})
+				if !ok {
+					next = make(map[string]interface{})
+					table[part] = next
+				}
+				table = next
+			}
+			table[parts[len(parts)-1]] = values[key]
+		}
+		out, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	case ".yaml", ".yml":
+		var tree yaml.MapSlice
+		if err := yaml.Unmarshal(content, &tree); err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			tree = setYAMLValue(tree, strings.Split(key, "."), values[key])
+		}
+		return yaml.Marshal(tree)
+	default:
+		for _, key := range keys {
+			content = SetTOMLValue(content, key, strconv.Quote(values[key]))
+		}
+		return content, nil
+	}
+}
+
+// setYAMLValue sets the value at the path of keys, keeping the order
+// of the mapping.
+func setYAMLValue(tree yaml.MapSlice, path []string, value string) yaml.MapSlice {
+	for i, item := range tree {
+		if item.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			tree[i].Value = value
+		} else {
+			table, _ := item.Value.(yaml.MapSlice)
+			tree[i].Value = setYAMLValue(table, path[1:], value)
+		}
+		return tree
+	}
+	if len(path) == 1 {
+		return append(tree, yaml.MapItem{Key: path[0], Value: value})
+	}
+	return append(tree, yaml.MapItem{Key: path[0], Value: setYAMLValue(nil, path[1:], value)})
+}
+
+// SetTOMLValue sets the dotted key of the TOML content to the literal.
+// An existing setting is replaced in place; a new one is added after the
+// last setting of its table, and a missing table is added at the end.
+// Other lines are kept as they are.
+func SetTOMLValue(content []byte, key, literal string) []byte {
+	table, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		table, name = key[:i], key[i+1:]
+	}
+	setting := name + " = " + literal
+
+	var lines []string
+	if trimmed := strings.TrimRight(string(content), "\n"); trimmed != "" {
+		lines = strings.Split(trimmed, "\n")
+	}
+
+	current, insert := "", -1
+	if table == "" {
+		insert = 0
+	}
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			current = strings.TrimSpace(strings.Trim(strings.SplitN(trimmed, "#", 2)[0], " \t[]"))
+			if current == table {
+				insert = i + 1
+			}
+		case current != table || trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		default:
+			if k := strings.SplitN(trimmed, "=", 2); len(k) == 2 && strings.TrimSpace(k[0]) == name {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				lines[i] = indent + setting
+				return []byte(strings.Join(lines, "\n") + "\n")
+			}
+			insert = i + 1
+		}
+	}
+
+	if insert < 0 {
+		if len(lines) != 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+table+"]", setting)
+	} else {
+		lines = append(lines[:insert], append([]string{setting}, lines[insert:]...)...)
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetDefinitionValues(t *testing.T) {
+	values := map[string]string{"name": "renamed", "service.name": "renamed"}
+
+	for file, content := range map[string]string{
+		"keys.toml": `name = "keys"
+type = "job"
+
+[service]
+name = "keys"
+image = "quay.io/eris/keys"
+
+[[hooks.pre_start]]
+command = "echo hello"
+`,
+		"keys.json": `{
+  "name": "keys",
+  "type": "job",
+  "service": {"name": "keys", "image": "quay.io/eris/keys"},
+  "hooks": {"pre_start": [{"command": "echo hello"}]}
+}
+`,
+		"keys.yaml": `name: keys
+type: job
+service:
+  name: keys
+  image: quay.io/eris/keys
+hooks:
+  pre_start:
+  - command: echo hello
+`,
+	} {
+		out, err := SetDefinitionValues([]byte(content), file, values)
+		if err != nil {
+			t.Fatalf("%s: expected values to be set, got %v", file, err)
+		}
+		if strings.Contains(string(out), `"keys"`) || strings.Contains(string(out), ": keys\n") {
+			t.Fatalf("%s: expected both names to change, got\n%s", file, out)
+		}
+		if strings.Count(string(out), "renamed") != 2 {
+			t.Fatalf("%s: expected two renamed values, got\n%s", file, out)
+		}
+		for _, kept := range []string{"job", "quay.io/eris/keys", "pre_start", "echo hello"} {
+			if !strings.Contains(string(out), kept) {
+				t.Fatalf("%s: expected %q to be kept, got\n%s", file, kept, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package util
+
+import "fmt"
+
+// freeDiskSpace is not supported on Windows.
+func freeDiskSpace(path string) (uint64, error) {
+	return 0, fmt.Errorf("checking free disk space is not supported on Windows")
+}
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// ParseKeyValues converts a list of "key=value" pairs (labels, sysctls,
+// or log options) to a map. The field name is used in error messages.
+func ParseKeyValues(field string, pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	values := make(map[string]string)
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid %s %q: expected <key>=<value>", field, pair)
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
+
+// ParseDevice parses the device in the "host[:container[:permissions]]"
+// format of [docker run --device]. The container path defaults to the
+// host one, permissions default to "rwm".
+func ParseDevice(device string) (docker.Device, error) {
+	parts := strings.Split(device, ":")
+	if len(parts) > 3 || !path.IsAbs(parts[0]) {
+		return docker.Device{}, fmt.Errorf("Invalid device %q: expected <host path>[:<container path>[:<permissions>]]", device)
+	}
+
+	d := docker.Device{
+		PathOnHost:        parts[0],
+		PathInContainer:   parts[0],
+		CgroupPermissions: "rwm",
+	}
+	if len(parts) > 1 {
+		if !path.IsAbs(parts[1]) {
+			return docker.Device{}, fmt.Errorf("Invalid device %q: the container path has to be absolute", device)
+		}
+		d.PathInContainer = parts[1]
+	}
+	if len(parts) > 2 {
+		if strings.Trim(parts[2], "rwm") != "" || parts[2] == "" {
+			return docker.Device{}, fmt.Errorf("Invalid device %q: permissions can only be a combination of r, w, and m", device)
+		}
+		d.CgroupPermissions = parts[2]
+	}
+	return d, nil
+}
+
+// StopGracePeriod returns the stop_grace_period of the service definition
+// in seconds, or 0 if it is not set.
+func StopGracePeriod(srv *def.Service) uint {
+	if srv == nil || srv.StopGracePeriod == "" {
+		return 0
+	}
+	period, err := time.ParseDuration(srv.StopGracePeriod)
+	if err != nil || period < 0 {
+		return 0
+	}
+	return uint((period + time.Second - 1) / time.Second)
+}
+
+// CheckDockerOptions returns an error if the labels, extra hosts, sysctls,
+// devices, stop, or logging options of the service definition are
+// malformed.
+func CheckDockerOptions(srv *def.Service) error {
+	labels, err := ParseKeyValues("label", srv.Labels)
+	if err != nil {
+		return err
+	}
+	for label := range labels {
+		if strings.HasPrefix(label, def.Namespace+":") {
+			return fmt.Errorf("Invalid label %q: the %s namespace is reserved", label, def.Namespace)
+		}
+	}
+
+	for _, host := range srv.ExtraHosts {
+		parts := strings.SplitN(host, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || net.ParseIP(parts[1]) == nil {
+			return fmt.Errorf("Invalid extra_hosts entry %q: expected <host>:<ip>", host)
+		}
+	}
+
+	if _, err := ParseKeyValues("sysctl", srv.Sysctls); err != nil {
+		return err
+	}
+
+	for _, device := range srv.Devices {
+		if _, err := ParseDevice(device); err != nil {
+			return err
+		}
+	}
+
+	if srv.ShmSize < 0 {
+		return fmt.Errorf("Invalid shm_size %d: expected a positive number of bytes", srv.ShmSize)
+	}
+
+	if signal := srv.StopSignal; signal != "" {
+		if _, err := strconv.Atoi(signal); err != nil && !strings.HasPrefix(signal, "SIG") {
+			return fmt.Errorf("Invalid stop_signal %q: expected a signal name (e.g. SIGINT) or number", signal)
+		}
+	}
+
+	if srv.StopGracePeriod != "" {
+		if period, err := time.ParseDuration(srv.StopGracePeriod); err != nil || period < 0 {
+			return fmt.Errorf("Invalid stop_grace_period %q: expected a duration (e.g. 30s or 2m)", srv.StopGracePeriod)
+		}
+	}
+
+	if _, err := ParseKeyValues("log_opts entry", srv.LogOpts); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package util
+
+import (
+	"testing"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+)
+
+func TestParseKeyValues(t *testing.T) {
+	values, err := ParseKeyValues("label", []string{"a=1", "b=x=y", "c="})
+	if err != nil {
+		t.Fatalf("expected pairs to parse, got %v", err)
+	}
+	if len(values) != 3 || values["a"] != "1" || values["b"] != "x=y" || values["c"] != "" {
+		t.Fatalf("expected map[a:1 b:x=y c:], got %v", values)
+	}
+
+	if values, err := ParseKeyValues("label", nil); values != nil || err != nil {
+		t.Fatalf("expected (nil, nil), got (%v, %v)", values, err)
+	}
+
+	for _, bad := range []string{"a", "=1"} {
+		if _, err := ParseKeyValues("label", []string{bad}); err == nil {
+			t.Fatalf("%q: expected an error, got nil", bad)
+		}
+	}
+}
+
+func TestParseDevice(t *testing.T) {
+	for _, test := range []struct {
+		device, host, container, permissions string
+	}{
+		{"/dev/fuse", "/dev/fuse", "/dev/fuse", "rwm"},
+		{"/dev/sdb:/dev/xvdb", "/dev/sdb", "/dev/xvdb", "rwm"},
+		{"/dev/sdb:/dev/xvdb:r", "/dev/sdb", "/dev/xvdb", "r"},
+	} {
+		d, err := ParseDevice(test.device)
+		if err != nil {
+			t.Fatalf("%q: expected device to parse, got %v", test.device, err)
+		}
+		if d.PathOnHost != test.host || d.PathInContainer != test.container || d.CgroupPermissions != test.permissions {
+			t.Fatalf("%q: expected (%v, %v, %v), got %+v", test.device, test.host, test.container, test.permissions, d)
+		}
+	}
+
+	for _, bad := range []string{"dev/fuse", "/dev/sdb:xvdb", "/dev/sdb:/dev/xvdb:x", "/dev/sdb:/dev/xvdb:", "/a:/b:r:w"} {
+		if _, err := ParseDevice(bad); err == nil {
+			t.Fatalf("%q: expected an error, got nil", bad)
+		}
+	}
+}
+
+func TestStopGracePeriod(t *testing.T) {
+	for period, seconds := range map[string]uint{"": 0, "30s": 30, "2m": 120, "1500ms": 2, "bad": 0} {
+		if returned := StopGracePeriod(&def.Service{StopGracePeriod: period}); returned != seconds {
+			t.Fatalf("%q: expected %v, got %v", period, seconds, returned)
+		}
+	}
+}
+
+func TestCheckDockerOptions(t *testing.T) {
+	good := &def.Service{
+		Labels:          []string{"com.example.team=infra"},
+		ExtraHosts:      []string{"registry.local:10.0.0.5", "v6.local:::1"},
+		Sysctls:         []string{"net.core.somaxconn=1024"},
+		Devices:         []string{"/dev/fuse"},
+		ShmSize:         1024,
+		StopSignal:      "SIGINT",
+		StopGracePeriod: "30s",
+		LogDriver:       "json-file",
+		LogOpts:         []string{"max-size=10m"},
+	}
+	if err := CheckDockerOptions(good); err != nil {
+		t.Fatalf("expected options to pass, got %v", err)
+	}
+
+	for i, bad := range []*def.Service{
+		{Labels: []string{"eris:type=service"}},
+		{Labels: []string{"team"}},
+		{ExtraHosts: []string{"registry.local"}},
+		{ExtraHosts: []string{"registry.local:nowhere"}},
+		{Sysctls: []string{"net.core.somaxconn"}},
+		{Devices: []string{"fuse"}},
+		{ShmSize: -1},
+		{StopSignal: "INT"},
+		{StopGracePeriod: "30"},
+		{LogOpts: []string{"max-size"}},
+	} {
+		if err := CheckDockerOptions(bad); err == nil {
+			t.Fatalf("%d: expected an error, got nil", i)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// AllowDrift lets images which do not match the lock file run (with
+// a warning). It's set by the --allow-drift flag. Setting the
+// ERIS_ALLOW_DRIFT environment variable to true does the same.
+var AllowDrift bool
+
+// DriftAllowed reports whether images may drift from the lock file.
+func DriftAllowed() bool {
+	return AllowDrift || os.Getenv("ERIS_ALLOW_DRIFT") == "true"
+}
+
+// ImageLock is the content of the images lock file written by
+// [eris services lock]. It maps image references of the service and
+// chain definitions (with tags) to the images they resolved to.
+type ImageLock struct {
+	Images map[string]*LockedImage `json:"images"`
+}
+
+// LockedImage is an image reference resolved to a digest.
+type LockedImage struct {
+	// repository digest (repo@sha256:...), empty for images
+	// which were never pushed to or pulled from a registry
+	Digest string `json:"digest,omitempty"`
+	// image ID
+	ID string `json:"id"`
+}
+
+// ImageDriftError is returned if an image does not match the lock file.
+type ImageDriftError struct {
+	Image  string
+	Locked *LockedImage
+	Found  string
+}
+
+func (e *ImageDriftError) Error() string {
+	locked := e.Locked.ID
+	if e.Locked.Digest != "" {
+		locked = e.Locked.Digest
+	}
+	return fmt.Sprintf(`The image %s does not match the lock file:
+  locked %s
+  found  %s
+Update the lock file with [eris services lock] or use the --allow-drift flag`, e.Image, locked, e.Found)
+}
+
+// ImageLockFile returns the path of the images lock file.
+func ImageLockFile() string {
+	return filepath.Join(ErisRoot, "images.lock")
+}
+
+// ReadImageLock reads the images lock file. An empty lock is returned
+// if there is no lock file.
+func ReadImageLock() (*ImageLock, error) {
+	lock := &ImageLock{Images: make(map[string]*LockedImage)}
+
+	contents, err := ioutil.ReadFile(ImageLockFile())
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, lock); err != nil {
+		return nil, fmt.Errorf("Cannot read the images lock file %s: %v", ImageLockFile(), err)
+	}
+	if lock.Images == nil {
+		lock.Images = make(map[string]*LockedImage)
+	}
+	return lock, nil
+}
+
+// WriteImageLock writes the images lock file.
+func WriteImageLock(lock *ImageLock) error {
+	contents, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(ImageLockFile(), append(contents, '\n'), 0644)
+}
+
+// LockedImages returns the sorted image references of the lock.
+func (lock *ImageLock) LockedImages() []string {
+	var images []string
+	for image := range lock.Images {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
+
+// Locked returns the locked image for the image reference or nil.
+func (lock *ImageLock) Locked(image string) *LockedImage {
+	return lock.Images[NormalizeImage(image)]
+}
+
+// SplitImage splits the image reference into the repository (including
+// the registry) and the tag or the digest. The tag defaults to "latest".
+func SplitImage(image string) (repository, tag string) {
+	if parts := strings.SplitN(image, "@", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+
+	// A colon after the last slash separates the tag;
+	// others belong to the registry port.
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// IsPinnedImage returns true if the image is referenced by a digest.
+func IsPinnedImage(image string) bool {
+	return strings.Contains(image, "@")
+}
+
+// NormalizeImage returns the image reference with an explicit tag.
+func NormalizeImage(image string) string {
+	if IsPinnedImage(image) {
+		return image
+	}
+	repository, tag := SplitImage(image)
+	return repository + ":" + tag
+}
+
+// LockImage resolves the image metadata to a locked image.
+func LockImage(image string, metadata *docker.Image) *LockedImage {
+	repository, _ := SplitImage(image)
+
+	locked := &LockedImage{ID: metadata.ID}
+	for _, digest := range metadata.RepoDigests {
+		repo, _ := SplitImage(digest)
+		if trimRegistry(repo) == trimRegistry(repository) {
+			locked.Digest = digest
+			break
+		}
+	}
+	if locked.Digest == "" && len(metadata.RepoDigests) != 0 {
+		locked.Digest = metadata.RepoDigests[0]
+	}
+	return locked
+}
+
+// Matches returns true if the image metadata matches the locked image.
+func (locked *LockedImage) Matches(metadata *docker.Image) bool {
+	if metadata.ID == locked.ID {
+		return true
+	}
+	for _, digest := range metadata.RepoDigests {
+		if digest == locked.Digest {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckImageLock returns an ImageDriftError if the local image does not
+// match the images lock file. Images referenced by digests, images
+// not in the lock file, and images not found locally are not checked.
+// The check is skipped (with a warning) if DriftAllowed.
+func CheckImageLock(image string) error {
+	if IsPinnedImage(image) {
+		return nil
+	}
+
+	lock, err := ReadImageLock()
+	if err != nil {
+		return err
+	}
+	locked := lock.Locked(image)
+	if locked == nil {
+		return nil
+	}
+
+	metadata, err := DockerClient.InspectImage(image)
+	if err == docker.ErrNoSuchImage {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if locked.Matches(metadata) {
+		return nil
+	}
+
+	drift := &ImageDriftError{Image: image, Locked: locked, Found: metadata.ID}
+	if DriftAllowed() {
+		log.WithFields(log.Fields{
+			"image":  image,
+			"locked": locked.ID,
+			"found":  metadata.ID,
+		}).Warn("The image does not match the lock file. Allowing drift")
+		return nil
+	}
+	return drift
+}
+
+func trimRegistry(repository string) string {
+	repository = strings.TrimPrefix(repository, "docker.io/")
+	return strings.TrimPrefix(repository, "library/")
+}
@@ -0,0 +1,86 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+func TestSplitImage(t *testing.T) {
+	for _, test := range []struct {
+		image, repository, tag string
+	}{
+		{"eris/ipfs", "eris/ipfs", "latest"},
+		{"quay.io/eris/ipfs:0.11", "quay.io/eris/ipfs", "0.11"},
+		{"localhost:5000/eris/ipfs", "localhost:5000/eris/ipfs", "latest"},
+		{"localhost:5000/eris/ipfs:beta", "localhost:5000/eris/ipfs", "beta"},
+		{"quay.io/eris/ipfs@sha256:abcd", "quay.io/eris/ipfs", "sha256:abcd"},
+	} {
+		if repository, tag := SplitImage(test.image); repository != test.repository || tag != test.tag {
+			t.Fatalf("%q: expected (%v, %v), got (%v, %v)", test.image, test.repository, test.tag, repository, tag)
+		}
+	}
+
+	if image := NormalizeImage("eris/ipfs"); image != "eris/ipfs:latest" {
+		t.Fatalf("expected a latest tag, got %v", image)
+	}
+	if image := NormalizeImage("eris/ipfs@sha256:abcd"); image != "eris/ipfs@sha256:abcd" {
+		t.Fatalf("expected pinned image to stay the same, got %v", image)
+	}
+}
+
+func TestLockImage(t *testing.T) {
+	metadata := &docker.Image{
+		ID:          "sha256:1111",
+		RepoDigests: []string{"other/ipfs@sha256:2222", "quay.io/eris/ipfs@sha256:3333"},
+	}
+
+	locked := LockImage("quay.io/eris/ipfs:latest", metadata)
+	if locked.ID != "sha256:1111" || locked.Digest != "quay.io/eris/ipfs@sha256:3333" {
+		t.Fatalf("expected the digest of the image repository, got %+v", locked)
+	}
+	if !locked.Matches(metadata) {
+		t.Fatalf("expected image to match its lock")
+	}
+	if !locked.Matches(&docker.Image{ID: "sha256:4444", RepoDigests: []string{"quay.io/eris/ipfs@sha256:3333"}}) {
+		t.Fatalf("expected image with the same digest to match")
+	}
+	if locked.Matches(&docker.Image{ID: "sha256:4444", RepoDigests: []string{"quay.io/eris/ipfs@sha256:5555"}}) {
+		t.Fatalf("expected different image not to match")
+	}
+}
+
+func TestReadWriteImageLock(t *testing.T) {
+	root := common.ErisRoot
+	defer func() { common.ErisRoot = root }()
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatalf("expected a temporary directory, got %v", err)
+	}
+	defer os.RemoveAll(dir)
+	common.ErisRoot = dir
+
+	lock, err := ReadImageLock()
+	if err != nil || len(lock.Images) != 0 {
+		t.Fatalf("expected an empty lock without a lock file, got %v (error %v)", lock.Images, err)
+	}
+
+	lock.Images["eris/ipfs:latest"] = &LockedImage{ID: "sha256:1111", Digest: "eris/ipfs@sha256:2222"}
+	if err := WriteImageLock(lock); err != nil {
+		t.Fatalf("expected lock file to be written, got %v", err)
+	}
+
+	lock, err = ReadImageLock()
+	if err != nil {
+		t.Fatalf("expected lock file to be read, got %v", err)
+	}
+	if locked := lock.Locked("eris/ipfs"); locked == nil || locked.ID != "sha256:1111" {
+		t.Fatalf("expected eris/ipfs to be locked, got %+v", locked)
+	}
+	if err := CheckImageLock("eris/ipfs@sha256:9999"); err != nil {
+		t.Fatalf("expected pinned image not to be checked, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"fmt"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+)
+
+// CheckHooks returns an error if a lifecycle hook of the definition
+// doesn't reference exactly one action or command, or has an unknown
+// failure policy.
+func CheckHooks(hooks *def.Hooks) error {
+	if hooks == nil {
+		return nil
+	}
+
+	for _, event := range []string{def.HookPreStart, def.HookPostStart, def.HookPreStop, def.HookPostStop} {
+		for i, hook := range HooksFor(hooks, event) {
+			switch {
+			case hook == nil:
+				return fmt.Errorf("Invalid %s hook #%d: empty hook", event, i+1)
+			case hook.Action == "" && hook.Command == "":
+				return fmt.Errorf("Invalid %s hook #%d: either an action or a command is required", event, i+1)
+			case hook.Action != "" && hook.Command != "":
+				return fmt.Errorf("Invalid %s hook #%d: give an action or a command, not both", event, i+1)
+			case hook.Action != "" && hook.Container:
+				return fmt.Errorf("Invalid %s hook #%d: actions cannot be run in a container", event, i+1)
+			}
+
+			switch hook.OnFailure {
+			case "", def.HookAbort, def.HookWarn:
+			default:
+				return fmt.Errorf("Invalid %s hook #%d: on_failure can be either %q or %q", event, i+1, def.HookAbort, def.HookWarn)
+			}
+		}
+	}
+	return nil
+}
+
+// HooksFor returns the hooks of the lifecycle event.
+func HooksFor(hooks *def.Hooks, event string) []*def.Hook {
+	if hooks == nil {
+		return nil
+	}
+
+	switch event {
+	case def.HookPreStart:
+		return hooks.PreStart
+	case def.HookPostStart:
+		return hooks.PostStart
+	case def.HookPreStop:
+		return hooks.PreStop
+	case def.HookPostStop:
+		return hooks.PostStop
+	}
+	return nil
+}
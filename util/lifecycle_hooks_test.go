@@ -0,0 +1,33 @@
+package util
+
+import (
+	"testing"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+)
+
+func TestCheckHooks(t *testing.T) {
+	good := &def.Hooks{
+		PreStart:  []*def.Hook{{Command: "mkdir -p /tmp/seed", Container: true}},
+		PostStart: []*def.Hook{{Action: "dns register", OnFailure: def.HookWarn}},
+		PostStop:  []*def.Hook{{Command: "echo stopped", OnFailure: def.HookAbort}},
+	}
+	if err := CheckHooks(good); err != nil {
+		t.Fatalf("expected hooks to pass, got %v", err)
+	}
+	if err := CheckHooks(nil); err != nil {
+		t.Fatalf("expected no hooks to pass, got %v", err)
+	}
+
+	for i, bad := range []*def.Hook{
+		nil,
+		{},
+		{Action: "dns register", Command: "echo"},
+		{Action: "dns register", Container: true},
+		{Command: "echo", OnFailure: "ignore"},
+	} {
+		if err := CheckHooks(&def.Hooks{PreStop: []*def.Hook{bad}}); err == nil {
+			t.Fatalf("%d: expected an error, got nil", i)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+
+	log "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/Sirupsen/logrus"
+	. "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/eris-ltd/common/go/common"
+)
+
+// Capabilities Docker grants to containers unless they are dropped.
+var defaultCapabilities = []string{
+	"CHOWN", "DAC_OVERRIDE", "FSETID", "FOWNER", "MKNOD", "NET_RAW",
+	"SETGID", "SETUID", "SETFCAP", "SETPCAP", "NET_BIND_SERVICE",
+	"SYS_CHROOT", "KILL", "AUDIT_WRITE",
+}
+
+// UnmetRequirements evaluates the machine.requires host preflight checks
+// and returns a description of each check which failed. The ops settings
+// (can be nil) are used to determine container capabilities.
+//
+// Recognized requirements:
+//
+//  docker>=1.9       - minimal Docker version
+//  memory>=2GB       - minimal Docker host memory
+//  disk>=10GB        - minimal free disk space in the eris root directory
+//  cap:NET_ADMIN     - the container is given a Linux capability
+//  port:46656        - the Docker host port is free (port:46656/udp for UDP)
+//  binary:git        - the host binary can be found in $PATH
+//
+func UnmetRequirements(requires []string, ops *def.Operation) []string {
+	var unmet []string
+	for _, req := range requires {
+		req = strings.TrimSpace(req)
+		if req == "" {
+			continue
+		}
+
+		log.WithField("requirement", req).Debug("Checking machine requirement")
+		if err := checkRequirement(req, ops); err != nil {
+			unmet = append(unmet, fmt.Sprintf("%s: %v", req, err))
+		}
+	}
+	return unmet
+}
+
+// RequirementsReport returns an error listing all unmet requirements
+// grouped by service, chain, or action name, or nil if unmet is empty.
+func RequirementsReport(unmet map[string][]string) error {
+	if len(unmet) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(unmet))
+	for name := range unmet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := []string{"The marmots found unmet machine requirements:"}
+	for _, name := range names {
+		report = append(report, "  "+name)
+		for _, u := range unmet[name] {
+			report = append(report, "    "+u)
+		}
+	}
+	return fmt.Errorf("%s", strings.Join(report, "\n"))
+}
+
+func checkRequirement(req string, ops *def.Operation) error {
+	kind, value := splitRequirement(req)
+
+	switch kind {
+	case "docker":
+		version, err := DockerClientVersion()
+		if err != nil {
+			return err
+		}
+		if !CompareVersions(version, value) {
+			return fmt.Errorf("found Docker version %s", version)
+		}
+	case "memory":
+		want, err := ParseSize(value)
+		if err != nil {
+			return err
+		}
+		info, err := DockerClient.Info()
+		if err != nil {
+			return err
+		}
+		if have := info.GetInt64("MemTotal"); uint64(have) < want {
+			return fmt.Errorf("found %s of memory", FormatSize(uint64(have)))
+		}
+	case "disk":
+		want, err := ParseSize(value)
+		if err != nil {
+			return err
+		}
+		have, err := freeDiskSpace(ErisRoot)
+		if err != nil {
+			return err
+		}
+		if have < want {
+			return fmt.Errorf("found %s free in %s", FormatSize(have), ErisRoot)
+		}
+	case "cap":
+		if !hasCapability(ops, value) {
+			return fmt.Errorf("capability not granted (add it with --cap-add)")
+		}
+	case "port":
+		return checkPortFree(dockerHostIP(), value)
+	case "binary":
+		if _, err := exec.LookPath(value); err != nil {
+			return fmt.Errorf("not found in $PATH")
+		}
+	default:
+		return fmt.Errorf("unknown requirement")
+	}
+	return nil
+}
+
+// splitRequirement splits "kind>=value" and "kind:value" requirements.
+func splitRequirement(req string) (kind, value string) {
+	for _, sep := range []string{">=", ":"} {
+		if parts := strings.SplitN(req, sep, 2); len(parts) == 2 {
+			return strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+		}
+	}
+	return strings.ToLower(req), ""
+}
+
+func hasCapability(ops *def.Operation, capability string) bool {
+	normalize := func(c string) string {
+		return strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+	}
+	capability = normalize(capability)
+
+	if ops == nil {
+		return contains(defaultCapabilities, capability)
+	}
+	if ops.Privileged {
+		return true
+	}
+	for _, c := range ops.CapAdd {
+		if c := normalize(c); c == capability || c == "ALL" {
+			return true
+		}
+	}
+	if IsHardened() {
+		return false
+	}
+	for _, c := range ops.CapDrop {
+		if c := normalize(c); c == capability || c == "ALL" {
+			return false
+		}
+	}
+	return contains(defaultCapabilities, capability)
+}
+
+// checkPortFree checks that the port is free on the Docker host. Ports
+// of a local Docker host are probed by listening on them; ports of a
+// remote one (e.g. a Docker Machine VM) by connecting to them, which
+// can't be done for UDP ports, so those are skipped with a warning.
+func checkPortFree(host, port string) error {
+	proto := "tcp"
+	if parts := strings.SplitN(port, "/", 2); len(parts) == 2 {
+		port, proto = parts[0], strings.ToLower(parts[1])
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return fmt.Errorf("bad port number")
+	}
+	if proto != "tcp" && proto != "udp" {
+		return fmt.Errorf("unknown protocol %s", proto)
+	}
+
+	if host != "127.0.0.1" && host != "localhost" && host != "::1" {
+		if proto == "udp" {
+			log.WithFields(log.Fields{
+				"port": port + "/udp",
+				"host": host,
+			}).Warn("Cannot check UDP ports of a remote Docker host. Skipping")
+			return nil
+		}
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), 2*time.Second)
+		if err != nil {
+			return nil
+		}
+		conn.Close()
+		return fmt.Errorf("port is in use on the Docker host %s", host)
+	}
+
+	if proto == "tcp" {
+		l, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			return fmt.Errorf("port is in use")
+		}
+		l.Close()
+	} else {
+		l, err := net.ListenPacket("udp", ":"+port)
+		if err != nil {
+			return fmt.Errorf("port is in use")
+		}
+		l.Close()
+	}
+	return nil
+}
+
+// ParseSize converts human readable sizes, such as 512MB or 2G,
+// to a number of bytes.
+func ParseSize(size string) (uint64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+
+	multiplier := uint64(1)
+	for i, unit := range []string{"K", "M", "G", "T"} {
+		if strings.HasSuffix(s, unit) {
+			multiplier = 1 << (10 * uint(i+1))
+			s = strings.TrimSuffix(s, unit)
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad size %q", size)
+	}
+	return uint64(n * float64(multiplier)), nil
+}
+
+// FormatSize is the opposite of ParseSize.
+func FormatSize(bytes uint64) string {
+	for i, unit := range []string{"TB", "GB", "MB", "KB"} {
+		if m := uint64(1) << (10 * uint(4-i)); bytes >= m {
+			return strconv.FormatFloat(float64(bytes)/float64(m), 'f', 1, 64) + unit
+		}
+	}
+	return strconv.FormatUint(bytes, 10) + "B"
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package util
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+)
+
+func TestParseSize(t *testing.T) {
+	for _, test := range []struct {
+		size string
+		want uint64
+	}{
+		{"100", 100},
+		{"100B", 100},
+		{"1K", 1 << 10},
+		{"1kb", 1 << 10},
+		{"512MB", 512 << 20},
+		{"2G", 2 << 30},
+		{"1.5GB", 3 << 29},
+		{"1TB", 1 << 40},
+	} {
+		if got, err := ParseSize(test.size); err != nil || got != test.want {
+			t.Fatalf("%q: expected %v, got %v (error %v)", test.size, test.want, got, err)
+		}
+	}
+
+	for _, size := range []string{"", "GB", "-1G", "lots"} {
+		if _, err := ParseSize(size); err == nil {
+			t.Fatalf("%q: expected an error, got nil", size)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for _, test := range []struct {
+		bytes uint64
+		want  string
+	}{
+		{100, "100B"},
+		{1 << 10, "1.0KB"},
+		{3 << 29, "1.5GB"},
+	} {
+		if got := FormatSize(test.bytes); got != test.want {
+			t.Fatalf("%v: expected %v, got %v", test.bytes, test.want, got)
+		}
+	}
+}
+
+func TestSplitRequirement(t *testing.T) {
+	for _, test := range []struct {
+		req, kind, value string
+	}{
+		{"docker>=1.9", "docker", "1.9"},
+		{"Memory >= 2GB", "memory", "2GB"},
+		{"port:46656", "port", "46656"},
+		{"binary:git", "binary", "git"},
+		{"nonsense", "nonsense", ""},
+	} {
+		if kind, value := splitRequirement(test.req); kind != test.kind || value != test.value {
+			t.Fatalf("%q: expected (%v, %v), got (%v, %v)", test.req, test.kind, test.value, kind, value)
+		}
+	}
+}
+
+func TestHasCapability(t *testing.T) {
+	for _, test := range []struct {
+		ops  *def.Operation
+		cap  string
+		want bool
+	}{
+		{nil, "CHOWN", true},
+		{nil, "NET_ADMIN", false},
+		{&def.Operation{CapAdd: []string{"NET_ADMIN"}}, "NET_ADMIN", true},
+		{&def.Operation{CapAdd: []string{"net_admin"}}, "CAP_NET_ADMIN", true},
+		{&def.Operation{CapAdd: []string{"ALL"}}, "SYS_ADMIN", true},
+		{&def.Operation{CapDrop: []string{"CHOWN"}}, "CHOWN", false},
+		{&def.Operation{CapDrop: []string{"ALL"}}, "KILL", false},
+		{&def.Operation{Privileged: true}, "SYS_ADMIN", true},
+	} {
+		if got := hasCapability(test.ops, test.cap); got != test.want {
+			t.Fatalf("%v with %+v: expected %v, got %v", test.cap, test.ops, test.want, got)
+		}
+	}
+}
+
+func TestUnmetRequirementsHostOnly(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("expected to listen on a random port, got %v", err)
+	}
+	defer l.Close()
+	busy := l.Addr().(*net.TCPAddr).Port
+
+	unmet := UnmetRequirements([]string{
+		"",
+		"binary:sh",
+		"binary:no-such-binary-for-the-marmots",
+		"port:" + strconv.Itoa(busy),
+		"cap:NET_ADMIN",
+		"flux capacitor",
+	}, nil)
+
+	if len(unmet) != 4 {
+		t.Fatalf("expected 4 unmet requirements, got %v", unmet)
+	}
+	for i, prefix := range []string{"binary:no-such", "port:", "cap:NET_ADMIN", "flux capacitor"} {
+		if !strings.HasPrefix(unmet[i], prefix) {
+			t.Fatalf("expected unmet requirement %q, got %q", prefix, unmet[i])
+		}
+	}
+}
+
+func TestRequirementsReport(t *testing.T) {
+	if err := RequirementsReport(nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	err := RequirementsReport(map[string][]string{
+		"keys": {"binary:git: not found in $PATH"},
+		"ipfs": {"port:4001: port is in use", "memory>=64GB: found 2.0GB of memory"},
+	})
+	if err == nil {
+		t.Fatalf("expected an error, got nil")
+	}
+
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 6 || strings.TrimSpace(lines[1]) != "ipfs" || strings.TrimSpace(lines[4]) != "keys" {
+		t.Fatalf("expected a report grouped and sorted by name, got %v", err)
+	}
+}
+
+func TestCheckPortFreeRemote(t *testing.T) {
+	// 127.0.0.2 stands in for the address of a Docker Machine VM.
+	l, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("cannot listen on 127.0.0.2: %v", err)
+	}
+	busy := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+
+	if err := checkPortFree("127.0.0.2", busy); err == nil || !strings.Contains(err.Error(), "127.0.0.2") {
+		t.Fatalf("expected port %s to be in use on the Docker host, got %v", busy, err)
+	}
+	if err := checkPortFree("127.0.0.2", busy+"/udp"); err != nil {
+		t.Fatalf("expected UDP ports of a remote host to be skipped, got %v", err)
+	}
+
+	l.Close()
+	if err := checkPortFree("127.0.0.2", busy); err != nil {
+		t.Fatalf("expected port %s to be free on the Docker host, got %v", busy, err)
+	}
+}
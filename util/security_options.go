@@ -0,0 +1,130 @@
+package util
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eris-ltd/eris-cli/config"
+	def "github.com/eris-ltd/eris-cli/definitions"
+
+	docker "github.com/eris-ltd/eris-cli/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
+)
+
+// NoNewPrivileges is the security option set by the no_new_privileges
+// field of the service definition.
+const NoNewPrivileges = "no-new-privileges"
+
+// HardenedProfile is the SecurityProfile value in eris.toml which drops
+// all capabilities of service and chain containers except those added
+// explicitly.
+const HardenedProfile = "hardened"
+
+var cpusetRegexp = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// IsHardened returns true if the hardened security profile is set in eris.toml.
+func IsHardened() bool {
+	return config.GetConfigValue("SecurityProfile") == HardenedProfile
+}
+
+// ParseUlimit parses the ulimit in the "name=soft[:hard]" format
+// of [docker run --ulimit]. The hard limit defaults to the soft one.
+func ParseUlimit(ulimit string) (docker.ULimit, error) {
+	parts := strings.SplitN(ulimit, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return docker.ULimit{}, fmt.Errorf("Invalid ulimit %q: expected <name>=<soft>[:<hard>]", ulimit)
+	}
+
+	limits := strings.SplitN(parts[1], ":", 2)
+	soft, err := strconv.ParseInt(limits[0], 10, 64)
+	if err != nil {
+		return docker.ULimit{}, fmt.Errorf("Invalid ulimit %q: bad soft limit %q", ulimit, limits[0])
+	}
+	hard := soft
+	if len(limits) == 2 {
+		if hard, err = strconv.ParseInt(limits[1], 10, 64); err != nil {
+			return docker.ULimit{}, fmt.Errorf("Invalid ulimit %q: bad hard limit %q", ulimit, limits[1])
+		}
+	}
+	if soft > hard {
+		return docker.ULimit{}, fmt.Errorf("Invalid ulimit %q: soft limit is greater than the hard limit", ulimit)
+	}
+
+	return docker.ULimit{Name: parts[0], Soft: soft, Hard: hard}, nil
+}
+
+// ParseTmpfs parses the tmpfs mount in the "path[:options]" format
+// of [docker run --tmpfs]. The path has to be absolute.
+func ParseTmpfs(tmpfs string) (mount, options string, err error) {
+	parts := strings.SplitN(tmpfs, ":", 2)
+	mount = parts[0]
+	if !path.IsAbs(mount) {
+		return "", "", fmt.Errorf("Invalid tmpfs %q: the mount point has to be an absolute path", tmpfs)
+	}
+	if len(parts) == 2 {
+		options = parts[1]
+	}
+	return mount, options, nil
+}
+
+// CheckSecurityOptions returns an error if the security and resource
+// options of the service definition are malformed.
+func CheckSecurityOptions(srv *def.Service) error {
+	for _, opt := range srv.SecurityOpt {
+		if opt == NoNewPrivileges || strings.HasPrefix(opt, NoNewPrivileges+":") {
+			continue
+		}
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			parts = strings.SplitN(opt, ":", 2)
+		}
+		if len(parts) != 2 || parts[1] == "" {
+			return fmt.Errorf("Invalid security_opt %q: expected <option>=<value>", opt)
+		}
+		switch parts[0] {
+		case "seccomp", "apparmor", "label":
+		default:
+			return fmt.Errorf("Invalid security_opt %q: unknown option %q", opt, parts[0])
+		}
+	}
+
+	for _, ulimit := range srv.Ulimits {
+		if _, err := ParseUlimit(ulimit); err != nil {
+			return err
+		}
+	}
+
+	for _, tmpfs := range srv.Tmpfs {
+		if _, _, err := ParseTmpfs(tmpfs); err != nil {
+			return err
+		}
+	}
+
+	if srv.PidsLimit < -1 {
+		return fmt.Errorf("Invalid pids_limit %d: expected a positive number or -1 for unlimited", srv.PidsLimit)
+	}
+
+	if srv.UserNS != "" && srv.UserNS != "host" {
+		return fmt.Errorf("Invalid user_ns %q: only \"host\" is supported", srv.UserNS)
+	}
+
+	if srv.MemorySwap != 0 {
+		if srv.MemorySwap < -1 {
+			return fmt.Errorf("Invalid memory_swap %d: expected a positive number or -1 for unlimited", srv.MemorySwap)
+		}
+		if srv.MemLimit == 0 {
+			return fmt.Errorf("The memory_swap field requires the mem_limit field to be set")
+		}
+		if srv.MemorySwap != -1 && srv.MemorySwap < srv.MemLimit {
+			return fmt.Errorf("Invalid memory_swap %d: it cannot be less than mem_limit %d", srv.MemorySwap, srv.MemLimit)
+		}
+	}
+
+	if srv.CPUSet != "" && !cpusetRegexp.MatchString(srv.CPUSet) {
+		return fmt.Errorf("Invalid cpuset %q: expected CPU numbers or ranges, e.g. \"0-3\" or \"0,1\"", srv.CPUSet)
+	}
+
+	return nil
+}
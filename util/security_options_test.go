@@ -0,0 +1,71 @@
+package util
+
+import (
+	"testing"
+
+	def "github.com/eris-ltd/eris-cli/definitions"
+)
+
+func TestParseUlimit(t *testing.T) {
+	ulimit, err := ParseUlimit("nofile=1024:4096")
+	if err != nil {
+		t.Fatalf("expected ulimit to parse, got %v", err)
+	}
+	if ulimit.Name != "nofile" || ulimit.Soft != 1024 || ulimit.Hard != 4096 {
+		t.Fatalf("expected nofile=1024:4096, got %+v", ulimit)
+	}
+
+	if ulimit, err = ParseUlimit("nproc=512"); err != nil || ulimit.Hard != 512 {
+		t.Fatalf("expected hard limit to default to the soft one, got %+v (%v)", ulimit, err)
+	}
+
+	for _, bad := range []string{"nofile", "=10", "nofile=x", "nofile=10:x", "nofile=20:10"} {
+		if _, err := ParseUlimit(bad); err == nil {
+			t.Fatalf("%q: expected an error, got nil", bad)
+		}
+	}
+}
+
+func TestParseTmpfs(t *testing.T) {
+	if mount, options, err := ParseTmpfs("/run:rw,size=64m"); err != nil || mount != "/run" || options != "rw,size=64m" {
+		t.Fatalf("expected (/run, rw,size=64m), got (%v, %v, %v)", mount, options, err)
+	}
+	if mount, options, err := ParseTmpfs("/tmp"); err != nil || mount != "/tmp" || options != "" {
+		t.Fatalf("expected (/tmp, ), got (%v, %v, %v)", mount, options, err)
+	}
+	if _, _, err := ParseTmpfs("tmp:rw"); err == nil {
+		t.Fatalf("expected relative path to fail, got nil")
+	}
+}
+
+func TestCheckSecurityOptions(t *testing.T) {
+	good := &def.Service{
+		SecurityOpt: []string{"seccomp=/etc/seccomp.json", "apparmor:docker-default", "no-new-privileges"},
+		Ulimits:     []string{"nofile=1024:4096"},
+		Tmpfs:       []string{"/run:rw"},
+		PidsLimit:   100,
+		UserNS:      "host",
+		MemLimit:    512,
+		MemorySwap:  1024,
+		CPUSet:      "0-1,3",
+	}
+	if err := CheckSecurityOptions(good); err != nil {
+		t.Fatalf("expected options to pass, got %v", err)
+	}
+
+	for i, bad := range []*def.Service{
+		{SecurityOpt: []string{"seccomp"}},
+		{SecurityOpt: []string{"selinux=foo"}},
+		{Ulimits: []string{"nofile"}},
+		{Tmpfs: []string{"run"}},
+		{PidsLimit: -2},
+		{UserNS: "private"},
+		{MemorySwap: 1024},
+		{MemLimit: 1024, MemorySwap: 512},
+		{CPUSet: "0-"},
+	} {
+		if err := CheckSecurityOptions(bad); err == nil {
+			t.Fatalf("%d: expected an error, got nil", i)
+		}
+	}
+}